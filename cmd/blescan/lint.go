@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/buckleypaul/blescan/internal/ble"
+	"github.com/buckleypaul/blescan/internal/capture"
+)
+
+// runLint implements "blescan lint <capture>": it validates every
+// advertisement in a btsnoop or pcap file and prints the findings grouped by
+// address. It returns the process exit code: 1 if any errors were found.
+func runLint(args []string, out io.Writer) int {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: blescan lint [flags] <capture file>")
+		fmt.Fprintln(fs.Output(), "")
+		fmt.Fprintln(fs.Output(), "Validate advertisements in a btsnoop or pcap capture against the Core Specification Supplement.")
+		fs.PrintDefaults()
+	}
+	warnings := fs.Bool("warnings", true, "report warnings as well as errors")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	ads, err := capture.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading capture: %v\n", err)
+		return 1
+	}
+
+	// Count each distinct finding per address so a beacon repeating the
+	// same malformed payload reports it once
+	type tally struct {
+		finding ble.LintFinding
+		count   int
+	}
+	byAddress := make(map[string][]*tally)
+	var checked, totalErrors, totalWarnings int
+
+	for i := range ads {
+		adv := &ads[i]
+		if len(adv.RawData) == 0 {
+			continue
+		}
+		checked++
+		for _, f := range adv.Lint() {
+			if f.Severity == ble.LintWarning {
+				totalWarnings++
+				if !*warnings {
+					continue
+				}
+			} else {
+				totalErrors++
+			}
+
+			found := false
+			for _, t := range byAddress[adv.Address] {
+				if t.finding == f {
+					t.count++
+					found = true
+					break
+				}
+			}
+			if !found {
+				byAddress[adv.Address] = append(byAddress[adv.Address], &tally{finding: f, count: 1})
+			}
+		}
+	}
+
+	addresses := make([]string, 0, len(byAddress))
+	for addr := range byAddress {
+		addresses = append(addresses, addr)
+	}
+	sort.Strings(addresses)
+
+	for _, addr := range addresses {
+		fmt.Fprintln(out, addr)
+		for _, t := range byAddress[addr] {
+			fmt.Fprintf(out, "  %s (x%d)\n", t.finding, t.count)
+		}
+	}
+
+	fmt.Fprintf(out, "%d advertisements checked, %d errors, %d warnings\n", checked, totalErrors, totalWarnings)
+	if totalErrors > 0 {
+		return 1
+	}
+	return 0
+}
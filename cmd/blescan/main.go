@@ -1,28 +1,124 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/buckleypaul/blescan/internal/annotate"
 	"github.com/buckleypaul/blescan/internal/ble"
+	"github.com/buckleypaul/blescan/internal/capture"
+	"github.com/buckleypaul/blescan/internal/classify"
+	"github.com/buckleypaul/blescan/internal/config"
+	"github.com/buckleypaul/blescan/internal/stats"
 	"github.com/buckleypaul/blescan/internal/ui"
 )
 
 var version = "dev"
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "lint" {
+		os.Exit(runLint(os.Args[2:], os.Stdout))
+	}
+
+	showVersion := flag.Bool("version", false, "print version and exit")
+	flag.BoolVar(showVersion, "v", false, "print version and exit (shorthand)")
+	irkPath := flag.String("irk-file", "", "identity resolving keys: a keys file, a BlueZ info file, or a BlueZ storage directory\n(default: identity_keys.txt in the config directory, if present)")
+	eadPath := flag.String("ead-keys", "", "encrypted advertising data keys file\n(default: ead_keys.txt in the config directory, if present)")
+	trackerAlert := flag.Duration("tracker-alert", ble.DefaultTrackerAlertThreshold, "alert when a tracker away from its owner has been near for this long")
+	floodLimit := flag.Bool("flood-limit", false, "admit only a few new addresses of an ongoing advertisement flood to the device table")
+	envFactor := flag.Float64("env-factor", 0, "path loss exponent for distance estimates, 2 in open space to 4 indoors\n(default: distance.json in the config directory, or 2)")
+	smoothing := ble.DefaultSmoothingConfig()
+	flag.StringVar(&smoothing.Kind, "smoothing", smoothing.Kind, "RSSI smoothing filter: kalman, ema, median or none (S cycles it while running)")
+	flag.Float64Var(&smoothing.Alpha, "ema-alpha", smoothing.Alpha, "weight of each new reading in the EMA filter, 0 to 1")
+	flag.Float64Var(&smoothing.ProcessNoise, "kalman-q", smoothing.ProcessNoise, "Kalman filter process noise: how fast the true RSSI is expected to change, in dB²")
+	flag.Float64Var(&smoothing.MeasurementNoise, "kalman-r", smoothing.MeasurementNoise, "Kalman filter measurement noise: variance of a single reading, in dB²")
+	flag.IntVar(&smoothing.Window, "median-window", smoothing.Window, "readings in the sliding median filter")
+	limits := ble.DefaultHistoryLimits()
+	flag.IntVar(&limits.Adverts, "history-adverts", limits.Adverts, "raw advertisements kept per device")
+	flag.IntVar(&limits.Seconds, "history-seconds", limits.Seconds, "per-second history points kept per device")
+	flag.IntVar(&limits.Minutes, "history-minutes", limits.Minutes, "per-minute history points kept per device")
+	replayPath := flag.String("replay", "", "replay advertisements from a btsnoop or pcap capture instead of scanning")
+	flag.Parse()
+
 	// Check for version flag
-	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "-v") {
+	if *showVersion {
 		fmt.Printf("blescan version %s\n", version)
 		os.Exit(0)
 	}
 
+	// Load user overrides for the assigned-numbers database, if present
+	if path, err := config.Path("assigned_numbers.json"); err == nil {
+		if err := ble.LoadAssignedNumbersOverride(path); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring assigned numbers override: %v\n", err)
+		}
+	}
+
+	// Load user device classification rules, if present
+	if path, err := config.Path("classify_rules.json"); err == nil {
+		if err := classify.LoadRules(path); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring classification rules: %v\n", err)
+		}
+	}
+
+	// Load payload field annotations, if present
+	if path, err := config.Path("annotations.json"); err == nil {
+		if err := annotate.Load(path); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring payload annotations: %v\n", err)
+		}
+	}
+
+	// Configure distance estimation
+	if err := loadPathLossModel(*envFactor); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring distance settings: %v\n", err)
+	}
+
+	if err := ble.SetRSSISmoothing(smoothing); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := ble.SetHistoryLimits(limits); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Create scanner
 	scanner := ble.NewScanner()
 
-	// Start scanning
-	if err := scanner.Start(); err != nil {
+	// Load identity resolving keys so our own devices' RPAs merge into one entry
+	resolver, err := loadResolver(*irkPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading identity resolving keys: %v\n", err)
+		os.Exit(1)
+	}
+	if resolver != nil {
+		scanner.SetResolver(resolver)
+	}
+
+	scanner.SetTrackerAlertThreshold(*trackerAlert)
+	scanner.SetFloodRateLimit(*floodLimit)
+
+	// Load Encrypted Advertising Data keys
+	eadKeys, err := loadEADKeys(*eadPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading encrypted advertising data keys: %v\n", err)
+		os.Exit(1)
+	}
+	if eadKeys != nil {
+		scanner.SetEADKeys(eadKeys)
+	}
+
+	// Replay a capture file instead of scanning
+	if *replayPath != "" {
+		ads, err := capture.ReadFile(*replayPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading capture: %v\n", err)
+			os.Exit(1)
+		}
+		scanner.StartReplay(ads)
+	} else if err := scanner.Start(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error starting BLE scanner: %v\n", err)
 		fmt.Fprintln(os.Stderr, "")
 		fmt.Fprintln(os.Stderr, "Troubleshooting tips:")
@@ -42,3 +138,71 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// loadResolver loads IRKs from path, or from the default keys file in the
+// config directory when path is empty. It returns nil if there are no keys.
+func loadResolver(path string) (*ble.Resolver, error) {
+	if path == "" {
+		defaultPath, err := config.Path("identity_keys.txt")
+		if err != nil {
+			return nil, nil
+		}
+		if _, err := os.Stat(defaultPath); err != nil {
+			return nil, nil
+		}
+		path = defaultPath
+	}
+
+	keys, err := ble.LoadIdentityKeys(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return ble.NewResolver(keys), nil
+}
+
+// loadEADKeys loads Encrypted Advertising Data keys from path, or from the
+// default keys file in the config directory when path is empty. It returns
+// nil if there are no keys.
+func loadEADKeys(path string) (*ble.EADKeyring, error) {
+	if path == "" {
+		defaultPath, err := config.Path("ead_keys.txt")
+		if err != nil {
+			return nil, nil
+		}
+		if _, err := os.Stat(defaultPath); err != nil {
+			return nil, nil
+		}
+		path = defaultPath
+	}
+
+	keys, err := ble.LoadEADKeys(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return ble.NewEADKeyring(keys), nil
+}
+
+// loadPathLossModel configures distance estimation from distance.json in the
+// config directory, if present, with envFactor overriding its exponent
+func loadPathLossModel(envFactor float64) error {
+	model := stats.DefaultPathLossModel()
+	var loadErr error
+	if path, err := config.Path("distance.json"); err == nil {
+		if m, err := stats.LoadPathLossModel(path); err == nil {
+			model = m
+		} else if !os.IsNotExist(err) {
+			loadErr = err
+		}
+	}
+	if envFactor > 0 {
+		model.EnvFactor = envFactor
+	}
+	stats.SetPathLossModel(model)
+	return loadErr
+}
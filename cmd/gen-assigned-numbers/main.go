@@ -0,0 +1,177 @@
+// Command gen-assigned-numbers regenerates the embedded Bluetooth SIG
+// assigned-numbers database (internal/ble/assigned_numbers.json) from the
+// YAML files the SIG publishes at https://bitbucket.org/bluetooth-SIG/public.
+//
+// Usage:
+//
+//	go generate ./internal/ble
+//	go run ./cmd/gen-assigned-numbers -src /path/to/public/assigned_numbers -o out.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultSource = "https://bitbucket.org/bluetooth-SIG/public/raw/main/assigned_numbers"
+
+// Source files, relative to the assigned_numbers directory of the SIG repo
+const (
+	companyIdentifiersFile  = "company_identifiers/company_identifiers.yaml"
+	serviceUUIDsFile        = "uuids/service_uuids.yaml"
+	memberUUIDsFile         = "uuids/member_uuids.yaml"
+	characteristicUUIDsFile = "uuids/characteristic_uuids.yaml"
+	appearanceValuesFile    = "core/appearance_values.yaml"
+)
+
+// assignedNumbersFile mirrors ble.AssignedNumbersFile. It's declared here
+// rather than imported so a broken embedded database can always be regenerated.
+type assignedNumbersFile struct {
+	CompanyIdentifiers  map[string]string                  `json:"company_identifiers,omitempty"`
+	ServiceUUIDs        map[string]string                  `json:"service_uuids,omitempty"`
+	MemberUUIDs         map[string]string                  `json:"member_uuids,omitempty"`
+	CharacteristicUUIDs map[string]string                  `json:"characteristic_uuids,omitempty"`
+	Appearance          map[string]appearanceCategoryEntry `json:"appearance,omitempty"`
+}
+
+type appearanceCategoryEntry struct {
+	Name          string            `json:"name"`
+	Subcategories map[string]string `json:"subcategories,omitempty"`
+}
+
+type companyIdentifiersYAML struct {
+	CompanyIdentifiers []struct {
+		Value uint16 `yaml:"value"`
+		Name  string `yaml:"name"`
+	} `yaml:"company_identifiers"`
+}
+
+type uuidsYAML struct {
+	UUIDs []struct {
+		UUID uint16 `yaml:"uuid"`
+		Name string `yaml:"name"`
+	} `yaml:"uuids"`
+}
+
+type appearanceYAML struct {
+	AppearanceValues []struct {
+		Category    uint16 `yaml:"category"`
+		Name        string `yaml:"name"`
+		Subcategory []struct {
+			Value uint16 `yaml:"value"`
+			Name  string `yaml:"name"`
+		} `yaml:"subcategory"`
+	} `yaml:"appearance_values"`
+}
+
+func main() {
+	src := flag.String("src", defaultSource, "base URL or local directory of the SIG assigned_numbers tree")
+	out := flag.String("o", "assigned_numbers.json", "output file")
+	flag.Parse()
+
+	db, err := generate(*src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gen-assigned-numbers: %v\n", err)
+		os.Exit(1)
+	}
+
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gen-assigned-numbers: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*out, append(data, '\n'), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "gen-assigned-numbers: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("wrote %s: %d companies, %d services, %d member UUIDs, %d characteristics, %d appearance categories\n",
+		*out, len(db.CompanyIdentifiers), len(db.ServiceUUIDs), len(db.MemberUUIDs),
+		len(db.CharacteristicUUIDs), len(db.Appearance))
+}
+
+func generate(src string) (assignedNumbersFile, error) {
+	db := assignedNumbersFile{
+		CompanyIdentifiers:  make(map[string]string),
+		ServiceUUIDs:        make(map[string]string),
+		MemberUUIDs:         make(map[string]string),
+		CharacteristicUUIDs: make(map[string]string),
+		Appearance:          make(map[string]appearanceCategoryEntry),
+	}
+
+	var companies companyIdentifiersYAML
+	if err := load(src, companyIdentifiersFile, &companies); err != nil {
+		return db, err
+	}
+	for _, c := range companies.CompanyIdentifiers {
+		db.CompanyIdentifiers[fmt.Sprintf("0x%04X", c.Value)] = c.Name
+	}
+
+	for file, dst := range map[string]map[string]string{
+		serviceUUIDsFile:        db.ServiceUUIDs,
+		memberUUIDsFile:         db.MemberUUIDs,
+		characteristicUUIDsFile: db.CharacteristicUUIDs,
+	} {
+		var uuids uuidsYAML
+		if err := load(src, file, &uuids); err != nil {
+			return db, err
+		}
+		for _, u := range uuids.UUIDs {
+			dst[fmt.Sprintf("0x%04X", u.UUID)] = u.Name
+		}
+	}
+
+	var appearance appearanceYAML
+	if err := load(src, appearanceValuesFile, &appearance); err != nil {
+		return db, err
+	}
+	for _, a := range appearance.AppearanceValues {
+		entry := appearanceCategoryEntry{Name: a.Name}
+		if len(a.Subcategory) > 0 {
+			entry.Subcategories = make(map[string]string, len(a.Subcategory))
+			for _, s := range a.Subcategory {
+				entry.Subcategories[fmt.Sprintf("0x%02X", s.Value)] = s.Name
+			}
+		}
+		db.Appearance[fmt.Sprintf("0x%03X", a.Category)] = entry
+	}
+
+	return db, nil
+}
+
+// load reads a YAML file from a URL or local directory and decodes it into v
+func load(src, file string, v interface{}) error {
+	var r io.ReadCloser
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		client := &http.Client{Timeout: 30 * time.Second}
+		resp, err := client.Get(strings.TrimRight(src, "/") + "/" + file)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return fmt.Errorf("%s: %s", file, resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(filepath.Join(src, filepath.FromSlash(file)))
+		if err != nil {
+			return err
+		}
+		r = f
+	}
+	defer r.Close()
+
+	if err := yaml.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Command gen-oui regenerates the embedded IEEE OUI vendor table
+// (internal/ble/oui.txt) from the MA-L registry the IEEE publishes as CSV.
+//
+// Usage:
+//
+//	go generate ./internal/ble
+//	go run ./cmd/gen-oui -src /path/to/oui.csv -o oui.txt
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultSource = "https://standards-oui.ieee.org/oui/oui.csv"
+
+func main() {
+	src := flag.String("src", defaultSource, "URL or local path of the IEEE MA-L registry CSV")
+	out := flag.String("o", "oui.txt", "output file")
+	flag.Parse()
+
+	vendors, err := generate(*src)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gen-oui: %v\n", err)
+		os.Exit(1)
+	}
+	if err := write(*out, *src, vendors); err != nil {
+		fmt.Fprintf(os.Stderr, "gen-oui: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("wrote %s: %d OUIs\n", *out, len(vendors))
+}
+
+// generate reads the registry and returns the organization name of each OUI,
+// keyed by its six upper-case hex digits
+func generate(src string) (map[string]string, error) {
+	r, err := open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	// Registry,Assignment,Organization Name,Organization Address
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", src, err)
+	}
+	assignment, organization := -1, -1
+	for i, h := range header {
+		switch strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")) {
+		case "Assignment":
+			assignment = i
+		case "Organization Name":
+			organization = i
+		}
+	}
+	if assignment < 0 || organization < 0 {
+		return nil, fmt.Errorf("%s: missing Assignment or Organization Name column", src)
+	}
+
+	vendors := make(map[string]string)
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", src, err)
+		}
+		if len(rec) <= assignment || len(rec) <= organization {
+			continue
+		}
+		prefix := strings.ToUpper(strings.TrimSpace(rec[assignment]))
+		if b, err := hex.DecodeString(prefix); err != nil || len(b) != 3 {
+			continue
+		}
+		// Tabs and newlines would break the one-line-per-OUI format
+		name := strings.Join(strings.Fields(rec[organization]), " ")
+		if name == "" {
+			continue
+		}
+		vendors[prefix] = name
+	}
+	return vendors, nil
+}
+
+// open returns the registry from a URL or local file
+func open(src string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return os.Open(src)
+	}
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Get(src)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", src, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// write stores the table sorted by OUI in the format ble.loadOUIs reads
+func write(path, src string, vendors map[string]string) error {
+	prefixes := make([]string, 0, len(vendors))
+	for p := range vendors {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "# IEEE MA-L (OUI) assignments, generated by cmd/gen-oui.\n")
+	fmt.Fprintf(w, "# Source: %s\n", src)
+	fmt.Fprintf(w, "# Format: <6 hex digits><TAB><organization name>\n")
+	for _, p := range prefixes {
+		fmt.Fprintf(w, "%s\t%s\n", p, vendors[p])
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
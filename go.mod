@@ -6,6 +6,7 @@ require (
 	github.com/charmbracelet/bubbles v0.20.0
 	github.com/charmbracelet/bubbletea v1.2.4
 	github.com/charmbracelet/lipgloss v1.0.0
+	gopkg.in/yaml.v3 v3.0.1
 	tinygo.org/x/bluetooth v0.10.0
 )
 
@@ -0,0 +1,338 @@
+// Package annotate extracts user-defined fields from advertisement payloads.
+// A field names a byte range of the manufacturer data of one company or the
+// service data of one UUID, so it applies to every device of that kind while
+// there is no proper decoder for the protocol.
+package annotate
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/buckleypaul/blescan/internal/ble"
+)
+
+// Field value types: signedness, width and byte order
+const (
+	U8    = "u8"
+	I8    = "i8"
+	U16LE = "u16le"
+	U16BE = "u16be"
+	I16LE = "i16le"
+	I16BE = "i16be"
+	U32LE = "u32le"
+	U32BE = "u32be"
+	I32LE = "i32le"
+	I32BE = "i32be"
+)
+
+// Types lists the field types, narrowest first
+var Types = []string{U8, I8, U16LE, U16BE, I16LE, I16BE, U32LE, U32BE, I32LE, I32BE}
+
+// TypeSize returns the width of a field type in bytes
+func TypeSize(t string) (int, bool) {
+	switch t {
+	case U8, I8:
+		return 1, true
+	case U16LE, U16BE, I16LE, I16BE:
+		return 2, true
+	case U32LE, U32BE, I32LE, I32BE:
+		return 4, true
+	}
+	return 0, false
+}
+
+// ParseType accepts a type as listed in Types or spelled out, such as
+// "int16 LE" or "uint32be". Multi-byte types without a byte order are
+// little endian, the order of BLE itself.
+func ParseType(s string) (string, error) {
+	t := strings.ToLower(strings.Join(strings.Fields(s), ""))
+	t = strings.Replace(t, "uint", "u", 1)
+	t = strings.Replace(t, "int", "i", 1)
+	if _, ok := TypeSize(t); ok {
+		return t, nil
+	}
+	if size, ok := TypeSize(t + "le"); ok && size > 1 {
+		return t + "le", nil
+	}
+	return "", fmt.Errorf("unknown type %q (want one of %s)", s, strings.Join(Types, ", "))
+}
+
+// Field is a named value at a fixed position of a payload
+type Field struct {
+	Source  string  `json:"source"` // ManufacturerSource or ServiceSource of the payload
+	Name    string  `json:"name"`
+	Offset  int     `json:"offset"` // Byte offset; manufacturer data starts with the company ID
+	Type    string  `json:"type"`
+	Divisor float64 `json:"divisor,omitempty"` // The raw value is divided by this, 1 if unset
+	Unit    string  `json:"unit,omitempty"`
+}
+
+// Validate checks that the field can be extracted
+func (f Field) Validate() error {
+	if f.Source == "" {
+		return fmt.Errorf("field %q has no source", f.Name)
+	}
+	if strings.TrimSpace(f.Name) == "" {
+		return fmt.Errorf("field has no name")
+	}
+	if f.Offset < 0 {
+		return fmt.Errorf("field %q: negative offset", f.Name)
+	}
+	if _, ok := TypeSize(f.Type); !ok {
+		return fmt.Errorf("field %q: unknown type %q", f.Name, f.Type)
+	}
+	if f.Divisor < 0 || math.IsNaN(f.Divisor) || math.IsInf(f.Divisor, 0) {
+		return fmt.Errorf("field %q: divisor must be positive", f.Name)
+	}
+	return nil
+}
+
+// Size returns the width of the field in bytes
+func (f Field) Size() int {
+	size, _ := TypeSize(f.Type)
+	return size
+}
+
+// Covers reports whether the field includes the byte at offset
+func (f Field) Covers(offset int) bool {
+	return offset >= f.Offset && offset < f.Offset+f.Size()
+}
+
+// Extract decodes the field from a payload of its source
+func (f Field) Extract(data []byte) (float64, bool) {
+	if f.Offset < 0 || f.Offset+f.Size() > len(data) {
+		return 0, false
+	}
+	b := data[f.Offset:]
+	var v float64
+	switch f.Type {
+	case U8:
+		v = float64(b[0])
+	case I8:
+		v = float64(int8(b[0]))
+	case U16LE:
+		v = float64(binary.LittleEndian.Uint16(b))
+	case U16BE:
+		v = float64(binary.BigEndian.Uint16(b))
+	case I16LE:
+		v = float64(int16(binary.LittleEndian.Uint16(b)))
+	case I16BE:
+		v = float64(int16(binary.BigEndian.Uint16(b)))
+	case U32LE:
+		v = float64(binary.LittleEndian.Uint32(b))
+	case U32BE:
+		v = float64(binary.BigEndian.Uint32(b))
+	case I32LE:
+		v = float64(int32(binary.LittleEndian.Uint32(b)))
+	case I32BE:
+		v = float64(int32(binary.BigEndian.Uint32(b)))
+	default:
+		return 0, false
+	}
+	if f.Divisor > 0 {
+		v /= f.Divisor
+	}
+	return v, true
+}
+
+// Format formats a value of the field with as many decimals as the divisor
+// calls for, followed by the unit
+func (f Field) Format(v float64) string {
+	decimals := 0
+	if f.Divisor > 1 {
+		decimals = int(math.Ceil(math.Log10(f.Divisor)))
+	}
+	s := fmt.Sprintf("%.*f", decimals, v)
+	if f.Unit != "" {
+		s += " " + f.Unit
+	}
+	return s
+}
+
+// Payload returns the payload of the field's source in an advertisement
+func (f Field) Payload(adv ble.Advertisement) ([]byte, bool) {
+	if data := adv.ManufacturerData; len(data) >= 2 {
+		if ManufacturerSource(uint16(data[0])|uint16(data[1])<<8) == f.Source {
+			return data, true
+		}
+	}
+	for uuid, data := range adv.ServiceData {
+		if ServiceSource(uuid) == f.Source {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
+// Value extracts the field from an advertisement
+func (f Field) Value(adv ble.Advertisement) (float64, bool) {
+	data, ok := f.Payload(adv)
+	if !ok {
+		return 0, false
+	}
+	return f.Extract(data)
+}
+
+// ManufacturerSource returns the source of manufacturer data of a company
+func ManufacturerSource(companyID uint16) string {
+	return fmt.Sprintf("company:0x%04X", companyID)
+}
+
+// ServiceSource returns the source of service data of a UUID, in the same
+// form however the UUID is written
+func ServiceSource(uuid string) string {
+	if short, ok := ble.ShortUUID(uuid); ok {
+		return fmt.Sprintf("service:0x%04X", short)
+	}
+	return "service:" + strings.ToLower(uuid)
+}
+
+// Sample is a field's value in one advertisement
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series extracts a field from every advertisement that carries it
+func Series(advs []ble.Advertisement, f Field) []Sample {
+	var samples []Sample
+	for _, adv := range advs {
+		if v, ok := f.Value(adv); ok {
+			samples = append(samples, Sample{Time: adv.Timestamp, Value: v})
+		}
+	}
+	return samples
+}
+
+// Latest returns a field's value in the newest advertisement carrying it
+func Latest(d *ble.Device, f Field) (float64, bool) {
+	for i := len(d.Advertisements) - 1; i >= 0; i-- {
+		if v, ok := f.Value(d.Advertisements[i]); ok {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// File is the on-disk format of the annotations
+type File struct {
+	Fields []Field `json:"fields"`
+}
+
+var (
+	mu     sync.RWMutex
+	fields []Field
+)
+
+// Load replaces the annotations with those in a file
+func Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, field := range f.Fields {
+		if err := field.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	mu.Lock()
+	fields = f.Fields
+	mu.Unlock()
+	return nil
+}
+
+// Save writes the annotations to a file
+func Save(path string) error {
+	data, err := json.MarshalIndent(File{Fields: Fields()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Fields returns every annotation, ordered by source and offset
+func Fields() []Field {
+	mu.RLock()
+	defer mu.RUnlock()
+	return append([]Field(nil), fields...)
+}
+
+// Add adds a field, replacing one of the same name for the same source
+func Add(f Field) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	replaced := false
+	for i, existing := range fields {
+		if existing.Source == f.Source && existing.Name == f.Name {
+			fields[i] = f
+			replaced = true
+		}
+	}
+	if !replaced {
+		fields = append(fields, f)
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		if fields[i].Source != fields[j].Source {
+			return fields[i].Source < fields[j].Source
+		}
+		return fields[i].Offset < fields[j].Offset
+	})
+	return nil
+}
+
+// Remove deletes a field, reporting whether it existed
+func Remove(source, name string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	for i, f := range fields {
+		if f.Source == source && f.Name == name {
+			fields = append(fields[:i], fields[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ForSource returns the fields of one source, ordered by offset
+func ForSource(source string) []Field {
+	var matched []Field
+	for _, f := range Fields() {
+		if f.Source == source {
+			matched = append(matched, f)
+		}
+	}
+	return matched
+}
+
+// ForDevice returns the fields of every payload source the device has
+// advertised
+func ForDevice(d *ble.Device) []Field {
+	sources := make(map[string]bool)
+	if d.ManufacturerID != nil {
+		sources[ManufacturerSource(*d.ManufacturerID)] = true
+	}
+	for uuid := range d.ServiceData {
+		sources[ServiceSource(uuid)] = true
+	}
+	var matched []Field
+	for _, f := range Fields() {
+		if sources[f.Source] {
+			matched = append(matched, f)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,184 @@
+package annotate
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/buckleypaul/blescan/internal/ble"
+)
+
+func TestParseType(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "u8", want: U8},
+		{in: "int8", want: I8},
+		{in: "uint16", want: U16LE},
+		{in: "int16 BE", want: I16BE},
+		{in: "UInt32be", want: U32BE},
+		{in: "i32", want: I32LE},
+		{in: "u8le", wantErr: true},
+		{in: "float", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseType(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseType(%q) = %q, %v, want %q (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	data := []byte{0x4C, 0x00, 0xFE, 0x01, 0x80, 0x00, 0x00, 0xFF}
+	tests := []struct {
+		typ     string
+		offset  int
+		divisor float64
+		want    float64
+		ok      bool
+	}{
+		{typ: U8, offset: 2, want: 254, ok: true},
+		{typ: I8, offset: 2, want: -2, ok: true},
+		{typ: U16LE, offset: 2, want: 0x01FE, ok: true},
+		{typ: U16BE, offset: 2, want: 0xFE01, ok: true},
+		{typ: I16LE, offset: 3, want: -32767, ok: true},
+		{typ: I16BE, offset: 2, want: -511, ok: true},
+		{typ: U32LE, offset: 4, want: 0xFF000080, ok: true},
+		{typ: U32BE, offset: 4, want: 0x800000FF, ok: true},
+		{typ: I32LE, offset: 4, want: -16777088, ok: true},
+		{typ: I32BE, offset: 4, want: -2147483393, ok: true},
+		{typ: U16LE, offset: 2, divisor: 100, want: 5.10, ok: true},
+		{typ: U32LE, offset: 5},
+		{typ: U8, offset: 8},
+		{typ: "f32", offset: 0},
+	}
+	for _, tt := range tests {
+		f := Field{Source: ManufacturerSource(0x004C), Name: "x", Offset: tt.offset, Type: tt.typ, Divisor: tt.divisor}
+		got, ok := f.Extract(data)
+		if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s at %d = %v, %v, want %v, %v", tt.typ, tt.offset, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFieldValidateAndFormat(t *testing.T) {
+	valid := Field{Source: "company:0x004C", Name: "Temperature", Offset: 2, Type: I16LE, Divisor: 100, Unit: "°C"}
+	tests := []struct {
+		name    string
+		change  func(f *Field)
+		wantErr bool
+	}{
+		{name: "valid", change: func(f *Field) {}},
+		{name: "no source", change: func(f *Field) { f.Source = "" }, wantErr: true},
+		{name: "blank name", change: func(f *Field) { f.Name = " " }, wantErr: true},
+		{name: "negative offset", change: func(f *Field) { f.Offset = -1 }, wantErr: true},
+		{name: "unknown type", change: func(f *Field) { f.Type = "u24" }, wantErr: true},
+		{name: "negative divisor", change: func(f *Field) { f.Divisor = -1 }, wantErr: true},
+		{name: "infinite divisor", change: func(f *Field) { f.Divisor = math.Inf(1) }, wantErr: true},
+		{name: "no divisor", change: func(f *Field) { f.Divisor = 0 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := valid
+			tt.change(&f)
+			if err := f.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if got := valid.Format(21.456); got != "21.46 °C" {
+		t.Errorf("Format = %q, want \"21.46 °C\"", got)
+	}
+	if got := (Field{Type: U8, Divisor: 10}).Format(3.25); got != "3.2" {
+		t.Errorf("Format = %q, want \"3.2\"", got)
+	}
+	if got := (Field{Type: U8}).Format(7); got != "7" {
+		t.Errorf("Format = %q, want \"7\"", got)
+	}
+	if !valid.Covers(3) || valid.Covers(4) || valid.Covers(1) {
+		t.Error("Covers doesn't match offsets 2 and 3")
+	}
+}
+
+func TestValue(t *testing.T) {
+	adv := ble.NewAdvertisement()
+	adv.ManufacturerData = []byte{0x4C, 0x00, 0x2A}
+	adv.ServiceData["0000181a-0000-1000-8000-00805f9b34fb"] = []byte{0x10, 0x27}
+
+	tests := []struct {
+		name  string
+		field Field
+		want  float64
+		ok    bool
+	}{
+		{name: "manufacturer data", field: Field{Source: ManufacturerSource(0x004C), Name: "a", Offset: 2, Type: U8}, want: 42, ok: true},
+		{name: "other company", field: Field{Source: ManufacturerSource(0x0006), Name: "a", Offset: 2, Type: U8}},
+		{name: "short service UUID", field: Field{Source: ServiceSource("181A"), Name: "b", Offset: 0, Type: U16LE}, want: 10000, ok: true},
+		{name: "service data too short", field: Field{Source: ServiceSource("181a"), Name: "b", Offset: 1, Type: U16LE}},
+		{name: "other service", field: Field{Source: ServiceSource("180f"), Name: "c", Offset: 0, Type: U8}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.field.Value(adv)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("value = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	t.Cleanup(func() { fields = nil })
+	fields = nil
+
+	battery := Field{Source: ServiceSource("181a"), Name: "Battery", Offset: 4, Type: U8, Unit: "%"}
+	temp := Field{Source: ServiceSource("181a"), Name: "Temperature", Offset: 0, Type: I16LE, Divisor: 100}
+	counter := Field{Source: ManufacturerSource(0x004C), Name: "Counter", Offset: 3, Type: U8}
+	for _, f := range []Field{battery, temp, counter} {
+		if err := Add(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := Add(Field{Source: "company:0x004C"}); err == nil {
+		t.Error("added an invalid field")
+	}
+
+	// Ordered by source, then offset
+	got := Fields()
+	if len(got) != 3 || got[0].Name != "Counter" || got[1].Name != "Temperature" || got[2].Name != "Battery" {
+		t.Fatalf("fields = %+v", got)
+	}
+
+	// Same name and source replaces
+	battery.Offset = 5
+	if err := Add(battery); err != nil {
+		t.Fatal(err)
+	}
+	if s := ForSource(ServiceSource("181a")); len(s) != 2 || s[1].Offset != 5 {
+		t.Errorf("fields of the service = %+v", s)
+	}
+
+	path := filepath.Join(t.TempDir(), "fields.json")
+	if err := Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if !Remove(counter.Source, counter.Name) || Remove(counter.Source, counter.Name) {
+		t.Error("Remove didn't report the field existed exactly once")
+	}
+	if len(Fields()) != 2 {
+		t.Errorf("fields after Remove = %d, want 2", len(Fields()))
+	}
+	if err := Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(Fields()) != 3 {
+		t.Errorf("fields after Load = %d, want 3", len(Fields()))
+	}
+	if err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("loaded a missing file")
+	}
+}
@@ -0,0 +1,142 @@
+package ble
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/hex"
+	"strings"
+)
+
+// AddressType classifies a Bluetooth device address
+type AddressType int
+
+const (
+	AddressTypeUnknown              AddressType = iota // Source doesn't report the address type (e.g. macOS)
+	AddressTypePublic                                  // IEEE-assigned public address
+	AddressTypeRandomStatic                            // Random static (top bits 0b11)
+	AddressTypeResolvablePrivate                       // Resolvable private address (top bits 0b01)
+	AddressTypeNonResolvablePrivate                    // Non-resolvable private address (top bits 0b00)
+	AddressTypeRandomInvalid                           // Random address with reserved top bits 0b10
+)
+
+// String returns a human-readable name for the address type
+func (t AddressType) String() string {
+	switch t {
+	case AddressTypePublic:
+		return "Public"
+	case AddressTypeRandomStatic:
+		return "Random Static"
+	case AddressTypeResolvablePrivate:
+		return "Resolvable Private"
+	case AddressTypeNonResolvablePrivate:
+		return "Non-resolvable Private"
+	case AddressTypeRandomInvalid:
+		return "Random (reserved)"
+	default:
+		return "Unknown"
+	}
+}
+
+// ShortString returns an abbreviated name for list columns and filters
+func (t AddressType) ShortString() string {
+	switch t {
+	case AddressTypePublic:
+		return "public"
+	case AddressTypeRandomStatic:
+		return "static"
+	case AddressTypeResolvablePrivate:
+		return "rpa"
+	case AddressTypeNonResolvablePrivate:
+		return "nrpa"
+	case AddressTypeRandomInvalid:
+		return "random"
+	default:
+		return "-"
+	}
+}
+
+// IsRandom returns true for any of the random address sub-types
+func (t AddressType) IsRandom() bool {
+	return t >= AddressTypeRandomStatic
+}
+
+// ParseAddress parses a colon-separated address string ("AA:BB:CC:DD:EE:FF")
+// into its six bytes, most significant first. It returns false for addresses
+// that aren't MAC addresses, such as the UUIDs CoreBluetooth reports on macOS.
+func ParseAddress(address string) ([6]byte, bool) {
+	var mac [6]byte
+	if len(address) != 17 {
+		return mac, false
+	}
+	b, err := hex.DecodeString(strings.ReplaceAll(address, ":", ""))
+	if err != nil || len(b) != 6 {
+		return mac, false
+	}
+	copy(mac[:], b)
+	return mac, true
+}
+
+// ClassifyAddress returns the address type for an address whose public/random
+// bit (TxAdd, or BlueZ's AddressType) is known. Random addresses are further
+// classified from their two most significant bits.
+func ClassifyAddress(address string, random bool) AddressType {
+	mac, ok := ParseAddress(address)
+	if !ok {
+		return AddressTypeUnknown
+	}
+	if !random {
+		return AddressTypePublic
+	}
+	switch mac[0] >> 6 {
+	case 0b11:
+		return AddressTypeRandomStatic
+	case 0b01:
+		return AddressTypeResolvablePrivate
+	case 0b00:
+		return AddressTypeNonResolvablePrivate
+	default:
+		return AddressTypeRandomInvalid
+	}
+}
+
+//go:generate go run ../../cmd/gen-oui -o oui.txt
+
+// ouiTXT maps IEEE OUIs to vendor names, one "XXXXXX<TAB>Vendor" per line,
+// generated from the IEEE MA-L registry by cmd/gen-oui
+//
+//go:embed oui.txt
+var ouiTXT []byte
+
+var ouiVendors = loadOUIs(ouiTXT)
+
+func loadOUIs(data []byte) map[[3]byte]string {
+	vendors := make(map[[3]byte]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		prefix, vendor, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		b, err := hex.DecodeString(prefix)
+		if err != nil || len(b) != 3 {
+			continue
+		}
+		vendors[[3]byte{b[0], b[1], b[2]}] = vendor
+	}
+	return vendors
+}
+
+// LookupOUI returns the IEEE-registered vendor of a public address
+func LookupOUI(address string) (string, bool) {
+	mac, ok := ParseAddress(address)
+	if !ok {
+		return "", false
+	}
+	vendor, ok := ouiVendors[[3]byte{mac[0], mac[1], mac[2]}]
+	return vendor, ok
+}
@@ -0,0 +1,145 @@
+package ble
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Maximum advertising data lengths
+const (
+	MaxLegacyAdvData   = 31   // Legacy ADV_IND/SCAN_RSP payload
+	MaxExtendedAdvData = 1650 // Extended advertising data after reassembly
+)
+
+// ADStructure is a single [length][type][data] element of advertising data
+type ADStructure struct {
+	Offset int // Offset of the length octet within the raw data
+	Type   uint8
+	Data   []byte
+}
+
+// ParseADStructures splits raw advertising data into AD structures. Parsing
+// stops at the first zero length octet (the start of zero padding). If an AD
+// structure's length runs past the end of the buffer, the structures parsed
+// so far are returned along with an error.
+func ParseADStructures(raw []byte) ([]ADStructure, error) {
+	var structures []ADStructure
+	offset := 0
+	for offset < len(raw) {
+		length := int(raw[offset])
+		if length == 0 {
+			break // Zero padding
+		}
+		if offset+1+length > len(raw) {
+			return structures, fmt.Errorf("AD structure at offset %d: length %d runs past end of data (%d bytes)",
+				offset, length, len(raw))
+		}
+		structures = append(structures, ADStructure{
+			Offset: offset,
+			Type:   raw[offset+1],
+			Data:   raw[offset+2 : offset+1+length],
+		})
+		offset += 1 + length
+	}
+	return structures, nil
+}
+
+// DecodeRawData populates the advertisement's fields from RawData. It is used
+// for sources that deliver raw advertising data (HCI captures) rather than
+// pre-parsed fields.
+func (a *Advertisement) DecodeRawData() {
+	structures, _ := ParseADStructures(a.RawData)
+	a.applyADStructures(structures)
+}
+
+func (a *Advertisement) applyADStructures(structures []ADStructure) {
+	if a.ServiceData == nil {
+		a.ServiceData = make(map[string][]byte)
+	}
+
+	for _, ad := range structures {
+		a.ADTypes = append(a.ADTypes, ad.Type)
+		data := ad.Data
+
+		switch ad.Type {
+		case 0x01: // Flags
+			if len(data) >= 1 {
+				flags := data[0]
+				a.Flags = &flags
+			}
+		case 0x02, 0x03: // 16-bit Service UUIDs
+			for i := 0; i+2 <= len(data); i += 2 {
+				a.ServiceUUIDs = append(a.ServiceUUIDs, uuid16String(binary.LittleEndian.Uint16(data[i:])))
+			}
+		case 0x04, 0x05: // 32-bit Service UUIDs
+			for i := 0; i+4 <= len(data); i += 4 {
+				a.ServiceUUIDs = append(a.ServiceUUIDs, uuid32String(binary.LittleEndian.Uint32(data[i:])))
+			}
+		case 0x06, 0x07: // 128-bit Service UUIDs
+			for i := 0; i+16 <= len(data); i += 16 {
+				a.ServiceUUIDs = append(a.ServiceUUIDs, uuid128String(data[i:i+16]))
+			}
+		case 0x08: // Shortened Local Name
+			if a.LocalName == "" {
+				a.LocalName = string(data)
+			}
+		case 0x09: // Complete Local Name
+			a.LocalName = string(data)
+		case 0x0A: // TX Power Level
+			if len(data) >= 1 {
+				tx := int8(data[0])
+				a.TxPowerLevel = &tx
+			}
+		case 0x16: // Service Data - 16-bit UUID
+			if len(data) >= 2 {
+				a.ServiceData[uuid16String(binary.LittleEndian.Uint16(data))] = data[2:]
+			}
+		case 0x19: // Appearance
+			if len(data) >= 2 {
+				appearance := binary.LittleEndian.Uint16(data)
+				a.Appearance = &appearance
+			}
+		case 0x20: // Service Data - 32-bit UUID
+			if len(data) >= 4 {
+				a.ServiceData[uuid32String(binary.LittleEndian.Uint32(data))] = data[4:]
+			}
+		case 0x21: // Service Data - 128-bit UUID
+			if len(data) >= 16 {
+				a.ServiceData[uuid128String(data[:16])] = data[16:]
+			}
+		case 0xFF: // Manufacturer Specific Data (first entry, matching the scanner)
+			if len(data) >= 2 && a.ManufacturerData == nil {
+				a.ManufacturerData = data
+			}
+		}
+	}
+}
+
+// FindAD returns the data of the first AD structure of the given type in RawData
+func (a *Advertisement) FindAD(adType uint8) ([]byte, bool) {
+	structures, _ := ParseADStructures(a.RawData)
+	for _, ad := range structures {
+		if ad.Type == adType {
+			return ad.Data, true
+		}
+	}
+	return nil, false
+}
+
+// uuid16String formats a 16-bit UUID in the same form as tinygo's UUID.String
+func uuid16String(v uint16) string {
+	return uuid32String(uint32(v))
+}
+
+func uuid32String(v uint32) string {
+	return fmt.Sprintf("%08x%s", v, bluetoothBaseUUIDSuffix)
+}
+
+// uuid128String formats a little-endian 128-bit UUID
+func uuid128String(le []byte) string {
+	var b [16]byte
+	for i := 0; i < 16; i++ {
+		b[i] = le[15-i]
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
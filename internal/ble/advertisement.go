@@ -9,6 +9,8 @@ import (
 // Advertisement represents a single advertisement packet
 type Advertisement struct {
 	Timestamp        time.Time
+	Address          string
+	AddressType      AddressType
 	RSSI             int16
 	RawData          []byte
 	ManufacturerData []byte
@@ -19,7 +21,11 @@ type Advertisement struct {
 	Connectable      bool
 	Flags            *uint8
 	Appearance       *uint16
-	ADTypes          []uint8 // All AD type codes in this advertisement
+	ADTypes          []uint8          // All AD type codes in this advertisement
+	ScanResponse     bool             // Data came from a scan response
+	Extended         *ExtendedAdvInfo // Extended advertising fields, nil for legacy PDUs
+	EAD              EADStatus        // Encrypted Data status
+	DecryptedData    []byte           // Plaintext AD structures from Encrypted Data, if decrypted
 }
 
 // NewAdvertisement creates a new Advertisement with the current timestamp
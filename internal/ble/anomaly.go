@@ -0,0 +1,310 @@
+package ble
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Spoofing and cloned-device detection. Each device learns a behavioural
+// baseline over its first advertisements; later advertisements that break it
+// in ways a single genuine transmitter can't are logged as anomalies.
+
+const (
+	// Advertisements used to learn a device's baseline
+	baselineAdverts = 20
+	// Payload layouts remembered per device
+	maxBaselineLayouts = 8
+	// Anomalies kept per device
+	maxDeviceAnomalies = 50
+	// Anomalies kept in the scanner-wide log
+	maxAnomalyLog = 10000
+	// The same kind of anomaly is reported at most once per this period
+	anomalyHoldoff = 30 * time.Second
+
+	// RSSI change that no single transmitter produces between two
+	// advertisements heard within rssiJumpWindow
+	rssiJumpThreshold = 30
+	rssiJumpWindow    = time.Second
+
+	// How far outside the learned RSSI range an advertisement has to be.
+	// Walking a device across a room moves it by less.
+	rssiRangeMargin = 20
+
+	// Factor by which the advertising interval has to move away from the
+	// baseline before it counts as a change
+	intervalChangeRatio = 2.0
+
+	// Intervals examined for two interleaved transmitters
+	interleaveIntervals = 30
+)
+
+// Anomaly kinds
+const (
+	AnomalyLayout     = "layout-change"
+	AnomalyName       = "name-change"
+	AnomalyInterleave = "interleaved-intervals"
+	AnomalyRSSIJump   = "rssi-jump"
+	AnomalyRSSIRange  = "rssi-out-of-range"
+	AnomalyInterval   = "interval-change"
+)
+
+// Anomaly is a departure from a device's baseline behaviour
+type Anomaly struct {
+	Time     time.Time `json:"time"`
+	DeviceID string    `json:"device_id"`
+	Address  string    `json:"address"`
+	Name     string    `json:"name,omitempty"`
+	Kind     string    `json:"kind"`
+	Message  string    `json:"message"`
+}
+
+// Baseline is a device's learned normal behaviour
+type Baseline struct {
+	Established bool
+	Samples     int           // Advertisements learned from
+	Layouts     []string      // Payload layouts (structure without contents) seen
+	Name        string        // Advertised name, if any
+	Interval    time.Duration // Median advertising interval when learning finished
+	RSSIMin     int16
+	RSSIMax     int16
+
+	reported map[string]time.Time // Last report of each anomaly kind
+}
+
+// hasLayout reports whether the layout is part of the baseline
+func (b *Baseline) hasLayout(layout string) bool {
+	for _, l := range b.Layouts {
+		if l == layout {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Baseline) addLayout(layout string) {
+	if !b.hasLayout(layout) && len(b.Layouts) < maxBaselineLayouts {
+		b.Layouts = append(b.Layouts, layout)
+	}
+}
+
+// learn adds an advertisement to a baseline that isn't established yet
+func (b *Baseline) learn(adv Advertisement, layout string) {
+	if layout != "" {
+		b.addLayout(layout)
+	}
+	if adv.LocalName != "" {
+		b.Name = adv.LocalName
+	}
+	if b.Samples == 0 || adv.RSSI < b.RSSIMin {
+		b.RSSIMin = adv.RSSI
+	}
+	if b.Samples == 0 || adv.RSSI > b.RSSIMax {
+		b.RSSIMax = adv.RSSI
+	}
+	b.Samples++
+}
+
+// detectAnomalies compares an advertisement with the device's baseline,
+// learning the baseline first if needed. Called with d.mu held, before the
+// advertisement is applied to the device.
+func (d *Device) detectAnomalies(adv Advertisement, lastSeen time.Time) {
+	if d.Baseline == nil {
+		d.Baseline = &Baseline{reported: make(map[string]time.Time)}
+	}
+	b := d.Baseline
+	layout, _ := adv.payloadTemplate()
+
+	// Signal strength can't swing this far between two adverts from one
+	// transmitter; two transmitters at different distances can
+	if len(d.RSSIHistory) >= 5 && adv.Timestamp.Sub(lastSeen) < rssiJumpWindow {
+		if median := medianRSSI(d.RSSIHistory); absInt(int(adv.RSSI)-int(median)) >= rssiJumpThreshold {
+			d.reportAnomaly(adv, AnomalyRSSIJump, fmt.Sprintf("RSSI jumped to %d dBm from a median of %d dBm within %s", adv.RSSI, median, formatGap(adv.Timestamp.Sub(lastSeen))))
+		}
+	}
+
+	if !b.Established {
+		b.learn(adv, layout)
+		if b.Samples >= baselineAdverts {
+			b.Established = true
+			b.Interval = d.AdvInterval
+		}
+		return
+	}
+
+	if layout != "" && !b.hasLayout(layout) {
+		d.reportAnomaly(adv, AnomalyLayout, fmt.Sprintf("payload layout changed to %s (baseline %s)", layout, formatLayouts(b.Layouts)))
+		b.addLayout(layout)
+	}
+
+	if adv.LocalName != "" && b.Name != "" && adv.LocalName != b.Name {
+		d.reportAnomaly(adv, AnomalyName, fmt.Sprintf("name changed to %q (baseline %q)", adv.LocalName, b.Name))
+	}
+
+	if int(adv.RSSI) > int(b.RSSIMax)+rssiRangeMargin || int(adv.RSSI) < int(b.RSSIMin)-rssiRangeMargin {
+		d.reportAnomaly(adv, AnomalyRSSIRange, fmt.Sprintf("RSSI %d dBm is outside the baseline range of %d to %d dBm", adv.RSSI, b.RSSIMin, b.RSSIMax))
+	}
+}
+
+// detectIntervalChange compares the device's advertising interval with the
+// baseline. Called with d.mu held, after the interval has been recalculated.
+func (d *Device) detectIntervalChange(adv Advertisement) {
+	b := d.Baseline
+	if b == nil || !b.Established || d.AdvInterval == 0 {
+		return
+	}
+	// Too few advertisements to know the interval when learning finished
+	if b.Interval == 0 {
+		b.Interval = d.AdvInterval
+		return
+	}
+	ratio := float64(d.AdvInterval) / float64(b.Interval)
+	if ratio >= intervalChangeRatio || ratio <= 1/intervalChangeRatio {
+		d.reportAnomaly(adv, AnomalyInterval, fmt.Sprintf("advertising interval changed to %s (baseline %s)", formatGap(d.AdvInterval), formatGap(b.Interval)))
+	}
+}
+
+// detectInterleaving looks for two transmitters sharing the device's address.
+// Called with d.mu held, after the advertisement has been stored.
+func (d *Device) detectInterleaving(adv Advertisement) {
+	if d.Baseline == nil || !d.Baseline.Established {
+		return
+	}
+	if short, long, ok := interleavedIntervals(d.recentAdvertisements(interleaveIntervals + 1)); ok {
+		d.reportAnomaly(adv, AnomalyInterleave, fmt.Sprintf("intervals alternate between %s and %s: two transmitters may share this address", formatGap(short), formatGap(long)))
+	}
+}
+
+// interleavedIntervals reports whether the gaps between advertisements
+// alternate between two clusters that aren't multiples of each other, the
+// pattern of two transmitters advertising at the same rate with a phase
+// offset. A single transmitter with missed packets produces gaps that are
+// multiples of its interval instead.
+func interleavedIntervals(ads []Advertisement) (short, long time.Duration, ok bool) {
+	var intervals []time.Duration
+	for i := 1; i < len(ads); i++ {
+		gap := ads[i].Timestamp.Sub(ads[i-1].Timestamp)
+		if gap >= 10*time.Millisecond && gap < 10*time.Second {
+			intervals = append(intervals, gap)
+		}
+	}
+	if len(intervals) < interleaveIntervals/2 {
+		return 0, 0, false
+	}
+
+	// Split at the largest ratio between consecutive sorted gaps
+	sorted := append([]time.Duration(nil), intervals...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	split, ratio := 0, 0.0
+	for i := 1; i < len(sorted); i++ {
+		if r := float64(sorted[i]) / float64(sorted[i-1]); r > ratio {
+			split, ratio = i, r
+		}
+	}
+	if ratio < 1.5 || split*10 < len(sorted)*3 || (len(sorted)-split)*10 < len(sorted)*3 {
+		return 0, 0, false
+	}
+	short = medianDuration(sorted[:split])
+	long = medianDuration(sorted[split:])
+
+	// Missed packets: long gaps are a whole multiple of short ones
+	multiple := float64(long) / float64(short)
+	if whole := float64(int(multiple + 0.5)); whole >= 2 && multiple > whole*0.85 && multiple < whole*1.15 {
+		return 0, 0, false
+	}
+
+	// The clusters must alternate, not come in runs
+	threshold := sorted[split]
+	alternations := 0
+	for i := 1; i < len(intervals); i++ {
+		if (intervals[i] >= threshold) != (intervals[i-1] >= threshold) {
+			alternations++
+		}
+	}
+	if alternations*10 < (len(intervals)-1)*7 {
+		return 0, 0, false
+	}
+	return short, long, true
+}
+
+// reportAnomaly records an anomaly unless one of the same kind was reported
+// recently. Called with d.mu held.
+func (d *Device) reportAnomaly(adv Advertisement, kind, message string) {
+	if last, ok := d.Baseline.reported[kind]; ok && adv.Timestamp.Sub(last) < anomalyHoldoff {
+		return
+	}
+	d.Baseline.reported[kind] = adv.Timestamp
+
+	name := d.Name
+	if name == "" {
+		name = d.IdentityName
+	}
+	a := Anomaly{
+		Time:     adv.Timestamp,
+		DeviceID: d.ID,
+		Address:  adv.Address,
+		Name:     name,
+		Kind:     kind,
+		Message:  message,
+	}
+	d.AnomalyCount++
+	d.Anomalies = append(d.Anomalies, a)
+	if len(d.Anomalies) > maxDeviceAnomalies {
+		d.Anomalies = d.Anomalies[1:]
+	}
+	d.pendingAnomalies = append(d.pendingAnomalies, a)
+}
+
+// takeAnomalies returns and forgets the anomalies reported since the last call
+func (d *Device) takeAnomalies() []Anomaly {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	a := d.pendingAnomalies
+	d.pendingAnomalies = nil
+	return a
+}
+
+// logAnomalies moves a device's new anomalies into the scanner-wide log.
+// Called with s.mu held.
+func (s *Scanner) logAnomalies(device *Device) {
+	s.anomalies = append(s.anomalies, device.takeAnomalies()...)
+	if n := len(s.anomalies); n > maxAnomalyLog {
+		s.anomalies = s.anomalies[n-maxAnomalyLog:]
+	}
+}
+
+// GetAnomalies returns every anomaly logged this session, oldest first,
+// including those of devices that have since been removed
+func (s *Scanner) GetAnomalies() []Anomaly {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Anomaly(nil), s.anomalies...)
+}
+
+func medianRSSI(values []int16) int16 {
+	sorted := append([]int16(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// formatGap formats a short duration in milliseconds
+func formatGap(d time.Duration) string {
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
+
+func formatLayouts(layouts []string) string {
+	if len(layouts) == 0 {
+		return "none"
+	}
+	if len(layouts) == 1 {
+		return layouts[0]
+	}
+	return fmt.Sprintf("%s and %d more", layouts[0], len(layouts)-1)
+}
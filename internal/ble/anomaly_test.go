@@ -0,0 +1,92 @@
+package ble
+
+import (
+	"testing"
+	"time"
+)
+
+// steadyAdvert is the i-th advertisement of a device advertising every
+// interval with RSSI alternating between -60 and -62 dBm
+func steadyAdvert(start time.Time, i int, interval time.Duration) Advertisement {
+	adv := NewAdvertisement()
+	adv.Address = "C6:11:22:33:44:55"
+	adv.AddressType = AddressTypeRandomStatic
+	adv.Timestamp = start.Add(time.Duration(i) * interval)
+	adv.RSSI = int16(-60 - 2*(i%2))
+	adv.LocalName = "Sensor"
+	return adv
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	tests := []struct {
+		name string
+		// after changes the advertisements from the 40th on
+		after func(adv *Advertisement, i int)
+		want  string // Anomaly kind expected, "" for none
+	}{
+		{
+			name:  "steady device",
+			after: func(adv *Advertisement, i int) {},
+		},
+		{
+			name: "name change",
+			after: func(adv *Advertisement, i int) {
+				adv.LocalName = "Spoof"
+			},
+			want: AnomalyName,
+		},
+		{
+			name: "stronger than the baseline range",
+			after: func(adv *Advertisement, i int) {
+				adv.RSSI = -35
+			},
+			want: AnomalyRSSIRange,
+		},
+		{
+			name: "weaker than the baseline range",
+			after: func(adv *Advertisement, i int) {
+				adv.RSSI = -88
+			},
+			want: AnomalyRSSIRange,
+		},
+		{
+			name: "within the margin",
+			after: func(adv *Advertisement, i int) {
+				adv.RSSI = -75
+			},
+		},
+		{
+			name: "interval tripled",
+			after: func(adv *Advertisement, i int) {
+				adv.Timestamp = start.Add(40*100*time.Millisecond + time.Duration(i-40)*300*time.Millisecond)
+			},
+			want: AnomalyInterval,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDevice("C6:11:22:33:44:55")
+			for i := 0; i < 150; i++ {
+				adv := steadyAdvert(start, i, 100*time.Millisecond)
+				if i >= 40 {
+					tt.after(&adv, i)
+				}
+				d.Update(adv)
+			}
+			if !d.Baseline.Established || d.Baseline.Interval != 100*time.Millisecond {
+				t.Fatalf("baseline established = %v, interval = %v", d.Baseline.Established, d.Baseline.Interval)
+			}
+			kinds := make(map[string]bool)
+			for _, a := range d.Anomalies {
+				kinds[a.Kind] = true
+			}
+			if tt.want == "" && len(kinds) > 0 {
+				t.Fatalf("unexpected anomalies %v", kinds)
+			}
+			if tt.want != "" && (len(kinds) != 1 || !kinds[tt.want]) {
+				t.Fatalf("anomalies %v, want only %s", kinds, tt.want)
+			}
+		})
+	}
+}
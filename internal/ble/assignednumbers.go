@@ -0,0 +1,195 @@
+package ble
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//go:generate go run ../../cmd/gen-assigned-numbers -o assigned_numbers.json
+
+// assignedNumbersJSON is the embedded assigned-numbers database. The copy in
+// the repository is a hand-maintained subset of the Bluetooth SIG tables, not
+// generator output; cmd/gen-assigned-numbers replaces it with the full tables
+// from the SIG's published YAML.
+//
+//go:embed assigned_numbers.json
+var assignedNumbersJSON []byte
+
+// AssignedNumbersFile is the on-disk format of the assigned-numbers database.
+// Keys are hex strings ("0x004C") so the embedded file and user override
+// files stay readable. The same format is used for both.
+type AssignedNumbersFile struct {
+	CompanyIdentifiers  map[string]string                  `json:"company_identifiers,omitempty"`
+	ServiceUUIDs        map[string]string                  `json:"service_uuids,omitempty"`
+	MemberUUIDs         map[string]string                  `json:"member_uuids,omitempty"`
+	CharacteristicUUIDs map[string]string                  `json:"characteristic_uuids,omitempty"`
+	Appearance          map[string]AppearanceCategoryEntry `json:"appearance,omitempty"`
+}
+
+// AppearanceCategoryEntry describes an appearance category and its subcategories
+type AppearanceCategoryEntry struct {
+	Name          string            `json:"name"`
+	Subcategories map[string]string `json:"subcategories,omitempty"`
+}
+
+// assignedNumbers holds the parsed lookup tables
+type assignedNumbers struct {
+	companies       map[uint16]string
+	services        map[uint16]string // 16-bit service class and member UUIDs
+	characteristics map[uint16]string
+	appearance      map[uint16]string // category (10 bits) -> name
+	subappearance   map[uint16]string // full 16-bit appearance value -> subcategory name
+}
+
+var db = mustLoadAssignedNumbers()
+
+func mustLoadAssignedNumbers() *assignedNumbers {
+	a := &assignedNumbers{
+		companies:       make(map[uint16]string),
+		services:        make(map[uint16]string),
+		characteristics: make(map[uint16]string),
+		appearance:      make(map[uint16]string),
+		subappearance:   make(map[uint16]string),
+	}
+	var f AssignedNumbersFile
+	if err := json.Unmarshal(assignedNumbersJSON, &f); err != nil {
+		panic(fmt.Sprintf("ble: invalid embedded assigned numbers: %v", err))
+	}
+	if err := a.merge(f); err != nil {
+		panic(fmt.Sprintf("ble: invalid embedded assigned numbers: %v", err))
+	}
+	return a
+}
+
+// merge adds all entries from f, replacing existing names
+func (a *assignedNumbers) merge(f AssignedNumbersFile) error {
+	if err := mergeHexMap(a.companies, f.CompanyIdentifiers); err != nil {
+		return fmt.Errorf("company_identifiers: %w", err)
+	}
+	if err := mergeHexMap(a.services, f.ServiceUUIDs); err != nil {
+		return fmt.Errorf("service_uuids: %w", err)
+	}
+	if err := mergeHexMap(a.services, f.MemberUUIDs); err != nil {
+		return fmt.Errorf("member_uuids: %w", err)
+	}
+	if err := mergeHexMap(a.characteristics, f.CharacteristicUUIDs); err != nil {
+		return fmt.Errorf("characteristic_uuids: %w", err)
+	}
+	for key, entry := range f.Appearance {
+		category, err := parseHex16(key)
+		if err != nil || category > 0x3FF {
+			return fmt.Errorf("appearance: invalid category %q", key)
+		}
+		if entry.Name != "" {
+			a.appearance[category] = entry.Name
+		}
+		for subKey, name := range entry.Subcategories {
+			sub, err := parseHex16(subKey)
+			if err != nil || sub > 0x3F {
+				return fmt.Errorf("appearance %s: invalid subcategory %q", key, subKey)
+			}
+			a.subappearance[category<<6|sub] = name
+		}
+	}
+	return nil
+}
+
+func mergeHexMap(dst map[uint16]string, src map[string]string) error {
+	for key, name := range src {
+		value, err := parseHex16(key)
+		if err != nil {
+			return fmt.Errorf("invalid key %q", key)
+		}
+		dst[value] = name
+	}
+	return nil
+}
+
+func parseHex16(s string) (uint16, error) {
+	v, err := strconv.ParseUint(strings.TrimSpace(s), 0, 16)
+	return uint16(v), err
+}
+
+// LoadAssignedNumbersOverride merges a user override file (same JSON format
+// as the embedded database) on top of the built-in tables. It must be called
+// before scanning starts.
+func LoadAssignedNumbersOverride(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var f AssignedNumbersFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := db.merge(f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// bluetoothBaseUUIDSuffix is the Bluetooth Base UUID without the 16/32-bit prefix
+const bluetoothBaseUUIDSuffix = "-0000-1000-8000-00805f9b34fb"
+
+// ShortUUID returns the 16-bit value of a UUID derived from the Bluetooth
+// Base UUID ("0000xxxx-0000-1000-8000-00805f9b34fb")
+func ShortUUID(uuid string) (uint16, bool) {
+	uuid = strings.ToLower(uuid)
+	switch {
+	case len(uuid) == 36 && strings.HasPrefix(uuid, "0000") && strings.HasSuffix(uuid, bluetoothBaseUUIDSuffix):
+		v, err := strconv.ParseUint(uuid[4:8], 16, 16)
+		return uint16(v), err == nil
+	case len(uuid) == 4:
+		v, err := strconv.ParseUint(uuid, 16, 16)
+		return uint16(v), err == nil
+	}
+	return 0, false
+}
+
+// GetServiceName returns the name of a 16-bit service or member UUID
+func GetServiceName(uuid uint16) (string, bool) {
+	name, ok := db.services[uuid]
+	return name, ok
+}
+
+// GetCharacteristicName returns the name of a 16-bit characteristic UUID
+func GetCharacteristicName(uuid uint16) (string, bool) {
+	name, ok := db.characteristics[uuid]
+	return name, ok
+}
+
+// FormatUUID returns a short display form of a UUID: the assigned name for
+// known 16-bit UUIDs, "0xXXXX" for other 16-bit UUIDs, and the first 8 hex
+// digits of vendor-specific 128-bit UUIDs
+func FormatUUID(uuid string) string {
+	if short, ok := ShortUUID(uuid); ok {
+		if name, ok := GetServiceName(short); ok {
+			return name
+		}
+		return fmt.Sprintf("0x%04X", short)
+	}
+	if len(uuid) > 8 {
+		return uuid[:8]
+	}
+	return uuid
+}
+
+// GetAppearanceName returns the category and (if known) subcategory name for
+// an appearance value. Category is the upper 10 bits, subcategory the lower 6.
+func GetAppearanceName(appearance uint16) string {
+	category, ok := db.appearance[appearance>>6]
+	if !ok {
+		return fmt.Sprintf("0x%04x", appearance)
+	}
+	if appearance&0x3F == 0 {
+		return category
+	}
+	if sub, ok := db.subappearance[appearance]; ok {
+		return category + ": " + sub
+	}
+	return fmt.Sprintf("%s (0x%02x)", category, appearance&0x3F)
+}
@@ -0,0 +1,151 @@
+package ble
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Smart-home commissioning advertisements: Matter over BLE and HomeKit
+// Accessory Protocol (HAP) over BLE
+
+const (
+	matterServiceUUID = 0xFFF6
+	companyApple      = 0x004C
+
+	matterOpcodeCommissionable = 0x00
+	homeKitAdvType             = 0x06
+)
+
+// CommissioningInfo describes a smart-home accessory's commissioning
+// advertisement
+type CommissioningInfo struct {
+	Protocol string // "Matter" or "HomeKit"
+	Ready    bool   // Accessory is advertising for commissioning/pairing
+	Fields   []ADType
+}
+
+// Status returns "ready" or "paired"
+func (c *CommissioningInfo) Status() string {
+	if c.Ready {
+		return "ready"
+	}
+	return "paired"
+}
+
+// Matter test vendor IDs (Matter Core spec 2.5.2)
+var matterTestVendors = map[uint16]bool{0xFFF1: true, 0xFFF2: true, 0xFFF3: true, 0xFFF4: true}
+
+// MatterInfo decodes Matter BLE commissioning service data: opcode,
+// discriminator and advertisement version, vendor ID, product ID and
+// additional data flags (Matter Core spec 5.4.2.5.6)
+func (a *Advertisement) MatterInfo() (*CommissioningInfo, bool) {
+	data, ok := a.serviceData16(matterServiceUUID)
+	if !ok || len(data) < 7 {
+		return nil, false
+	}
+
+	c := &CommissioningInfo{Protocol: "Matter", Ready: data[0] == matterOpcodeCommissionable}
+	versionDiscriminator := binary.LittleEndian.Uint16(data[1:])
+	vendorID := binary.LittleEndian.Uint16(data[3:])
+	productID := binary.LittleEndian.Uint16(data[5:])
+
+	opcode := fmt.Sprintf("0x%02X", data[0])
+	if c.Ready {
+		opcode += " (commissionable)"
+	}
+	vendor := fmt.Sprintf("0x%04X", vendorID)
+	if matterTestVendors[vendorID] {
+		vendor += " (test vendor)"
+	}
+
+	c.Fields = []ADType{
+		{Name: "Opcode", Value: opcode},
+		{Name: "Discriminator", Value: fmt.Sprintf("%d (0x%03X)", versionDiscriminator&0x0FFF, versionDiscriminator&0x0FFF)},
+		{Name: "Adv Version", Value: fmt.Sprintf("%d", versionDiscriminator>>12)},
+		{Name: "Vendor ID", Value: vendor},
+		{Name: "Product ID", Value: fmt.Sprintf("0x%04X", productID)},
+	}
+	if len(data) >= 8 {
+		flags := "none"
+		if data[7]&0x01 != 0 {
+			flags = "additional data (rotating ID)"
+		}
+		c.Fields = append(c.Fields, ADType{Name: "Flags", Value: flags})
+	}
+	return c, true
+}
+
+// HomeKit accessory categories (HAP specification, 13)
+var homeKitCategories = map[uint16]string{
+	1: "Other", 2: "Bridge", 3: "Fan", 4: "Garage Door Opener",
+	5: "Lightbulb", 6: "Door Lock", 7: "Outlet", 8: "Switch",
+	9: "Thermostat", 10: "Sensor", 11: "Security System", 12: "Door",
+	13: "Window", 14: "Window Covering", 15: "Programmable Switch",
+	16: "Range Extender", 17: "IP Camera", 18: "Video Doorbell",
+	19: "Air Purifier", 20: "Heater", 21: "Air Conditioner",
+	22: "Humidifier", 23: "Dehumidifier", 28: "Sprinkler", 29: "Faucet",
+	30: "Shower System", 31: "Television", 32: "Remote Control",
+	33: "Wi-Fi Router", 34: "Audio Receiver", 35: "TV Set Top Box",
+	36: "TV Streaming Stick",
+}
+
+// HomeKitInfo decodes a HAP BLE regular advertisement in Apple manufacturer
+// data: type 0x06, subtype/length, status flags, device ID, category,
+// global state number, configuration number, compatible version and an
+// optional setup hash
+func (a *Advertisement) HomeKitInfo() (*CommissioningInfo, bool) {
+	data, ok := a.manufacturerPayload(companyApple)
+	if !ok || len(data) < 15 || data[0] != homeKitAdvType {
+		return nil, false
+	}
+
+	length := int(data[1] & 0x1F)
+	body := data[2:]
+	if length < 13 || length > len(body) {
+		return nil, false
+	}
+	body = body[:length]
+
+	statusFlags := body[0]
+	deviceID := body[1:7]
+	category := binary.LittleEndian.Uint16(body[7:])
+	gsn := binary.LittleEndian.Uint16(body[9:])
+	configNumber := body[11]
+	compatibleVersion := body[12]
+
+	// Status flag bit 0 is set until the accessory is paired with a controller
+	c := &CommissioningInfo{Protocol: "HomeKit", Ready: statusFlags&0x01 != 0}
+
+	categoryName, ok := homeKitCategories[category]
+	if !ok {
+		categoryName = "Unknown"
+	}
+	pairing := "paired"
+	if c.Ready {
+		pairing = "not paired"
+	}
+	setupHash := "absent"
+	if length >= 17 {
+		setupHash = fmt.Sprintf("present (%x)", body[13:17])
+	}
+
+	c.Fields = []ADType{
+		{Name: "Category", Value: fmt.Sprintf("%s (%d)", categoryName, category)},
+		{Name: "Status Flags", Value: fmt.Sprintf("0x%02X (%s)", statusFlags, pairing)},
+		{Name: "Device ID", Value: fmt.Sprintf("%02X:%02X:%02X:%02X:%02X:%02X", deviceID[0], deviceID[1], deviceID[2], deviceID[3], deviceID[4], deviceID[5])},
+		{Name: "State Number", Value: fmt.Sprintf("%d", gsn)},
+		{Name: "Config Number", Value: fmt.Sprintf("%d", configNumber)},
+		{Name: "HAP Version", Value: fmt.Sprintf("%d", compatibleVersion)},
+		{Name: "Setup Hash", Value: setupHash},
+	}
+	return c, true
+}
+
+// Commissioning returns the Matter or HomeKit commissioning information in
+// the advertisement, if any
+func (a *Advertisement) Commissioning() (*CommissioningInfo, bool) {
+	if c, ok := a.MatterInfo(); ok {
+		return c, true
+	}
+	return a.HomeKitInfo()
+}
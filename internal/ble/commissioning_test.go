@@ -0,0 +1,117 @@
+package ble
+
+import "testing"
+
+func TestCommissioning(t *testing.T) {
+	const (
+		homeKitBody = "01" + "aabbccddeeff" + "0500" + "0100" + "02" + "02"
+		setupHash   = "11223344"
+	)
+	tests := []struct {
+		name     string
+		raw      string
+		ok       bool
+		protocol string
+		status   string
+		fields   map[string]string // Fields to check
+	}{
+		{
+			name:     "commissionable Matter accessory",
+			raw:      "0b16" + "f6ff" + "00" + "000f" + "f1ff" + "0080" + "00",
+			ok:       true,
+			protocol: "Matter",
+			status:   "ready",
+			fields: map[string]string{
+				"Opcode":        "0x00 (commissionable)",
+				"Discriminator": "3840 (0xF00)",
+				"Adv Version":   "0",
+				"Vendor ID":     "0xFFF1 (test vendor)",
+				"Product ID":    "0x8000",
+				"Flags":         "none",
+			},
+		},
+		{
+			name:     "Matter accessory with a rotating ID",
+			raw:      "0b16" + "f6ff" + "00" + "2a11" + "4a13" + "0100" + "01",
+			ok:       true,
+			protocol: "Matter",
+			status:   "ready",
+			fields: map[string]string{
+				"Discriminator": "298 (0x12A)",
+				"Adv Version":   "1",
+				"Vendor ID":     "0x134A",
+				"Flags":         "additional data (rotating ID)",
+			},
+		},
+		{
+			name:     "Matter accessory not commissioning",
+			raw:      "0a16" + "f6ff" + "01" + "000f" + "f1ff" + "0080",
+			ok:       true,
+			protocol: "Matter",
+			status:   "paired",
+			fields:   map[string]string{"Opcode": "0x01", "Flags": ""},
+		},
+		{
+			name: "short Matter service data",
+			raw:  "0916" + "f6ff" + "00" + "000f" + "f1ff" + "00",
+		},
+		{
+			name:     "unpaired HomeKit accessory",
+			raw:      "16ff" + "4c00" + "06" + "31" + homeKitBody + setupHash,
+			ok:       true,
+			protocol: "HomeKit",
+			status:   "ready",
+			fields: map[string]string{
+				"Category":     "Lightbulb (5)",
+				"Status Flags": "0x01 (not paired)",
+				"Device ID":    "AA:BB:CC:DD:EE:FF",
+				"State Number": "1",
+				"Setup Hash":   "present (11223344)",
+			},
+		},
+		{
+			name:     "paired HomeKit accessory without setup hash",
+			raw:      "12ff" + "4c00" + "06" + "2d" + "00" + homeKitBody[2:],
+			ok:       true,
+			protocol: "HomeKit",
+			status:   "paired",
+			fields:   map[string]string{"Status Flags": "0x00 (paired)", "Setup Hash": "absent"},
+		},
+		{
+			name:     "unknown HomeKit category",
+			raw:      "12ff" + "4c00" + "06" + "2d" + "01" + "aabbccddeeff" + "6300" + "0100" + "0202",
+			ok:       true,
+			protocol: "HomeKit",
+			status:   "ready",
+			fields:   map[string]string{"Category": "Unknown (99)"},
+		},
+		{
+			name: "HomeKit length past the data",
+			raw:  "12ff" + "4c00" + "06" + "31" + homeKitBody,
+		},
+		{
+			name: "other Apple advertisement",
+			raw:  "12ff" + "4c00" + "10" + "2d" + homeKitBody,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adv := decodedAdvert(t, tt.raw)
+			c, ok := adv.Commissioning()
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if c.Protocol != tt.protocol || c.Status() != tt.status {
+				t.Errorf("protocol = %s, status = %s, want %s and %s", c.Protocol, c.Status(), tt.protocol, tt.status)
+			}
+			for name, want := range tt.fields {
+				if got := fieldValue(DecodedPayload{Fields: c.Fields}, name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,377 @@
+package ble
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Correlation is a heuristic suggestion that a device is the same physical
+// device as another that recently stopped advertising (typically a MAC
+// address rotation we couldn't resolve with an IRK)
+type Correlation struct {
+	CandidateID   string   // ID of the device this one probably is
+	CandidateName string   // Display name of the candidate when it was last seen
+	Score         float64  // Confidence, 0-1
+	Reasons       []string // Evidence that contributed to the score
+}
+
+const (
+	// How long departed devices remain merge candidates
+	correlationWindow = 2 * time.Minute
+	// New devices are evaluated until they are this old
+	correlationMaxAge = time.Minute
+	// Minimum advertisements before a new device is evaluated
+	correlationMinAdvs = 3
+	// Suggestions below this score are discarded
+	correlationMinScore = 0.5
+	// Maximum silence between the old address and the new one appearing
+	correlationMaxGap = 30 * time.Second
+)
+
+// departedDevice is a device removed by cleanup that may still reappear
+// under a new address
+type departedDevice struct {
+	device   *Device
+	departed time.Time
+}
+
+// correlationEvidence is a comparable snapshot of a device's fingerprint
+type correlationEvidence struct {
+	id           string
+	name         string
+	addressType  AddressType
+	mfgData      []byte
+	serviceUUIDs []string
+	txPower      *int8
+	interval     time.Duration
+	firstSeen    time.Time
+	lastSeen     time.Time
+	firstRSSI    float64 // Mean of the first few RSSI samples
+	lastRSSI     float64 // Mean of the last few RSSI samples
+	advCount     int
+}
+
+func (d *Device) correlationEvidence() correlationEvidence {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	e := correlationEvidence{
+		id:           d.ID,
+		name:         d.Name,
+		addressType:  d.AddressType,
+		mfgData:      d.ManufacturerData,
+		serviceUUIDs: d.ServiceUUIDs,
+		txPower:      d.TxPowerLevel,
+		interval:     d.AdvInterval,
+		firstSeen:    d.FirstSeen,
+		lastSeen:     d.LastSeen,
+		advCount:     d.AdvCount,
+	}
+
+	const samples = 3
+	ads := d.recentAdvertisements(maxAdvertisements)
+	if len(ads) > 0 {
+		n := min(samples, len(ads))
+		var first, last float64
+		for i := 0; i < n; i++ {
+			first += float64(ads[i].RSSI)
+			last += float64(ads[len(ads)-1-i].RSSI)
+		}
+		e.firstRSSI = first / float64(n)
+		e.lastRSSI = last / float64(n)
+	}
+	return e
+}
+
+// rotates reports whether an address type is one a device would rotate away from
+func rotates(t AddressType) bool {
+	return t == AddressTypeResolvablePrivate || t == AddressTypeNonResolvablePrivate ||
+		t == AddressTypeRandomInvalid || t == AddressTypeUnknown
+}
+
+// scoreCorrelation estimates how likely newer is older under a new address
+func scoreCorrelation(older, newer correlationEvidence) (float64, []string) {
+	if !rotates(older.addressType) || !rotates(newer.addressType) {
+		return 0, nil
+	}
+
+	// The old address must have gone quiet around the time the new one appeared.
+	// A long overlap means two devices transmitting at once.
+	gap := newer.firstSeen.Sub(older.lastSeen)
+	if gap < -2*time.Second || gap > correlationMaxGap {
+		return 0, nil
+	}
+
+	var score float64
+	var reasons []string
+
+	// Handover timing: best when the new address appears right after the old one stops
+	timing := 1 - math.Max(0, gap.Seconds())/correlationMaxGap.Seconds()
+	score += 0.1 * timing
+	reasons = append(reasons, fmt.Sprintf("handover %s", gap.Round(100*time.Millisecond)))
+
+	// Manufacturer data structure: same company and layout
+	if len(older.mfgData) >= 2 && len(newer.mfgData) >= 2 {
+		if older.mfgData[0] == newer.mfgData[0] && older.mfgData[1] == newer.mfgData[1] {
+			similarity := payloadSimilarity(older.mfgData, newer.mfgData)
+			score += 0.1 + 0.2*similarity
+			reasons = append(reasons, fmt.Sprintf("mfg data %.0f%% similar", similarity*100))
+		} else {
+			score -= 0.3
+		}
+	} else if len(older.mfgData) != len(newer.mfgData) {
+		score -= 0.1
+	}
+
+	// Service UUIDs
+	if len(older.serviceUUIDs) > 0 || len(newer.serviceUUIDs) > 0 {
+		if sameStrings(older.serviceUUIDs, newer.serviceUUIDs) {
+			score += 0.15
+			reasons = append(reasons, "same services")
+		} else {
+			score -= 0.15
+		}
+	}
+
+	// Name
+	if older.name != "" && newer.name != "" {
+		if older.name == newer.name {
+			score += 0.2
+			reasons = append(reasons, "same name")
+		} else {
+			score -= 0.3
+		}
+	}
+
+	// TX power
+	if older.txPower != nil && newer.txPower != nil {
+		if *older.txPower == *newer.txPower {
+			score += 0.05
+			reasons = append(reasons, "same TX power")
+		} else {
+			score -= 0.1
+		}
+	}
+
+	// Advertising interval within 10%
+	if older.interval > 0 && newer.interval > 0 {
+		ratio := float64(newer.interval) / float64(older.interval)
+		if ratio > 0.9 && ratio < 1.1 {
+			score += 0.15
+			reasons = append(reasons, fmt.Sprintf("interval %dms", newer.interval.Milliseconds()))
+		} else if ratio < 0.5 || ratio > 2 {
+			score -= 0.1
+		}
+	}
+
+	// RSSI continuity: full credit within 6 dB, none beyond 20 dB
+	delta := math.Abs(older.lastRSSI - newer.firstRSSI)
+	continuity := 1 - math.Max(0, math.Min(1, (delta-6)/14))
+	score += 0.1 * continuity
+	if continuity > 0.5 {
+		reasons = append(reasons, fmt.Sprintf("RSSI Δ%.0f dB", delta))
+	}
+
+	return math.Max(0, math.Min(1, score)), reasons
+}
+
+// payloadSimilarity returns the fraction of byte positions that match,
+// penalizing length differences
+func payloadSimilarity(a, b []byte) float64 {
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 1
+	}
+	same := 0
+	for i := 0; i < min(len(a), len(b)); i++ {
+		if a[i] == b[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(longest)
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	as := append([]string(nil), a...)
+	bs := append([]string(nil), b...)
+	sort.Strings(as)
+	sort.Strings(bs)
+	for i := range as {
+		if !strings.EqualFold(as[i], bs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// updateCorrelations looks for rotation candidates for recently appeared
+// devices. Caller must hold s.mu.
+func (s *Scanner) updateCorrelations(now time.Time) {
+	// Drop departed devices that are too old to match
+	kept := s.departed[:0]
+	for _, dd := range s.departed {
+		if now.Sub(dd.departed) <= correlationWindow {
+			kept = append(kept, dd)
+		}
+	}
+	s.departed = kept
+
+	// Gather evidence once per device
+	evidence := make(map[string]correlationEvidence, len(s.devices))
+	for id, d := range s.devices {
+		evidence[id] = d.correlationEvidence()
+	}
+	candidates := make([]correlationEvidence, 0, len(evidence)+len(s.departed))
+	for _, e := range evidence {
+		candidates = append(candidates, e)
+	}
+	for _, dd := range s.departed {
+		candidates = append(candidates, dd.device.correlationEvidence())
+	}
+
+	for id, d := range s.devices {
+		newer := evidence[id]
+		if newer.advCount < correlationMinAdvs || now.Sub(newer.firstSeen) > correlationMaxAge {
+			continue
+		}
+
+		var best *Correlation
+		for _, older := range candidates {
+			if older.id == newer.id || !older.firstSeen.Before(newer.firstSeen) {
+				continue
+			}
+			score, reasons := scoreCorrelation(older, newer)
+			if score < correlationMinScore || (best != nil && score <= best.Score) {
+				continue
+			}
+			name := older.name
+			if name == "" {
+				name = older.id
+			}
+			best = &Correlation{
+				CandidateID:   older.id,
+				CandidateName: name,
+				Score:         score,
+				Reasons:       reasons,
+			}
+		}
+
+		d.mu.Lock()
+		d.Correlation = best
+		d.mu.Unlock()
+	}
+}
+
+// MergeDevices merges the device fromID into intoID, for example after
+// confirming a correlation suggestion. intoID may be a device that has
+// already been removed as stale; it is restored. Future advertisements from
+// fromID's addresses are attributed to intoID.
+func (s *Scanner) MergeDevices(fromID, intoID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from, ok := s.devices[fromID]
+	if !ok {
+		return fmt.Errorf("device %s not found", fromID)
+	}
+
+	into, ok := s.devices[intoID]
+	if !ok {
+		for i, dd := range s.departed {
+			if dd.device.ID == intoID {
+				into = dd.device
+				s.departed = append(s.departed[:i], s.departed[i+1:]...)
+				s.devices[intoID] = into
+				break
+			}
+		}
+	}
+	if into == nil {
+		return fmt.Errorf("device %s not found", intoID)
+	}
+
+	into.absorb(from)
+	delete(s.devices, fromID)
+	s.mergePresence(fromID, intoID)
+
+	// Route the merged device's addresses, and anything already aliased to it
+	from.mu.RLock()
+	for _, sighting := range from.AddressHistory {
+		s.aliases[sighting.Address] = intoID
+	}
+	from.mu.RUnlock()
+	for addr, id := range s.aliases {
+		if id == fromID {
+			s.aliases[addr] = intoID
+		}
+	}
+	return nil
+}
+
+// absorb merges other's history and latest state into d
+func (d *Device) absorb(other *Device) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+
+	if other.FirstSeen.Before(d.FirstSeen) {
+		d.FirstSeen = other.FirstSeen
+	}
+	d.AdvCount += other.AdvCount
+	d.windows.Merge(other.windows)
+
+	// Interleave history by time
+	adverts := append(d.adverts.Slice(), other.adverts.Slice()...)
+	sort.SliceStable(adverts, func(i, j int) bool {
+		return adverts[i].Timestamp.Before(adverts[j].Timestamp)
+	})
+	d.adverts.Reset(adverts)
+	arrivals := append(d.arrivals.Slice(), other.arrivals.Slice()...)
+	sort.Slice(arrivals, func(i, j int) bool { return arrivals[i].Before(arrivals[j]) })
+	d.arrivals.Reset(arrivals)
+	d.trend.Merge(other.trend)
+
+	d.AddressHistory = append(d.AddressHistory, other.AddressHistory...)
+	sort.SliceStable(d.AddressHistory, func(i, j int) bool {
+		return d.AddressHistory[i].FirstSeen.Before(d.AddressHistory[j].FirstSeen)
+	})
+	if len(d.AddressHistory) > maxAddressHistory {
+		d.AddressHistory = d.AddressHistory[len(d.AddressHistory)-maxAddressHistory:]
+	}
+
+	// The newer device carries the current state
+	if other.LastSeen.After(d.LastSeen) {
+		d.LastSeen = other.LastSeen
+		d.Address = other.Address
+		d.AddressType = other.AddressType
+		d.RSSICurrent = other.RSSICurrent
+		d.RSSIHistory = append([]int16(nil), other.RSSIHistory...)
+		d.RSSIAverage = other.RSSIAverage
+		d.resmooth()
+		if other.Name != "" {
+			d.Name = other.Name
+		}
+		if other.ManufacturerID != nil {
+			d.ManufacturerID = other.ManufacturerID
+			d.ManufacturerData = other.ManufacturerData
+		}
+		if len(other.ServiceUUIDs) > 0 {
+			d.ServiceUUIDs = other.ServiceUUIDs
+		}
+		for k, v := range other.ServiceData {
+			d.ServiceData[k] = v
+		}
+		if other.TxPowerLevel != nil {
+			d.TxPowerLevel = other.TxPowerLevel
+		}
+	}
+	d.Correlation = nil
+	d.calculateAdvInterval()
+}
@@ -0,0 +1,72 @@
+package ble
+
+import "strings"
+
+// DecodedPayload is a protocol-level interpretation of an advertisement's
+// manufacturer or service data
+type DecodedPayload struct {
+	Protocol string   // e.g. "Fast Pair"
+	Model    string   // Model identifier or device class, if the protocol carries one
+	Intent   string   // What the advertisement is asking for, e.g. "pairing"
+	Name     string   // Display name carried in the payload
+	Fields   []ADType // Additional protocol fields for the detail view
+}
+
+// Summary returns a one-line description: protocol, intent and model or name
+func (p DecodedPayload) Summary() string {
+	parts := []string{p.Protocol}
+	if p.Intent != "" {
+		parts = append(parts, p.Intent)
+	}
+	if p.Name != "" {
+		parts = append(parts, p.Name)
+	} else if p.Model != "" {
+		parts = append(parts, p.Model)
+	}
+	return strings.Join(parts, " · ")
+}
+
+// payloadDecoder recognizes one protocol in an advertisement
+type payloadDecoder func(adv *Advertisement) (DecodedPayload, bool)
+
+// payloadDecoders are tried in order against every advertisement
+var payloadDecoders = []payloadDecoder{
+	decodeFastPair,
+	decodeSwiftPair,
+	decodeEasySetup,
+	decodeBroadcastAudio,
+	decodeMesh,
+}
+
+// DecodePayloads runs every protocol decoder against the advertisement
+func (a *Advertisement) DecodePayloads() []DecodedPayload {
+	var decoded []DecodedPayload
+	for _, decode := range payloadDecoders {
+		if p, ok := decode(a); ok {
+			decoded = append(decoded, p)
+		}
+	}
+	return decoded
+}
+
+// serviceData16 returns the service data for a 16-bit service UUID
+func (a *Advertisement) serviceData16(uuid uint16) ([]byte, bool) {
+	for k, v := range a.ServiceData {
+		if short, ok := ShortUUID(k); ok && short == uuid {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// manufacturerPayload returns the manufacturer data after the company ID if
+// it belongs to the given company
+func (a *Advertisement) manufacturerPayload(companyID uint16) ([]byte, bool) {
+	if len(a.ManufacturerData) < 2 {
+		return nil, false
+	}
+	if uint16(a.ManufacturerData[0])|uint16(a.ManufacturerData[1])<<8 != companyID {
+		return nil, false
+	}
+	return a.ManufacturerData[2:], true
+}
@@ -2,19 +2,30 @@ package ble
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/buckleypaul/blescan/internal/classify"
+	"github.com/buckleypaul/blescan/internal/stats/history"
+	"github.com/buckleypaul/blescan/internal/stats/window"
 )
 
 // Device represents a discovered BLE device
 type Device struct {
-	Address          string
+	ID               string // Key in the scanner's device table: the address, or IdentityKey.DeviceID for resolved identities
+	Address          string // Most recently seen address
+	AddressType      AddressType
+	IdentityName     string            // Name of the IRK that resolved this device's private addresses
+	AddressHistory   []AddressSighting // Every address this device has used, oldest first
+	Correlation      *Correlation      // Suggested match with a device that stopped advertising
 	Name             string
 	RSSIHistory      []int16
 	RSSICurrent      int16
-	RSSIAverage      float64
-	Advertisements   []Advertisement
+	RSSIAverage      float64         // Mean of RSSIHistory
+	RSSISmoothed     float64         // Output of the configured smoothing filter
+	Advertisements   []Advertisement // Recent advertisements, oldest first: the last 100 in copies, the whole raw history from Scanner.GetDevice
 	FirstSeen        time.Time
 	LastSeen         time.Time
 	AdvInterval      time.Duration
@@ -27,26 +38,73 @@ type Device struct {
 	Connectable      bool
 	Flags            *uint8
 	Appearance       *uint16
-	ADTypes          []uint8 // All AD type codes seen
+	ADTypes          []uint8            // All AD type codes seen
+	Extended         *ExtendedAdvInfo   // Latest extended advertising fields, nil if only legacy PDUs seen
+	LintFindings     []LintFinding      // Spec violations in the latest raw advertisement
+	LintCount        int                // Advertisements with at least one finding
+	EAD              EADStatus          // Encrypted Data status of the latest advertisement carrying it
+	DecryptedData    []byte             // Latest decrypted Encrypted Data payload
+	Decoded          []DecodedPayload   // Latest payload of each recognized protocol
+	Broadcast        *BroadcastSource   // LE Audio broadcast source announcement, if any
+	Mesh             *MeshInfo          // Bluetooth Mesh state accumulated across advertisements
+	Commissioning    *CommissioningInfo // Matter or HomeKit commissioning advertisement, if any
+	Tracker          *TrackerInfo       // Location tracker identification, if any
+	FloodTemplate    string             // Payload template of a random-address device, for flood detection
+	FloodMembers     int                // Devices collapsed into this row when it stands in for a flood
+	Baseline         *Baseline          // Learned normal behaviour, for spoofing detection
+	Anomalies        []Anomaly          // Most recent departures from the baseline
+	AnomalyCount     int                // Anomalies reported since the device was first seen
+	Class            classify.Result    // What kind of device this is, with confidence
+	Windows          []window.Summary   // Rolling statistics per window; only filled in by Scanner.GetDevice
+	Arrivals         []time.Time        // Recent advertisement times, oldest first; only filled in by Scanner.GetDevice
+	ReceptionHistory []ReceptionCount   // Advertisements received per ReceptionBucket, oldest first
+	Counter          *ReceptionCounter  // Advertised counter giving exact packet loss, if any
+	SecondTrend      []history.Point    // Per-second history, oldest first; only filled in by Scanner.GetDevice
+	MinuteTrend      []history.Point    // Per-minute history, oldest first; only filled in by Scanner.GetDevice
+	Presence         *Presence          // Visits this session; only filled in by Scanner.GetDevice
+
+	pendingAnomalies []Anomaly // Reported but not yet collected by the scanner
+
+	windows             *window.Set                  // Rolling statistics
+	arrivals            *history.Ring[time.Time]     // Recent advertisement times, for interval analysis
+	adverts             *history.Ring[Advertisement] // Raw advertisement history
+	trend               *history.Trend               // Per-second and per-minute history
+	smoother            rssiFilter                   // RSSI smoothing filter state
+	smoothingGeneration int                          // Smoothing configuration the filter was built with
 
 	mu sync.RWMutex
 }
 
+// AddressSighting records when a device used a particular address
+type AddressSighting struct {
+	Address   string
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
 const (
 	maxRSSIHistory    = 20
 	maxAdvertisements = 100
+	maxAddressHistory = 50
+	maxArrivals       = 1000
 )
 
 // NewDevice creates a new Device with the given address
 func NewDevice(address string) *Device {
 	now := time.Now()
+	limits := CurrentHistoryLimits()
 	return &Device{
+		ID:           address,
 		Address:      address,
 		RSSIHistory:  make([]int16, 0, maxRSSIHistory),
 		FirstSeen:    now,
 		LastSeen:     now,
 		ServiceData:  make(map[string][]byte),
 		ServiceUUIDs: make([]string, 0),
+		windows:      window.NewSet(),
+		arrivals:     history.NewRing[time.Time](maxArrivals),
+		adverts:      history.NewRing[Advertisement](limits.Adverts),
+		trend:        history.NewTrend(limits.Seconds, limits.Minutes),
 	}
 }
 
@@ -55,9 +113,31 @@ func (d *Device) Update(adv Advertisement) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
+	// Compare with the learned baseline before the device changes
+	d.detectAnomalies(adv, d.LastSeen)
+
+	// Update rolling statistics, expecting the interval measured so far
+	var gap time.Duration
+	if d.AdvCount > 0 {
+		gap = adv.Timestamp.Sub(d.LastSeen)
+	}
+	d.windows.Add(adv.Timestamp, adv.RSSI, gap, d.AdvInterval)
+	d.arrivals.Push(adv.Timestamp)
+	d.updateReception(adv)
+
 	d.LastSeen = adv.Timestamp
 	d.AdvCount++
 
+	// Track address rotation for resolved identities
+	if adv.Address != "" {
+		d.recordAddress(adv.Address, adv.Timestamp)
+	}
+
+	// Update address type if the source reported one
+	if adv.AddressType != AddressTypeUnknown {
+		d.AddressType = adv.AddressType
+	}
+
 	// Update name if provided
 	if adv.LocalName != "" {
 		d.Name = adv.LocalName
@@ -65,11 +145,15 @@ func (d *Device) Update(adv Advertisement) {
 
 	// Update RSSI
 	d.RSSICurrent = adv.RSSI
-	d.RSSIHistory = append(d.RSSIHistory, adv.RSSI)
-	if len(d.RSSIHistory) > maxRSSIHistory {
-		d.RSSIHistory = d.RSSIHistory[1:]
+	// Shift within the allocated capacity rather than reslicing past it
+	if len(d.RSSIHistory) < maxRSSIHistory {
+		d.RSSIHistory = append(d.RSSIHistory, adv.RSSI)
+	} else {
+		copy(d.RSSIHistory, d.RSSIHistory[1:])
+		d.RSSIHistory[len(d.RSSIHistory)-1] = adv.RSSI
 	}
 	d.RSSIAverage = d.calculateRSSIAverage()
+	d.smoothRSSI(adv.RSSI)
 
 	// Update manufacturer data
 	if len(adv.ManufacturerData) >= 2 {
@@ -106,6 +190,53 @@ func (d *Device) Update(adv Advertisement) {
 		d.Appearance = adv.Appearance
 	}
 
+	// Update extended advertising fields
+	if adv.Extended != nil {
+		d.Extended = adv.Extended
+	}
+
+	// Update Encrypted Data status
+	if adv.EAD != EADNone {
+		d.EAD = adv.EAD
+		d.DecryptedData = adv.DecryptedData
+	}
+
+	// Update LE Audio broadcast source
+	if b, ok := adv.BroadcastSource(); ok {
+		d.Broadcast = b
+	}
+
+	// Update smart-home commissioning state
+	if c, ok := adv.Commissioning(); ok {
+		d.Commissioning = c
+	}
+
+	// Update tracker identification
+	if t, ok := adv.TrackerInfo(); ok {
+		d.Tracker = t
+	}
+
+	// Accumulate Bluetooth Mesh state
+	if m, ok := adv.MeshInfo(); ok {
+		if d.Mesh == nil {
+			d.Mesh = &MeshInfo{}
+		}
+		d.Mesh.merge(m)
+	}
+
+	// Decode protocol payloads, keeping the latest per protocol
+	for _, p := range adv.DecodePayloads() {
+		d.setDecoded(p)
+	}
+
+	// Validate raw data when the source provides it
+	if len(adv.RawData) > 0 {
+		d.LintFindings = adv.Lint()
+		if len(d.LintFindings) > 0 {
+			d.LintCount++
+		}
+	}
+
 	// Update AD types - merge with existing
 	if len(adv.ADTypes) > 0 {
 		// Build a set of all unique AD types seen
@@ -124,13 +255,62 @@ func (d *Device) Update(adv Advertisement) {
 	}
 
 	// Store advertisement
-	d.Advertisements = append(d.Advertisements, adv)
-	if len(d.Advertisements) > maxAdvertisements {
-		d.Advertisements = d.Advertisements[1:]
-	}
+	d.recordHistory(adv)
 
 	// Calculate advertisement interval
 	d.calculateAdvInterval()
+
+	// Look for two transmitters sharing this address, or a changed interval
+	d.detectInterleaving(adv)
+	d.detectIntervalChange(adv)
+
+	// Classify from everything known so far
+	d.Class = classify.Classify(d.classifyInput())
+}
+
+// classifyInput gathers the classification inputs. Called with d.mu held.
+func (d *Device) classifyInput() classify.Input {
+	in := classify.Input{
+		Appearance:       d.Appearance,
+		CompanyID:        d.ManufacturerID,
+		ManufacturerData: d.ManufacturerData,
+		Services:         append([]string(nil), d.ServiceUUIDs...),
+		Name:             d.Name,
+		Tracker:          d.Tracker != nil,
+	}
+	for uuid := range d.ServiceData {
+		in.Services = append(in.Services, uuid)
+	}
+	for _, p := range d.Decoded {
+		in.Protocols = append(in.Protocols, p.Protocol, p.Intent, p.Model)
+	}
+	return in
+}
+
+func (d *Device) setDecoded(p DecodedPayload) {
+	for i := range d.Decoded {
+		if d.Decoded[i].Protocol == p.Protocol {
+			d.Decoded[i] = p
+			return
+		}
+	}
+	d.Decoded = append(d.Decoded, p)
+}
+
+func (d *Device) recordAddress(address string, ts time.Time) {
+	d.Address = address
+	if n := len(d.AddressHistory); n > 0 && d.AddressHistory[n-1].Address == address {
+		d.AddressHistory[n-1].LastSeen = ts
+		return
+	}
+	d.AddressHistory = append(d.AddressHistory, AddressSighting{
+		Address:   address,
+		FirstSeen: ts,
+		LastSeen:  ts,
+	})
+	if len(d.AddressHistory) > maxAddressHistory {
+		d.AddressHistory = d.AddressHistory[1:]
+	}
 }
 
 func (d *Device) calculateRSSIAverage() float64 {
@@ -146,15 +326,17 @@ func (d *Device) calculateRSSIAverage() float64 {
 
 func (d *Device) calculateAdvInterval() {
 	// Need at least 5 advertisements for a meaningful interval calculation
-	if len(d.Advertisements) < 5 {
+	n := min(d.arrivals.Len(), maxAdvertisements)
+	if n < 5 {
 		d.AdvInterval = 0
 		return
 	}
 
-	// Calculate intervals between consecutive advertisements
-	intervals := make([]time.Duration, 0, len(d.Advertisements)-1)
-	for i := 1; i < len(d.Advertisements); i++ {
-		interval := d.Advertisements[i].Timestamp.Sub(d.Advertisements[i-1].Timestamp)
+	// Calculate intervals between the latest consecutive advertisements
+	first := d.arrivals.Len() - n
+	intervals := make([]time.Duration, 0, n-1)
+	for i := first + 1; i < d.arrivals.Len(); i++ {
+		interval := d.arrivals.At(i).Sub(d.arrivals.At(i - 1))
 		// Filter out unreasonable intervals:
 		// - BLE minimum advertisement interval is 20ms (we use 10ms to be safe)
 		// - Maximum reasonable interval is 10 seconds
@@ -179,16 +361,9 @@ func medianDuration(durations []time.Duration) time.Duration {
 		return 0
 	}
 
-	// Simple selection for median - could use quickselect for better performance
 	sorted := make([]time.Duration, n)
 	copy(sorted, durations)
-	for i := 0; i < n; i++ {
-		for j := i + 1; j < n; j++ {
-			if sorted[j] < sorted[i] {
-				sorted[i], sorted[j] = sorted[j], sorted[i]
-			}
-		}
-	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
 
 	if n%2 == 0 {
 		return (sorted[n/2-1] + sorted[n/2]) / 2
@@ -196,6 +371,21 @@ func medianDuration(durations []time.Duration) time.Duration {
 	return sorted[n/2]
 }
 
+// WindowStats returns the device's rolling statistics at now
+func (d *Device) WindowStats(now time.Time) []window.Summary {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.windows.Summaries(now)
+}
+
+// RecentArrivals returns the times of the device's recent advertisements,
+// oldest first
+func (d *Device) RecentArrivals() []time.Time {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.arrivals.Slice()
+}
+
 // GetDisplayName returns the device name or address if no name is set
 func (d *Device) GetDisplayName() string {
 	d.mu.RLock()
@@ -204,6 +394,12 @@ func (d *Device) GetDisplayName() string {
 	if d.Name != "" {
 		return d.Name
 	}
+	if d.Broadcast != nil && d.Broadcast.Name != "" {
+		return d.Broadcast.Name
+	}
+	if d.IdentityName != "" {
+		return d.IdentityName
+	}
 	return d.Address
 }
 
@@ -213,10 +409,16 @@ func (d *Device) Copy() Device {
 	defer d.mu.RUnlock()
 
 	copy := Device{
+		ID:               d.ID,
 		Address:          d.Address,
+		AddressType:      d.AddressType,
+		IdentityName:     d.IdentityName,
+		AddressHistory:   append([]AddressSighting(nil), d.AddressHistory...),
+		Correlation:      d.Correlation,
 		Name:             d.Name,
 		RSSICurrent:      d.RSSICurrent,
 		RSSIAverage:      d.RSSIAverage,
+		RSSISmoothed:     d.RSSISmoothed,
 		FirstSeen:        d.FirstSeen,
 		LastSeen:         d.LastSeen,
 		AdvInterval:      d.AdvInterval,
@@ -225,6 +427,19 @@ func (d *Device) Copy() Device {
 		ServiceUUIDs:     append([]string(nil), d.ServiceUUIDs...),
 		TxPowerLevel:     d.TxPowerLevel,
 		Connectable:      d.Connectable,
+		LintFindings:     append([]LintFinding(nil), d.LintFindings...),
+		LintCount:        d.LintCount,
+		EAD:              d.EAD,
+		DecryptedData:    append([]byte(nil), d.DecryptedData...),
+		Decoded:          append([]DecodedPayload(nil), d.Decoded...),
+		Broadcast:        d.Broadcast,
+		Commissioning:    d.Commissioning,
+		Tracker:          d.Tracker,
+		FloodTemplate:    d.FloodTemplate,
+		FloodMembers:     d.FloodMembers,
+		Anomalies:        append([]Anomaly(nil), d.Anomalies...),
+		AnomalyCount:     d.AnomalyCount,
+		Class:            d.Class,
 	}
 
 	if d.ManufacturerID != nil {
@@ -242,10 +457,33 @@ func (d *Device) Copy() Device {
 		copy.Appearance = &appearance
 	}
 
+	if d.Extended != nil {
+		extended := *d.Extended
+		copy.Extended = &extended
+	}
+
+	if d.Mesh != nil {
+		mesh := *d.Mesh
+		copy.Mesh = &mesh
+	}
+
+	if d.Counter != nil {
+		counter := *d.Counter
+		copy.Counter = &counter
+	}
+
+	if d.Baseline != nil {
+		baseline := *d.Baseline
+		baseline.Layouts = append([]string(nil), d.Baseline.Layouts...)
+		baseline.reported = nil
+		copy.Baseline = &baseline
+	}
+
 	copy.ADTypes = append([]uint8(nil), d.ADTypes...)
 
 	copy.RSSIHistory = append([]int16(nil), d.RSSIHistory...)
-	copy.Advertisements = append([]Advertisement(nil), d.Advertisements...)
+	copy.ReceptionHistory = append([]ReceptionCount(nil), d.ReceptionHistory...)
+	copy.Advertisements = d.recentAdvertisements(maxAdvertisements)
 
 	copy.ServiceData = make(map[string][]byte)
 	for k, v := range d.ServiceData {
@@ -288,15 +526,19 @@ func (d *Device) GetADTypes() []ADType {
 	if len(d.ServiceData) > 0 {
 		var parts []string
 		for uuid, data := range d.ServiceData {
-			shortUUID := uuid
-			if len(uuid) > 8 {
-				shortUUID = uuid[:8]
-			}
+			shortUUID := FormatUUID(uuid)
 			parts = append(parts, fmt.Sprintf("%s:[%x]", shortUUID, data))
 		}
 		types = append(types, ADType{Name: "Service Data", Value: strings.Join(parts, ", ")})
 	}
 
+	switch d.EAD {
+	case EADDecrypted:
+		types = append(types, ADType{Name: "Encrypted Data", Value: fmt.Sprintf("decrypted [%x]", d.DecryptedData)})
+	case EADEncrypted, EADFailed:
+		types = append(types, ADType{Name: "Encrypted Data", Value: d.EAD.String()})
+	}
+
 	if d.TxPowerLevel != nil {
 		types = append(types, ADType{Name: "TX Power", Value: fmt.Sprintf("%d dBm", *d.TxPowerLevel)})
 	}
@@ -347,15 +589,10 @@ func (d *Device) FormatServiceUUIDs() string {
 		return "-"
 	}
 
-	// Shorten UUIDs for display
+	// Shorten UUIDs for display, using assigned names where known
 	var shortened []string
 	for _, uuid := range d.ServiceUUIDs {
-		// If it's a full UUID, show just the characteristic part
-		if len(uuid) > 8 {
-			shortened = append(shortened, uuid[:8])
-		} else {
-			shortened = append(shortened, uuid)
-		}
+		shortened = append(shortened, FormatUUID(uuid))
 	}
 
 	result := strings.Join(shortened, ",")
@@ -376,10 +613,7 @@ func (d *Device) FormatServiceData() string {
 
 	var parts []string
 	for uuid, data := range d.ServiceData {
-		shortUUID := uuid
-		if len(uuid) > 8 {
-			shortUUID = uuid[:8]
-		}
+		shortUUID := FormatUUID(uuid)
 		// Show first few bytes of data
 		dataStr := fmt.Sprintf("%x", data)
 		if len(dataStr) > 8 {
@@ -404,67 +638,36 @@ func (d *Device) FormatAppearance() string {
 		return "-"
 	}
 
-	appearance := *d.Appearance
-
-	// BLE Appearance values (common ones)
-	// Category is upper 10 bits, subcategory is lower 6 bits
-	category := appearance >> 6
-	switch category {
-	case 0:
-		return "Unknown"
-	case 1:
-		return "Phone"
-	case 2:
-		return "Computer"
-	case 3:
-		return "Watch"
-	case 4:
-		return "Clock"
-	case 5:
-		return "Display"
-	case 6:
-		return "Remote"
-	case 7:
-		return "Eye-glasses"
-	case 8:
-		return "Tag"
-	case 9:
-		return "Keyring"
-	case 10:
-		return "Media Player"
-	case 11:
-		return "Barcode Scanner"
-	case 12:
-		return "Thermometer"
-	case 13:
-		return "Heart Rate"
-	case 14:
-		return "Blood Pressure"
-	case 15:
-		return "HID"
-	case 16:
-		return "Glucose"
-	case 17:
-		return "Running/Walking"
-	case 18:
-		return "Cycling"
-	case 49:
-		return "Pulse Oximeter"
-	case 50:
-		return "Weight Scale"
-	case 51:
-		return "Personal Mobility"
-	case 52:
-		return "Continuous Glucose"
-	case 53:
-		return "Insulin Pump"
-	case 54:
-		return "Medication Delivery"
-	case 81:
-		return "Outdoor Sports"
-	default:
-		return fmt.Sprintf("0x%04x", appearance)
+	return GetAppearanceName(*d.Appearance)
+}
+
+// FormatAddressVendor returns the IEEE OUI vendor for public addresses
+func (d *Device) FormatAddressVendor() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.AddressType != AddressTypePublic {
+		return "-"
+	}
+	if vendor, ok := LookupOUI(d.Address); ok {
+		return vendor
+	}
+	return "Unknown"
+}
+
+// FormatDecoded returns a summary of the recognized protocol payloads
+func (d *Device) FormatDecoded() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if len(d.Decoded) == 0 {
+		return "-"
+	}
+	parts := make([]string, len(d.Decoded))
+	for i, p := range d.Decoded {
+		parts[i] = p.Summary()
 	}
+	return strings.Join(parts, "; ")
 }
 
 // FormatOtherADTypes returns a list of AD types not shown in other columns
@@ -489,6 +692,11 @@ func (d *Device) FormatOtherADTypes() string {
 		0x19: true, // Appearance
 		0x20: true, // Service Data - 32-bit UUID
 		0x21: true, // Service Data - 128-bit UUID
+		0x29: true, // PB-ADV
+		0x2A: true, // Mesh Message
+		0x2B: true, // Mesh Beacon
+		0x30: true, // Broadcast Name
+		0x31: true, // Encrypted Data
 		0xFF: true, // Manufacturer Specific Data
 	}
 
@@ -558,12 +766,12 @@ func (d *Device) FormatRawData() string {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 
-	if len(d.Advertisements) == 0 {
+	// Get the most recent advertisement
+	recent := d.recentAdvertisements(1)
+	if len(recent) == 0 {
 		return "-"
 	}
-
-	// Get the most recent advertisement
-	latest := d.Advertisements[len(d.Advertisements)-1]
+	latest := recent[0]
 	if len(latest.RawData) == 0 {
 		return "-"
 	}
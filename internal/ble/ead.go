@@ -0,0 +1,265 @@
+package ble
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ADTypeEncryptedData is the Encrypted Data AD type (Core Specification
+// Supplement Part A, 1.23)
+const ADTypeEncryptedData = 0x31
+
+// Encrypted Data layout: Randomizer || Payload || MIC
+const (
+	eadRandomizerSize = 5
+	eadMICSize        = 4
+	eadAAD            = 0xEA // Additional authenticated data, a single octet
+)
+
+// EADStatus describes what happened to an advertisement's Encrypted Data
+type EADStatus int
+
+const (
+	EADNone      EADStatus = iota // No Encrypted Data AD structure
+	EADEncrypted                  // Encrypted Data present, no key configured
+	EADDecrypted                  // Decrypted and authenticated
+	EADFailed                     // A key was configured but the MIC didn't match
+)
+
+// String returns a short description of the status
+func (s EADStatus) String() string {
+	switch s {
+	case EADEncrypted:
+		return "encrypted"
+	case EADDecrypted:
+		return "decrypted"
+	case EADFailed:
+		return "MIC failed"
+	default:
+		return "-"
+	}
+}
+
+// ErrEADAuth is returned when the MIC of Encrypted Data doesn't verify
+var ErrEADAuth = errors.New("encrypted data: MIC check failed")
+
+// EADKey is the key material shared by a device that encrypts its
+// advertising data
+type EADKey struct {
+	Target     string   // Device address, IRK identity name, or "*" to try on any device
+	SessionKey [16]byte // Most significant octet first, as printed in the Core spec
+	IV         [8]byte  // In transmission order
+}
+
+// DecryptEAD decrypts the data of an Encrypted Data AD structure
+// (Randomizer || Payload || MIC) and returns the plaintext AD structures.
+// The nonce is Randomizer || IV and the additional data is the single
+// octet 0xEA, with a 4-octet MIC, per Core spec Vol 3, Part C, 12.6.
+func DecryptEAD(key EADKey, data []byte) ([]byte, error) {
+	if len(data) < eadRandomizerSize+eadMICSize {
+		return nil, fmt.Errorf("encrypted data: %d bytes is too short", len(data))
+	}
+
+	var nonce [13]byte
+	copy(nonce[:eadRandomizerSize], data[:eadRandomizerSize])
+	copy(nonce[eadRandomizerSize:], key.IV[:])
+
+	ciphertext := data[eadRandomizerSize : len(data)-eadMICSize]
+	mic := data[len(data)-eadMICSize:]
+
+	block, err := aes.NewCipher(key.SessionKey[:])
+	if err != nil {
+		return nil, err
+	}
+	return ccmDecrypt(block, nonce[:], []byte{eadAAD}, ciphertext, mic)
+}
+
+// ccmDecrypt implements AES-CCM decryption (RFC 3610) with a 13-octet nonce
+// (L = 2) and a MIC of len(mic) octets
+func ccmDecrypt(block cipher.Block, nonce, aad, ciphertext, mic []byte) ([]byte, error) {
+	const l = 2
+	if len(nonce) != 15-l {
+		return nil, fmt.Errorf("ccm: nonce must be %d bytes", 15-l)
+	}
+	if len(ciphertext) > 0xFFFF {
+		return nil, fmt.Errorf("ccm: message too long")
+	}
+
+	// Counter mode: A_i = flags || nonce || i, S_0 encrypts the MIC
+	var ctr, s [16]byte
+	ctr[0] = l - 1
+	copy(ctr[1:], nonce)
+	keystream := func(i int) []byte {
+		ctr[14] = byte(i >> 8)
+		ctr[15] = byte(i)
+		block.Encrypt(s[:], ctr[:])
+		return s[:]
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	for i := 0; i < len(ciphertext); i += 16 {
+		ks := keystream(i/16 + 1)
+		for j := i; j < len(ciphertext) && j < i+16; j++ {
+			plaintext[j] = ciphertext[j] ^ ks[j-i]
+		}
+	}
+
+	// CBC-MAC over B_0, the length-prefixed AAD and the plaintext
+	var x, b [16]byte
+	b[0] = byte((len(mic)-2)/2)<<3 | (l - 1)
+	if len(aad) > 0 {
+		b[0] |= 1 << 6
+	}
+	copy(b[1:], nonce)
+	b[14] = byte(len(plaintext) >> 8)
+	b[15] = byte(len(plaintext))
+	mac := func(data []byte) {
+		for i := 0; i < len(data); i += 16 {
+			var blk [16]byte
+			copy(blk[:], data[i:min(i+16, len(data))])
+			subtle.XORBytes(x[:], x[:], blk[:])
+			block.Encrypt(x[:], x[:])
+		}
+	}
+	mac(b[:])
+	if len(aad) > 0 {
+		mac(append([]byte{byte(len(aad) >> 8), byte(len(aad))}, aad...))
+	}
+	mac(plaintext)
+
+	tag := make([]byte, len(mic))
+	ks := keystream(0)
+	subtle.XORBytes(tag, x[:len(mic)], ks[:len(mic)])
+	if subtle.ConstantTimeCompare(tag, mic) != 1 {
+		return nil, ErrEADAuth
+	}
+	return plaintext, nil
+}
+
+// EADKeyring holds the configured Encrypted Data keys
+type EADKeyring struct {
+	byTarget map[string][]EADKey
+	wildcard []EADKey
+}
+
+// NewEADKeyring creates a keyring from a list of keys
+func NewEADKeyring(keys []EADKey) *EADKeyring {
+	k := &EADKeyring{byTarget: make(map[string][]EADKey)}
+	for _, key := range keys {
+		if key.Target == "*" {
+			k.wildcard = append(k.wildcard, key)
+			continue
+		}
+		target := strings.ToUpper(key.Target)
+		k.byTarget[target] = append(k.byTarget[target], key)
+	}
+	return k
+}
+
+// KeyCount returns the number of keys in the keyring
+func (k *EADKeyring) KeyCount() int {
+	n := len(k.wildcard)
+	for _, keys := range k.byTarget {
+		n += len(keys)
+	}
+	return n
+}
+
+// Decrypt tries the keys for each of targets (addresses or identity names),
+// then the wildcard keys, and returns the plaintext from the first key whose
+// MIC verifies. The status is EADEncrypted if no key applied and EADFailed if
+// every candidate key failed authentication.
+func (k *EADKeyring) Decrypt(data []byte, targets ...string) ([]byte, EADStatus) {
+	var candidates []EADKey
+	for _, t := range targets {
+		if t != "" {
+			candidates = append(candidates, k.byTarget[strings.ToUpper(t)]...)
+		}
+	}
+	candidates = append(candidates, k.wildcard...)
+	if len(candidates) == 0 {
+		return nil, EADEncrypted
+	}
+
+	for _, key := range candidates {
+		if plaintext, err := DecryptEAD(key, data); err == nil {
+			return plaintext, EADDecrypted
+		}
+	}
+	return nil, EADFailed
+}
+
+// LoadEADKeys reads Encrypted Data keys from a file with one key per line:
+//
+//	<target> <session key> <iv>
+//	<target> km <key material>
+//
+// target is a device address, an IRK identity name, or "*". The session key
+// is 32 hex digits, most significant octet first; the IV is 16 hex digits in
+// transmission order. The "km" form takes the 48-digit value of the Encrypted
+// Data Key Material characteristic exactly as read over GATT, where the
+// session key is least significant octet first. Blank lines and lines
+// starting with '#' are ignored.
+func LoadEADKeys(path string) ([]EADKey, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var keys []EADKey
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: expected \"<target> <session key> <iv>\" or \"<target> km <key material>\"", path, lineNum)
+		}
+
+		key := EADKey{Target: fields[0]}
+		if fields[1] == "km" {
+			material, err := parseHexBytes(fields[2], 24)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: key material: %w", path, lineNum, err)
+			}
+			for i := 0; i < 16; i++ {
+				key.SessionKey[i] = material[15-i]
+			}
+			copy(key.IV[:], material[16:])
+		} else {
+			sessionKey, err := parseHexBytes(fields[1], 16)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: session key: %w", path, lineNum, err)
+			}
+			iv, err := parseHexBytes(fields[2], 8)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: iv: %w", path, lineNum, err)
+			}
+			copy(key.SessionKey[:], sessionKey)
+			copy(key.IV[:], iv)
+		}
+		keys = append(keys, key)
+	}
+	return keys, scanner.Err()
+}
+
+func parseHexBytes(s string, n int) ([]byte, error) {
+	s = strings.TrimPrefix(strings.ReplaceAll(s, ":", ""), "0x")
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != n {
+		return nil, fmt.Errorf("invalid value %q: want %d hex digits", s, n*2)
+	}
+	return b, nil
+}
@@ -0,0 +1,106 @@
+package ble
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// Packet Vector #1 from RFC 3610: 8-octet MIC and additional data
+func TestCCMDecryptRFC3610(t *testing.T) {
+	block, err := aes.NewCipher(mustHex(t, "c0c1c2c3c4c5c6c7c8c9cacbcccdcecf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := mustHex(t, "00000003020100a0a1a2a3a4a5")
+	aad := mustHex(t, "0001020304050607")
+	ciphertext := mustHex(t, "588c979a61c663d2f066d0c2c0f989806d5f6b61dac384")
+	mic := mustHex(t, "17e8d12cfdf926e0")
+
+	plaintext, err := ccmDecrypt(block, nonce, aad, ciphertext, mic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := mustHex(t, "08090a0b0c0d0e0f101112131415161718191a1b1c1d1e"); !bytes.Equal(plaintext, want) {
+		t.Fatalf("plaintext = %x, want %x", plaintext, want)
+	}
+}
+
+// Encrypted Data built with OpenSSL's AES-128-CCM, using the EAD nonce
+// (Randomizer || IV), additional data 0xEA and a 4-octet MIC
+func TestDecryptEAD(t *testing.T) {
+	var key EADKey
+	copy(key.SessionKey[:], mustHex(t, "57a9da12d12e6e131e20612ad10a6a19"))
+	copy(key.IV[:], mustHex(t, "9e7a00efb17ae746"))
+
+	tests := []struct {
+		name    string
+		data    string // Randomizer || Payload || MIC
+		want    string
+		wantErr error
+	}{
+		{
+			name: "flags and name",
+			data: "18817d492e" + "b86fd7bc06298c6b11" + "81836455",
+			want: "020106" + "0509" + hex.EncodeToString([]byte("Test")),
+		},
+		{
+			name: "spans two blocks",
+			data: "8d1c976e05" + "09b8bec379dc7d4748760726f83d75360c1f27d58d75b965c2d8fe" + "bbf4247a",
+			want: "1609" + hex.EncodeToString([]byte("Encrypted Advertising")) + "0319c103",
+		},
+		{
+			name:    "tampered MIC",
+			data:    "18817d492e" + "b86fd7bc06298c6b11" + "81836456",
+			wantErr: ErrEADAuth,
+		},
+		{
+			name:    "tampered payload",
+			data:    "18817d492e" + "b86fd7bc06298c6b10" + "81836455",
+			wantErr: ErrEADAuth,
+		},
+		{
+			name:    "tampered randomizer",
+			data:    "19817d492e" + "b86fd7bc06298c6b11" + "81836455",
+			wantErr: ErrEADAuth,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext, err := DecryptEAD(key, mustHex(t, tt.data))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := mustHex(t, tt.want); !bytes.Equal(plaintext, want) {
+				t.Fatalf("plaintext = %x, want %x", plaintext, want)
+			}
+		})
+	}
+
+	if _, err := DecryptEAD(key, mustHex(t, "18817d492e818364")); err == nil || errors.Is(err, ErrEADAuth) {
+		t.Fatalf("short data: err = %v, want a length error", err)
+	}
+
+	wrongIV := key
+	wrongIV.IV[0] ^= 1
+	if _, err := DecryptEAD(wrongIV, mustHex(t, tests[0].data)); !errors.Is(err, ErrEADAuth) {
+		t.Fatalf("wrong IV: err = %v, want %v", err, ErrEADAuth)
+	}
+}
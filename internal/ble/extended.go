@@ -0,0 +1,307 @@
+package ble
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// PHY is an LE physical layer
+type PHY uint8
+
+const (
+	PHYNone  PHY = 0x00 // No packets on the secondary channel
+	PHY1M    PHY = 0x01
+	PHY2M    PHY = 0x02
+	PHYCoded PHY = 0x03
+)
+
+// String returns the PHY name
+func (p PHY) String() string {
+	switch p {
+	case PHYNone:
+		return "-"
+	case PHY1M:
+		return "1M"
+	case PHY2M:
+		return "2M"
+	case PHYCoded:
+		return "Coded"
+	default:
+		return fmt.Sprintf("0x%02x", uint8(p))
+	}
+}
+
+// DataStatus describes whether an extended advertising report carries all of its data
+type DataStatus uint8
+
+const (
+	DataComplete   DataStatus = 0 // All data received
+	DataIncomplete DataStatus = 1 // More fragments follow
+	DataTruncated  DataStatus = 2 // Incomplete, no more fragments will follow
+)
+
+// String returns the data status name
+func (s DataStatus) String() string {
+	switch s {
+	case DataComplete:
+		return "Complete"
+	case DataIncomplete:
+		return "Incomplete"
+	case DataTruncated:
+		return "Truncated"
+	default:
+		return "Reserved"
+	}
+}
+
+// NoSID is the Advertising SID value reported when no ADI field is present
+const NoSID = 0xFF
+
+// ExtendedAdvInfo holds the Bluetooth 5 extended-advertising fields of an advertisement
+type ExtendedAdvInfo struct {
+	PrimaryPHY       PHY
+	SecondaryPHY     PHY
+	SID              uint8         // Advertising Set ID, NoSID if absent
+	PeriodicInterval time.Duration // Periodic advertising interval, 0 if none
+	DataStatus       DataStatus
+	Fragments        int // Number of reports reassembled into this advertisement
+}
+
+// FormatPHY returns "primary/secondary", or just the primary PHY when there's no secondary
+func (e *ExtendedAdvInfo) FormatPHY() string {
+	if e.SecondaryPHY == PHYNone {
+		return e.PrimaryPHY.String()
+	}
+	return e.PrimaryPHY.String() + "/" + e.SecondaryPHY.String()
+}
+
+// FormatSID returns the Advertising SID, or "-" when absent
+func (e *ExtendedAdvInfo) FormatSID() string {
+	if e.SID == NoSID {
+		return "-"
+	}
+	return fmt.Sprintf("%d", e.SID)
+}
+
+// HCI LE Meta event subevent codes
+const (
+	HCISubeventAdvertisingReport         = 0x02
+	HCISubeventExtendedAdvertisingReport = 0x0D
+)
+
+// Event_Type bits of the LE Extended Advertising Report
+const (
+	extEventConnectable  = 1 << 0
+	extEventScanResponse = 1 << 3
+	extEventLegacy       = 1 << 4
+)
+
+// Legacy advertising report event types (ADV_SCAN_IND and
+// ADV_NONCONN_IND are neither connectable nor scan responses)
+const (
+	legacyAdvInd       = 0x00
+	legacyAdvDirectInd = 0x01
+	legacyScanRsp      = 0x04
+)
+
+// hciAddress formats a little-endian HCI address as "AA:BB:CC:DD:EE:FF"
+func hciAddress(b []byte) string {
+	return fmt.Sprintf("%02X:%02X:%02X:%02X:%02X:%02X", b[5], b[4], b[3], b[2], b[1], b[0])
+}
+
+// hciAddressType converts an HCI Address_Type to an AddressType
+func hciAddressType(t uint8, address string) AddressType {
+	switch t {
+	case 0x00, 0x02: // Public, or public identity of a resolved RPA
+		return AddressTypePublic
+	case 0x01:
+		return ClassifyAddress(address, true)
+	case 0x03: // Random static identity of a resolved RPA
+		return AddressTypeRandomStatic
+	default:
+		return AddressTypeUnknown
+	}
+}
+
+// ParseAdvertisingReports decodes the parameters of an HCI LE Advertising
+// Report event (after the subevent code) into advertisements
+func ParseAdvertisingReports(params []byte, ts time.Time) ([]Advertisement, error) {
+	if len(params) < 1 {
+		return nil, fmt.Errorf("advertising report: empty event")
+	}
+	numReports := int(params[0])
+	b := params[1:]
+
+	var ads []Advertisement
+	for i := 0; i < numReports; i++ {
+		if len(b) < 9 {
+			return ads, fmt.Errorf("advertising report %d: truncated header", i)
+		}
+		eventType := b[0]
+		address := hciAddress(b[2:8])
+		dataLen := int(b[8])
+		if len(b) < 9+dataLen+1 {
+			return ads, fmt.Errorf("advertising report %d: truncated data", i)
+		}
+
+		adv := NewAdvertisement()
+		adv.Timestamp = ts
+		adv.Address = address
+		adv.AddressType = hciAddressType(b[1], address)
+		adv.RawData = append([]byte(nil), b[9:9+dataLen]...)
+		adv.RSSI = int16(int8(b[9+dataLen]))
+		adv.Connectable = eventType == legacyAdvInd || eventType == legacyAdvDirectInd
+		adv.ScanResponse = eventType == legacyScanRsp
+		adv.DecodeRawData()
+		ads = append(ads, adv)
+
+		b = b[9+dataLen+1:]
+	}
+	return ads, nil
+}
+
+// ExtendedAdvReport is one report from an HCI LE Extended Advertising Report event
+type ExtendedAdvReport struct {
+	EventType        uint16
+	AddressType      uint8
+	Address          string
+	PrimaryPHY       PHY
+	SecondaryPHY     PHY
+	SID              uint8
+	TxPower          int8 // 127 if unavailable
+	RSSI             int8
+	PeriodicInterval uint16 // Units of 1.25 ms, 0 if none
+	Data             []byte
+}
+
+// DataStatus returns the report's data status bits
+func (r ExtendedAdvReport) DataStatus() DataStatus {
+	return DataStatus((r.EventType >> 5) & 0x03)
+}
+
+// ParseExtendedAdvertisingReports decodes the parameters of an HCI LE
+// Extended Advertising Report event (after the subevent code)
+func ParseExtendedAdvertisingReports(params []byte) ([]ExtendedAdvReport, error) {
+	if len(params) < 1 {
+		return nil, fmt.Errorf("extended advertising report: empty event")
+	}
+	numReports := int(params[0])
+	b := params[1:]
+
+	const headerLen = 24
+	var reports []ExtendedAdvReport
+	for i := 0; i < numReports; i++ {
+		if len(b) < headerLen {
+			return reports, fmt.Errorf("extended advertising report %d: truncated header", i)
+		}
+		dataLen := int(b[23])
+		if len(b) < headerLen+dataLen {
+			return reports, fmt.Errorf("extended advertising report %d: truncated data", i)
+		}
+		reports = append(reports, ExtendedAdvReport{
+			EventType:        binary.LittleEndian.Uint16(b[0:2]),
+			AddressType:      b[2],
+			Address:          hciAddress(b[3:9]),
+			PrimaryPHY:       PHY(b[9]),
+			SecondaryPHY:     PHY(b[10]),
+			SID:              b[11],
+			TxPower:          int8(b[12]),
+			RSSI:             int8(b[13]),
+			PeriodicInterval: binary.LittleEndian.Uint16(b[14:16]),
+			Data:             append([]byte(nil), b[headerLen:headerLen+dataLen]...),
+		})
+		b = b[headerLen+dataLen:]
+	}
+	return reports, nil
+}
+
+// reassemblyTimeout discards partial extended advertisements whose
+// remaining fragments never arrived
+const reassemblyTimeout = 2 * time.Second
+
+type reassemblyKey struct {
+	address      string
+	sid          uint8
+	scanResponse bool
+}
+
+type partialAdv struct {
+	data      []byte
+	fragments int
+	started   time.Time
+}
+
+// Reassembler joins fragmented extended advertising reports (Data_Status
+// "incomplete, more data to come") into complete advertisements
+type Reassembler struct {
+	partial map[reassemblyKey]*partialAdv
+}
+
+// NewReassembler creates an empty reassembler
+func NewReassembler() *Reassembler {
+	return &Reassembler{partial: make(map[reassemblyKey]*partialAdv)}
+}
+
+// Add feeds a report. It returns the reassembled advertisement once the last
+// fragment arrives, and false while more fragments are expected.
+func (r *Reassembler) Add(report ExtendedAdvReport, ts time.Time) (Advertisement, bool) {
+	for key, p := range r.partial {
+		if ts.Sub(p.started) > reassemblyTimeout {
+			delete(r.partial, key)
+		}
+	}
+
+	key := reassemblyKey{
+		address:      report.Address,
+		sid:          report.SID,
+		scanResponse: report.EventType&extEventScanResponse != 0,
+	}
+	p, ok := r.partial[key]
+	if !ok {
+		p = &partialAdv{started: ts}
+	}
+	p.data = append(p.data, report.Data...)
+	p.fragments++
+
+	status := report.DataStatus()
+	if len(p.data) > MaxExtendedAdvData {
+		p.data = p.data[:MaxExtendedAdvData]
+		status = DataTruncated
+	}
+	if status == DataIncomplete {
+		r.partial[key] = p
+		return Advertisement{}, false
+	}
+	delete(r.partial, key)
+
+	return report.advertisement(p.data, p.fragments, status, ts), true
+}
+
+// advertisement converts a (reassembled) report into an Advertisement
+func (r ExtendedAdvReport) advertisement(data []byte, fragments int, status DataStatus, ts time.Time) Advertisement {
+	adv := NewAdvertisement()
+	adv.Timestamp = ts
+	adv.Address = r.Address
+	adv.AddressType = hciAddressType(r.AddressType, r.Address)
+	adv.RSSI = int16(r.RSSI)
+	adv.RawData = data
+	adv.Connectable = r.EventType&extEventConnectable != 0
+	adv.ScanResponse = r.EventType&extEventScanResponse != 0
+	if r.EventType&extEventLegacy == 0 {
+		adv.Extended = &ExtendedAdvInfo{
+			PrimaryPHY:       r.PrimaryPHY,
+			SecondaryPHY:     r.SecondaryPHY,
+			SID:              r.SID,
+			PeriodicInterval: time.Duration(r.PeriodicInterval) * 1250 * time.Microsecond,
+			DataStatus:       status,
+			Fragments:        fragments,
+		}
+	}
+	adv.DecodeRawData()
+	if adv.TxPowerLevel == nil && r.TxPower != 127 {
+		tx := r.TxPower
+		adv.TxPowerLevel = &tx
+	}
+	return adv
+}
@@ -0,0 +1,240 @@
+package ble
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// Event_Type values of extended advertising reports used in the tests
+const (
+	testExtConnectable = extEventConnectable
+	testExtIncomplete  = uint16(DataIncomplete) << 5
+	testExtTruncated   = uint16(DataTruncated) << 5
+)
+
+// extReport encodes one report of an LE Extended Advertising Report event
+// from C6:11:22:33:44:55 (random) on 1M/2M with RSSI -55 and no Tx power
+func extReport(eventType uint16, sid uint8, data []byte) []byte {
+	b := []byte{
+		byte(eventType), byte(eventType >> 8),
+		0x01,                               // Address_Type: random
+		0x55, 0x44, 0x33, 0x22, 0x11, 0xC6, // Address
+		0x01, 0x02, // Primary and secondary PHY
+		sid,
+		0x7F,       // Tx_Power: unavailable
+		0xC9,       // RSSI: -55
+		0x00, 0x00, // Periodic_Advertising_Interval
+		0x00, 0, 0, 0, 0, 0, 0, // Direct address
+		byte(len(data)),
+	}
+	return append(b, data...)
+}
+
+// extEvent joins reports into the parameters of one event
+func extEvent(reports ...[]byte) []byte {
+	return append([]byte{byte(len(reports))}, bytes.Join(reports, nil)...)
+}
+
+func TestParseExtendedAdvertisingReports(t *testing.T) {
+	name := []byte{0x05, 0x09, 'T', 'e', 's', 't'}
+	tests := []struct {
+		name    string
+		params  []byte
+		reports int
+		wantErr bool
+	}{
+		{name: "single report", params: extEvent(extReport(testExtConnectable, 3, name)), reports: 1},
+		{name: "two reports", params: extEvent(extReport(0, 3, name), extReport(0, 4, nil)), reports: 2},
+		{name: "empty event", params: nil, wantErr: true},
+		{name: "truncated header", params: extEvent(extReport(0, 3, name))[:20], wantErr: true},
+		{name: "truncated data", params: extEvent(extReport(0, 3, name))[:27], wantErr: true},
+		{
+			name:    "second report missing",
+			params:  append([]byte{2}, extReport(0, 3, name)...),
+			reports: 1,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports, err := ParseExtendedAdvertisingReports(tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if len(reports) != tt.reports {
+				t.Fatalf("reports = %d, want %d", len(reports), tt.reports)
+			}
+			if len(reports) == 0 {
+				return
+			}
+			r := reports[0]
+			if r.Address != "C6:11:22:33:44:55" || r.AddressType != 0x01 || r.SID != 3 || r.RSSI != -55 || r.TxPower != 127 {
+				t.Errorf("report = %+v", r)
+			}
+			if r.PrimaryPHY != PHY1M || r.SecondaryPHY != PHY2M {
+				t.Errorf("PHYs = %v/%v, want 1M/2M", r.PrimaryPHY, r.SecondaryPHY)
+			}
+			if !bytes.Equal(r.Data, name) {
+				t.Errorf("data = %x, want %x", r.Data, name)
+			}
+		})
+	}
+}
+
+func TestReassembler(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	first := []byte{0x05, 0x09, 'T', 'e'}
+	rest := []byte{'s', 't', 0x02, 0x01, 0x06}
+	type fragment struct {
+		eventType uint16
+		sid       uint8
+		data      []byte
+		after     time.Duration
+	}
+	tests := []struct {
+		name      string
+		fragments []fragment
+		complete  []bool // Whether each fragment completes an advertisement
+		data      []byte // Data of the last advertisement completed
+		status    DataStatus
+		count     int // Fragments of the last advertisement completed
+	}{
+		{
+			name:      "single report",
+			fragments: []fragment{{eventType: 0, sid: 1, data: first}},
+			complete:  []bool{true},
+			data:      first,
+			status:    DataComplete,
+			count:     1,
+		},
+		{
+			name: "two fragments",
+			fragments: []fragment{
+				{eventType: testExtIncomplete, sid: 1, data: first},
+				{eventType: 0, sid: 1, data: rest},
+			},
+			complete: []bool{false, true},
+			data:     append(append([]byte(nil), first...), rest...),
+			status:   DataComplete,
+			count:    2,
+		},
+		{
+			name: "truncated by the controller",
+			fragments: []fragment{
+				{eventType: testExtIncomplete, sid: 1, data: first},
+				{eventType: testExtTruncated, sid: 1, data: nil},
+			},
+			complete: []bool{false, true},
+			data:     first,
+			status:   DataTruncated,
+			count:    2,
+		},
+		{
+			name: "other set's fragment doesn't join",
+			fragments: []fragment{
+				{eventType: testExtIncomplete, sid: 1, data: first},
+				{eventType: 0, sid: 2, data: rest},
+			},
+			complete: []bool{false, true},
+			data:     rest,
+			status:   DataComplete,
+			count:    1,
+		},
+		{
+			name: "stale fragment discarded",
+			fragments: []fragment{
+				{eventType: testExtIncomplete, sid: 1, data: first},
+				{eventType: 0, sid: 1, data: rest, after: reassemblyTimeout + time.Second},
+			},
+			complete: []bool{false, true},
+			data:     rest,
+			status:   DataComplete,
+			count:    1,
+		},
+		{
+			name: "longer than the maximum",
+			fragments: []fragment{
+				{eventType: testExtIncomplete, sid: 1, data: make([]byte, 251)},
+				{eventType: testExtIncomplete, sid: 1, data: make([]byte, 251)},
+				{eventType: testExtIncomplete, sid: 1, data: make([]byte, 251)},
+				{eventType: testExtIncomplete, sid: 1, data: make([]byte, 251)},
+				{eventType: testExtIncomplete, sid: 1, data: make([]byte, 251)},
+				{eventType: testExtIncomplete, sid: 1, data: make([]byte, 251)},
+				{eventType: testExtIncomplete, sid: 1, data: make([]byte, 251)},
+			},
+			complete: []bool{false, false, false, false, false, false, true},
+			data:     make([]byte, MaxExtendedAdvData),
+			status:   DataTruncated,
+			count:    7,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReassembler()
+			var adv Advertisement
+			for i, f := range tt.fragments {
+				reports, err := ParseExtendedAdvertisingReports(extEvent(extReport(f.eventType, f.sid, f.data)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				a, ok := r.Add(reports[0], ts.Add(f.after))
+				if ok != tt.complete[i] {
+					t.Fatalf("fragment %d: complete = %v, want %v", i, ok, tt.complete[i])
+				}
+				if ok {
+					adv = a
+				}
+			}
+			if !bytes.Equal(adv.RawData, tt.data) {
+				t.Errorf("data = %x, want %x", adv.RawData, tt.data)
+			}
+			if adv.Extended == nil {
+				t.Fatal("no extended advertising info")
+			}
+			if adv.Extended.DataStatus != tt.status || adv.Extended.Fragments != tt.count {
+				t.Errorf("status = %v, fragments = %d, want %v and %d", adv.Extended.DataStatus, adv.Extended.Fragments, tt.status, tt.count)
+			}
+			if adv.AddressType != AddressTypeRandomStatic || adv.TxPowerLevel != nil {
+				t.Errorf("address type = %v, Tx power = %v", adv.AddressType, adv.TxPowerLevel)
+			}
+		})
+	}
+}
+
+func TestParseAdvertisingReports(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	// ADV_IND from a public address with a name and RSSI -60
+	report := mustHex(t, "00"+"00"+"665544332211"+"06"+"050954657374"+"c4")
+	tests := []struct {
+		name    string
+		params  []byte
+		adverts int
+		wantErr bool
+	}{
+		{name: "single report", params: append([]byte{1}, report...), adverts: 1},
+		{name: "two reports", params: append(append([]byte{2}, report...), report...), adverts: 2},
+		{name: "empty event", params: nil, wantErr: true},
+		{name: "truncated header", params: append([]byte{1}, report[:5]...), wantErr: true},
+		{name: "missing RSSI", params: append([]byte{1}, report[:len(report)-1]...), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ads, err := ParseAdvertisingReports(tt.params, ts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if len(ads) != tt.adverts {
+				t.Fatalf("adverts = %d, want %d", len(ads), tt.adverts)
+			}
+			for _, adv := range ads {
+				if adv.Address != "11:22:33:44:55:66" || adv.AddressType != AddressTypePublic || adv.RSSI != -60 {
+					t.Errorf("address = %s (%v), RSSI = %d", adv.Address, adv.AddressType, adv.RSSI)
+				}
+				if !adv.Connectable || adv.ScanResponse || adv.LocalName != "Test" {
+					t.Errorf("connectable = %v, scan response = %v, name = %q", adv.Connectable, adv.ScanResponse, adv.LocalName)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,391 @@
+package ble
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Advertisement flood detection. Popup-spam tools send a fixed payload
+// template from a new random address every few advertisements; normal
+// devices rotate addresses every ~15 minutes. A template that produces many
+// new, short-lived random addresses in a short window is reported as a flood.
+
+const (
+	// New addresses are counted per template over this window
+	floodWindow = 30 * time.Second
+	// A template producing this many new addresses within floodWindow is a
+	// flood candidate (120 per minute, far above crowd-level rotation)
+	floodMinAddresses = 60
+	// Addresses not heard for this long are considered gone (short-lived)
+	floodQuietAfter = 5 * time.Second
+	// At least this fraction of the window's addresses must already be gone
+	floodMinShortLived = 0.5
+	// RSSI gap separating source clusters
+	floodSourceGap = 6
+	// New addresses of a flooding template admitted to the device table per
+	// floodWindow when rate limiting
+	floodAdmitPerWindow = 10
+)
+
+// Flood describes an ongoing advertisement flood from one payload template
+type Flood struct {
+	Template    string
+	Description string
+	Rate        float64 // New addresses per minute over the detection window
+	Addresses   int     // Distinct addresses attributed to the flood
+	Adverts     int     // Advertisements attributed to the flood
+	Suppressed  int     // New addresses not admitted because of rate limiting
+	Sources     []int16 // Estimated transmitters, as the median RSSI of each cluster
+	FirstSeen   time.Time
+	LastSeen    time.Time
+
+	suppressedAdverts int // Advertisements from suppressed addresses
+}
+
+// FormatSources describes the estimated number of transmitters
+func (f *Flood) FormatSources() string {
+	if len(f.Sources) == 0 {
+		return "unknown sources"
+	}
+	rssi := make([]string, len(f.Sources))
+	for i, r := range f.Sources {
+		rssi[i] = fmt.Sprintf("%d", r)
+	}
+	noun := "source"
+	if len(f.Sources) > 1 {
+		noun = "sources"
+	}
+	return fmt.Sprintf("~%d %s (RSSI %s)", len(f.Sources), noun, strings.Join(rssi, ", "))
+}
+
+// templateStats tracks new-address arrivals for one payload template
+type templateStats struct {
+	arrivals []floodArrival
+	flood    *Flood
+	// Addresses kept out of the device table, with when each was last
+	// heard, so their later advertisements aren't new arrivals again
+	suppressed map[string]time.Time
+	// When the addresses admitted under rate limiting arrived, within floodWindow
+	admitted []time.Time
+}
+
+type floodArrival struct {
+	address string
+	at      time.Time
+	rssi    int16
+}
+
+// Apple Continuity message types, for flood descriptions
+var continuityTypes = map[byte]string{
+	0x02: "iBeacon",
+	0x05: "AirDrop",
+	0x07: "Proximity Pairing",
+	0x09: "AirPlay Target",
+	0x0C: "Handoff",
+	0x0F: "Nearby Action",
+	0x10: "Nearby Info",
+	0x12: "Find My",
+}
+
+// payloadTemplate returns a signature of the advertisement's structure with
+// the variable parts (address, name, payload contents) removed, and a human
+// readable description of it
+func (a *Advertisement) payloadTemplate() (string, string) {
+	var sig, desc []string
+
+	if len(a.ManufacturerData) >= 2 {
+		companyID := uint16(a.ManufacturerData[0]) | uint16(a.ManufacturerData[1])<<8
+		first := -1
+		if len(a.ManufacturerData) > 2 {
+			first = int(a.ManufacturerData[2])
+		}
+		sig = append(sig, fmt.Sprintf("mfg:%04x/%d/%d", companyID, first, len(a.ManufacturerData)))
+
+		d := GetManufacturerName(companyID)
+		if companyID == companyApple && first >= 0 {
+			if name, ok := continuityTypes[byte(first)]; ok {
+				d = "Apple " + name
+			} else {
+				d = fmt.Sprintf("Apple type 0x%02X", first)
+			}
+		}
+		desc = append(desc, d)
+	}
+
+	var services []string
+	for uuid, data := range a.ServiceData {
+		services = append(services, fmt.Sprintf("svc:%s/%d", FormatUUID(uuid), len(data)))
+	}
+	sort.Strings(services)
+	sig = append(sig, services...)
+	if len(services) > 0 {
+		if _, ok := a.serviceData16(fastPairServiceUUID); ok {
+			desc = append(desc, "Fast Pair")
+		} else {
+			desc = append(desc, strings.TrimPrefix(strings.SplitN(services[0], "/", 2)[0], "svc:"))
+		}
+	}
+
+	uuids := append([]string(nil), a.ServiceUUIDs...)
+	sort.Strings(uuids)
+	if len(uuids) > 0 {
+		sig = append(sig, "uuids:"+strings.Join(uuids, ","))
+	}
+
+	if len(sig) == 0 {
+		return "", ""
+	}
+	if len(desc) == 0 {
+		desc = append(desc, "service UUIDs only")
+	}
+	return strings.Join(sig, "|"), strings.Join(desc, " + ")
+}
+
+// SetFloodRateLimit controls whether new addresses matching a flooding
+// template are rate limited, admitting floodAdmitPerWindow of them per
+// floodWindow to the device table. It must be called before Start.
+func (s *Scanner) SetFloodRateLimit(enabled bool) {
+	s.floodLimit = enabled
+}
+
+// checkFlood records a new address for the advertisement's template and
+// reports whether it should be kept out of the device table. Called with
+// s.mu held, before the device is created.
+func (s *Scanner) checkFlood(adv Advertisement) (template string, suppress bool) {
+	if adv.AddressType == AddressTypePublic {
+		return "", false
+	}
+	template, desc := adv.payloadTemplate()
+	if template == "" {
+		return "", false
+	}
+
+	ts, ok := s.templates[template]
+	if !ok {
+		ts = &templateStats{suppressed: make(map[string]time.Time)}
+		s.templates[template] = ts
+	}
+	now := adv.Timestamp
+	if _, ok := ts.suppressed[adv.Address]; ok {
+		ts.suppressed[adv.Address] = now
+		if f := ts.flood; f != nil {
+			f.LastSeen = now
+			f.Adverts++
+			f.suppressedAdverts++
+		}
+		return template, true
+	}
+	ts.arrivals = append(ts.arrivals, floodArrival{address: adv.Address, at: now, rssi: adv.RSSI})
+	cutoff := 0
+	for cutoff < len(ts.arrivals) && now.Sub(ts.arrivals[cutoff].at) > floodWindow {
+		cutoff++
+	}
+	ts.arrivals = ts.arrivals[cutoff:]
+
+	if ts.flood == nil && len(ts.arrivals) >= floodMinAddresses && s.shortLived(ts, now) {
+		// Addresses that arrived before detection count towards the flood
+		ts.flood = &Flood{Template: template, Description: desc, FirstSeen: ts.arrivals[0].at, Addresses: len(ts.arrivals) - 1}
+	}
+	if ts.flood == nil {
+		return template, false
+	}
+
+	f := ts.flood
+	f.LastSeen = now
+	f.Addresses++
+	f.Rate = float64(len(ts.arrivals)) / floodWindow.Minutes()
+	f.Sources = estimateSources(ts.arrivals)
+	if s.floodLimit {
+		cutoff := 0
+		for cutoff < len(ts.admitted) && now.Sub(ts.admitted[cutoff]) > floodWindow {
+			cutoff++
+		}
+		ts.admitted = ts.admitted[cutoff:]
+		if len(ts.admitted) >= floodAdmitPerWindow {
+			f.Suppressed++
+			f.Adverts++
+			f.suppressedAdverts++
+			ts.suppressed[adv.Address] = now
+			return template, true
+		}
+		ts.admitted = append(ts.admitted, now)
+	}
+	return template, false
+}
+
+// shortLived reports whether most of a template's arrivals have already gone
+// quiet. Called with s.mu held.
+func (s *Scanner) shortLived(ts *templateStats, now time.Time) bool {
+	quiet := 0
+	for _, a := range ts.arrivals {
+		var lastSeen time.Time
+		if d, ok := s.devices[a.address]; ok {
+			d.mu.RLock()
+			lastSeen = d.LastSeen
+			d.mu.RUnlock()
+		} else if heard, ok := ts.suppressed[a.address]; ok {
+			lastSeen = heard
+		}
+		if now.Sub(lastSeen) > floodQuietAfter {
+			quiet++
+		}
+	}
+	return float64(quiet) >= floodMinShortLived*float64(len(ts.arrivals))
+}
+
+// estimateSources clusters arrival RSSIs: spam from one transmitter arrives
+// at a consistent signal strength, so each cluster is likely one source
+func estimateSources(arrivals []floodArrival) []int16 {
+	rssi := make([]int, len(arrivals))
+	for i, a := range arrivals {
+		rssi[i] = int(a.rssi)
+	}
+	sort.Ints(rssi)
+
+	var sources []int16
+	start := 0
+	for i := 1; i <= len(rssi); i++ {
+		if i == len(rssi) || rssi[i]-rssi[i-1] > floodSourceGap {
+			// Ignore stray clusters under 10% of arrivals
+			if (i-start)*10 >= len(rssi) {
+				sources = append(sources, int16(rssi[(start+i)/2]))
+			}
+			start = i
+		}
+	}
+	return sources
+}
+
+// countFloodAdvert attributes an advertisement from a known device to its
+// template's flood, if any. Called with s.mu held.
+func (s *Scanner) countFloodAdvert(template string) {
+	if ts, ok := s.templates[template]; ok && ts.flood != nil {
+		ts.flood.Adverts++
+	}
+}
+
+// expireFloods ends floods whose template has stopped producing new
+// addresses once their addresses have gone stale, and forgets idle
+// templates. Called with s.mu held.
+func (s *Scanner) expireFloods(now time.Time) {
+	// A flood with members still in the device table keeps collapsing them
+	members := make(map[string]bool)
+	for _, d := range s.devices {
+		d.mu.RLock()
+		if d.FloodTemplate != "" {
+			members[d.FloodTemplate] = true
+		}
+		d.mu.RUnlock()
+	}
+
+	for template, ts := range s.templates {
+		cutoff := 0
+		for cutoff < len(ts.arrivals) && now.Sub(ts.arrivals[cutoff].at) > floodWindow {
+			cutoff++
+		}
+		ts.arrivals = ts.arrivals[cutoff:]
+		// A suppressed address that went quiet is new again if it returns,
+		// like a device removed as stale
+		for address, heard := range ts.suppressed {
+			if now.Sub(heard) > DeviceTimeout {
+				delete(ts.suppressed, address)
+			}
+		}
+		if ts.flood != nil {
+			ts.flood.Rate = float64(len(ts.arrivals)) / floodWindow.Minutes()
+			if members[template] || len(ts.suppressed) > 0 {
+				continue
+			}
+		}
+		if len(ts.arrivals) == 0 {
+			delete(s.templates, template)
+		}
+	}
+}
+
+// GetFloods returns the ongoing floods, largest first
+func (s *Scanner) GetFloods() []Flood {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var floods []Flood
+	for _, ts := range s.templates {
+		if ts.flood != nil {
+			f := *ts.flood
+			f.Sources = append([]int16(nil), ts.flood.Sources...)
+			floods = append(floods, f)
+		}
+	}
+	sort.Slice(floods, func(i, j int) bool {
+		return floods[i].Addresses > floods[j].Addresses
+	})
+	return floods
+}
+
+// floodAggregate builds a single device standing in for every device of a
+// flooding template. Called with s.mu held.
+func (s *Scanner) floodAggregate(f *Flood) *Device {
+	agg := &Device{
+		ID:           "flood:" + f.Template,
+		Name:         "FLOOD: " + f.Description,
+		Address:      fmt.Sprintf("%d addresses", f.Addresses),
+		ServiceData:  make(map[string][]byte),
+		FirstSeen:    f.FirstSeen,
+		LastSeen:     f.LastSeen,
+		AdvCount:     f.suppressedAdverts,
+		FloodMembers: f.Addresses,
+	}
+
+	var rssiSum, smoothedSum float64
+	var members int
+	var latest *Device
+	for _, d := range s.devices {
+		d.mu.RLock()
+		if d.FloodTemplate == f.Template {
+			members++
+			agg.AdvCount += d.AdvCount
+			rssiSum += d.RSSIAverage
+			smoothedSum += d.RSSISmoothed
+			if latest == nil || d.LastSeen.After(latest.LastSeen) {
+				latest = d
+			}
+		}
+		d.mu.RUnlock()
+	}
+	if members > 0 {
+		agg.RSSIAverage = rssiSum / float64(members)
+		agg.RSSISmoothed = smoothedSum / float64(members)
+	}
+
+	// Representative payload from the most recently heard member
+	if latest != nil {
+		c := latest.Copy()
+		agg.AddressType = c.AddressType
+		agg.RSSICurrent = c.RSSICurrent
+		agg.ManufacturerID = c.ManufacturerID
+		agg.ManufacturerData = c.ManufacturerData
+		agg.ServiceUUIDs = c.ServiceUUIDs
+		agg.ServiceData = c.ServiceData
+		agg.ADTypes = c.ADTypes
+		agg.Decoded = c.Decoded
+		if c.LastSeen.After(agg.LastSeen) {
+			agg.LastSeen = c.LastSeen
+		}
+	}
+	return agg
+}
+
+// flooding returns the ongoing flood for a template, if any. Called with
+// s.mu held.
+func (s *Scanner) flooding(template string) (*Flood, bool) {
+	if template == "" {
+		return nil, false
+	}
+	ts, ok := s.templates[template]
+	if !ok || ts.flood == nil {
+		return nil, false
+	}
+	return ts.flood, true
+}
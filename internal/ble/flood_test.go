@@ -0,0 +1,95 @@
+package ble
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// spamAdvert is an Apple Proximity Pairing popup from a fresh non-resolvable
+// private address
+func spamAdvert(i int, at time.Time) Advertisement {
+	adv := NewAdvertisement()
+	adv.Address = fmt.Sprintf("0A:00:00:00:%02X:%02X", i>>8, i&0xFF)
+	adv.AddressType = AddressTypeNonResolvablePrivate
+	adv.Timestamp = at
+	adv.RSSI = -50
+	adv.ManufacturerData = []byte{0x4C, 0x00, 0x07, 0x19, 0x01, 0x02, 0x20}
+	return adv
+}
+
+// floodScanner feeds a scanner one new address every 200ms, each heard once,
+// and returns the time of the last advertisement
+func floodScanner(t *testing.T, s *Scanner, addresses int) time.Time {
+	t.Helper()
+	start := time.Now()
+	var at time.Time
+	for i := 0; i < addresses; i++ {
+		at = start.Add(time.Duration(i) * 200 * time.Millisecond)
+		s.ingest(spamAdvert(i, at))
+	}
+	return at
+}
+
+func TestFloodDetected(t *testing.T) {
+	s := NewScanner()
+	floodScanner(t, s, 100)
+
+	floods := s.GetFloods()
+	if len(floods) != 1 {
+		t.Fatalf("floods = %d, want 1", len(floods))
+	}
+	if f := floods[0]; f.Addresses != 100 || f.Suppressed != 0 {
+		t.Errorf("addresses = %d, suppressed = %d, want 100 and 0", f.Addresses, f.Suppressed)
+	}
+	devices := s.GetDevices()
+	if len(devices) != 1 || devices[0].FloodMembers != 100 {
+		t.Fatalf("GetDevices returned %d rows, want one FLOOD row of 100 members", len(devices))
+	}
+}
+
+func TestFloodRateLimitAdmitsPerWindow(t *testing.T) {
+	s := NewScanner()
+	s.SetFloodRateLimit(true)
+	floodScanner(t, s, 200)
+
+	// The flood is detected on the 60th address; from then on 10 addresses
+	// are admitted in the remaining 28 seconds, within one window
+	if got, want := len(s.devices), floodMinAddresses-1+floodAdmitPerWindow; got != want {
+		t.Errorf("device table holds %d addresses, want %d", got, want)
+	}
+	f := s.GetFloods()[0]
+	if f.Addresses != 200 || f.Suppressed != 200-len(s.devices) {
+		t.Errorf("addresses = %d, suppressed = %d, want 200 and %d", f.Addresses, f.Suppressed, 200-len(s.devices))
+	}
+}
+
+func TestFloodOutlivesNewAddressesUntilMembersExpire(t *testing.T) {
+	s := NewScanner()
+	s.SetFloodRateLimit(true)
+	last := floodScanner(t, s, 100)
+
+	// No new addresses for a while, but members are still in the table
+	s.expireFloods(last.Add(2 * floodWindow))
+	if len(s.GetFloods()) != 1 {
+		t.Fatal("flood ended while its members were still in the device table")
+	}
+	if devices := s.GetDevices(); len(devices) != 1 {
+		t.Fatalf("GetDevices returned %d rows, want the members collapsed into one", len(devices))
+	}
+	// A returning address joins the flood rather than getting its own row
+	s.ingest(spamAdvert(99, last.Add(2*floodWindow)))
+	if devices := s.GetDevices(); len(devices) != 1 || devices[0].FloodMembers != 101 {
+		t.Fatalf("returning address was not collapsed into the flood")
+	}
+
+	// Members went stale and were removed
+	s.devices = make(map[string]*Device)
+	s.expireFloods(last.Add(3*floodWindow + DeviceTimeout))
+	if floods := s.GetFloods(); len(floods) != 0 {
+		t.Fatalf("flood still reported after its addresses expired: %+v", floods)
+	}
+	if len(s.templates) != 0 {
+		t.Errorf("%d templates kept after the flood expired", len(s.templates))
+	}
+}
@@ -0,0 +1,105 @@
+package ble
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/buckleypaul/blescan/internal/stats/history"
+)
+
+// Long-term device history. Every device keeps its latest raw
+// advertisements in a ring buffer, and aggregates per second and per minute
+// that cover far longer, each capped so a long session stays within bounded
+// memory. The buffers grow as a device is heard, so a device seen only
+// briefly costs little however high the limits are.
+//
+// Each aggregate keeps the manufacturer data and service data of its last
+// advertisement alive, which can be a few hundred bytes more once the raw
+// advertisement has left its ring. Aggregates share them while the payload
+// doesn't change, so a device with a static payload pays for it once.
+
+// HistoryLimits caps how much history each device keeps
+type HistoryLimits struct {
+	Adverts int // Raw advertisements, about 300 bytes each plus payload
+	Seconds int // Per-second points, about 100 bytes each plus payload
+	Minutes int // Per-minute points, about 100 bytes each plus payload
+}
+
+// DefaultHistoryLimits returns the limits used when nothing is configured:
+// 1000 advertisements, an hour of seconds and a week of minutes
+func DefaultHistoryLimits() HistoryLimits {
+	return HistoryLimits{Adverts: 1000, Seconds: 3600, Minutes: 7 * 24 * 60}
+}
+
+// Validate checks the limits for out-of-range values
+func (l HistoryLimits) Validate() error {
+	if l.Adverts < maxAdvertisements {
+		return fmt.Errorf("advertisement history %d below the minimum of %d", l.Adverts, maxAdvertisements)
+	}
+	if l.Seconds < 1 || l.Minutes < 1 {
+		return fmt.Errorf("history must keep at least one second and one minute")
+	}
+	return nil
+}
+
+var (
+	historyMu     sync.RWMutex
+	historyLimits = DefaultHistoryLimits()
+)
+
+// SetHistoryLimits sets the history limits of devices discovered from now
+// on. It should be called before scanning starts.
+func SetHistoryLimits(l HistoryLimits) error {
+	if err := l.Validate(); err != nil {
+		return err
+	}
+	historyMu.Lock()
+	historyLimits = l
+	historyMu.Unlock()
+	return nil
+}
+
+// CurrentHistoryLimits returns the history limits of new devices
+func CurrentHistoryLimits() HistoryLimits {
+	historyMu.RLock()
+	defer historyMu.RUnlock()
+	return historyLimits
+}
+
+// recordHistory adds an advertisement to the raw and aggregated history.
+// Called with d.mu held.
+func (d *Device) recordHistory(adv Advertisement) {
+	d.adverts.Push(adv)
+	d.trend.Add(adv.Timestamp, adv.RSSI, adv.ManufacturerData, adv.ServiceData)
+}
+
+// recentAdvertisements returns the newest n advertisements, oldest first:
+// from the ring on a scanner's device, or from Advertisements on a copy.
+// Called with d.mu held.
+func (d *Device) recentAdvertisements(n int) []Advertisement {
+	if d.adverts == nil {
+		return append([]Advertisement(nil), d.Advertisements[max(0, len(d.Advertisements)-n):]...)
+	}
+	return d.adverts.Tail(n)
+}
+
+// AllAdvertisements returns every advertisement in the raw history, oldest
+// first
+func (d *Device) AllAdvertisements() []Advertisement {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.adverts == nil {
+		return append([]Advertisement(nil), d.Advertisements...)
+	}
+	return d.adverts.Slice()
+}
+
+// Trend returns the device's per-second and per-minute history, oldest first
+func (d *Device) Trend() (seconds, minutes []history.Point) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.trend == nil {
+		return nil, nil
+	}
+	return d.trend.Seconds(), d.trend.Minutes()
+}
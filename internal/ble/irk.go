@@ -0,0 +1,237 @@
+package ble
+
+import (
+	"bufio"
+	"crypto/aes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// IdentityKey is an Identity Resolving Key for one of the user's own devices
+type IdentityKey struct {
+	Name            string   // Friendly name shown in place of the rotating address
+	IRK             [16]byte // Most significant octet first, as printed in the Core spec
+	IdentityAddress string   // Public or static identity address, if known
+}
+
+// DeviceID returns the scanner's device ID for the identity. Names aren't
+// unique (two bonded "AirPods"), so it's the identity address, or the IRK
+// itself when the address isn't known.
+func (k *IdentityKey) DeviceID() string {
+	if k.IdentityAddress != "" {
+		return "irk:" + k.IdentityAddress
+	}
+	return "irk:" + hex.EncodeToString(k.IRK[:])
+}
+
+// ah is the random address hash function from Core spec Vol 3, Part H, 2.2.2:
+// ah(k, r) = e(k, r') mod 2^24, where r' is r padded with 104 zero bits.
+// r is the 24-bit prand, most significant octet first.
+func ah(irk [16]byte, r [3]byte) [3]byte {
+	block, err := aes.NewCipher(irk[:])
+	if err != nil {
+		panic(err) // 16-byte keys are always valid
+	}
+	var in, out [16]byte
+	copy(in[13:], r[:])
+	block.Encrypt(out[:], in[:])
+	return [3]byte{out[13], out[14], out[15]}
+}
+
+// ResolvePrivateAddress reports whether a resolvable private address was
+// generated from the given IRK. The upper 24 bits of an RPA are prand and
+// the lower 24 bits are ah(IRK, prand).
+func ResolvePrivateAddress(irk [16]byte, address string) bool {
+	mac, ok := ParseAddress(address)
+	if !ok || mac[0]>>6 != 0b01 {
+		return false
+	}
+	hash := ah(irk, [3]byte{mac[0], mac[1], mac[2]})
+	return hash == [3]byte{mac[3], mac[4], mac[5]}
+}
+
+// maxResolverCache bounds the per-address resolution cache. Addresses rotate,
+// so old entries are useless; the cache is simply reset when it fills up.
+const maxResolverCache = 4096
+
+// Resolver maps resolvable private addresses to known identities
+type Resolver struct {
+	keys  []IdentityKey
+	cache map[string]*IdentityKey // nil value caches a failed resolution
+	mu    sync.Mutex
+}
+
+// NewResolver creates a resolver for the given identity keys
+func NewResolver(keys []IdentityKey) *Resolver {
+	return &Resolver{
+		keys:  keys,
+		cache: make(map[string]*IdentityKey),
+	}
+}
+
+// KeyCount returns the number of loaded identity keys
+func (r *Resolver) KeyCount() int {
+	return len(r.keys)
+}
+
+// Resolve returns the identity whose IRK generated the address
+func (r *Resolver) Resolve(address string) (*IdentityKey, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key, ok := r.cache[address]; ok {
+		return key, key != nil
+	}
+	if len(r.cache) >= maxResolverCache {
+		r.cache = make(map[string]*IdentityKey)
+	}
+
+	for i := range r.keys {
+		if ResolvePrivateAddress(r.keys[i].IRK, address) {
+			r.cache[address] = &r.keys[i]
+			return &r.keys[i], true
+		}
+	}
+	r.cache[address] = nil
+	return nil, false
+}
+
+// LoadIdentityKeys loads IRKs from a keys file, a BlueZ device "info" file,
+// or a directory searched recursively for BlueZ "info" files
+// (e.g. /var/lib/bluetooth).
+//
+// Keys files have one key per line: "<name> <32 hex digits> [identity address]",
+// with the IRK written most significant octet first. Blank lines and lines
+// starting with '#' are ignored.
+func LoadIdentityKeys(path string) ([]IdentityKey, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return loadBlueZKeyDir(path)
+	}
+	if filepath.Base(path) == "info" {
+		key, ok, err := loadBlueZInfo(path)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return []IdentityKey{key}, nil
+	}
+	return loadKeysFile(path)
+}
+
+func loadKeysFile(path string) ([]IdentityKey, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var keys []IdentityKey
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"<name> <irk>\"", path, lineNum)
+		}
+		irk, err := parseIRK(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+		key := IdentityKey{Name: fields[0], IRK: irk}
+		if len(fields) > 2 {
+			key.IdentityAddress = strings.ToUpper(fields[2])
+		}
+		keys = append(keys, key)
+	}
+	return keys, scanner.Err()
+}
+
+func parseIRK(s string) ([16]byte, error) {
+	var irk [16]byte
+	s = strings.TrimPrefix(strings.ReplaceAll(s, ":", ""), "0x")
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 16 {
+		return irk, fmt.Errorf("invalid IRK %q: want 32 hex digits", s)
+	}
+	copy(irk[:], b)
+	return irk, nil
+}
+
+// loadBlueZKeyDir walks a BlueZ storage directory for device info files
+func loadBlueZKeyDir(root string) ([]IdentityKey, error) {
+	var keys []IdentityKey
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != "info" {
+			return nil
+		}
+		key, ok, err := loadBlueZInfo(path)
+		if err != nil {
+			return err
+		}
+		if ok {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// loadBlueZInfo reads the IRK from a BlueZ device info file
+// (/var/lib/bluetooth/<adapter>/<device>/info). BlueZ stores the key in the
+// order it was distributed over SMP, least significant octet first.
+func loadBlueZInfo(path string) (IdentityKey, bool, error) {
+	var key IdentityKey
+	f, err := os.Open(path)
+	if err != nil {
+		return key, false, err
+	}
+	defer f.Close()
+
+	// The device directory is named after its identity address
+	key.IdentityAddress = strings.ToUpper(filepath.Base(filepath.Dir(path)))
+	key.Name = key.IdentityAddress
+
+	section := ""
+	found := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = line[1 : len(line)-1]
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch {
+		case section == "General" && name == "Name" && value != "":
+			key.Name = value
+		case section == "IdentityResolvingKey" && name == "Key":
+			irk, err := parseIRK(value)
+			if err != nil {
+				return key, false, fmt.Errorf("%s: %w", path, err)
+			}
+			for i := range irk {
+				key.IRK[i] = irk[15-i]
+			}
+			found = true
+		}
+	}
+	return key, found, scanner.Err()
+}
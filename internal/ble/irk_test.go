@@ -0,0 +1,54 @@
+package ble
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func mustIRK(t *testing.T, s string) [16]byte {
+	t.Helper()
+	irk, err := parseIRK(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return irk
+}
+
+// Sample data from Core spec Vol 3, Part H, Appendix D.7
+func TestAhSpecSample(t *testing.T) {
+	irk := mustIRK(t, "ec0234a357c8ad05341010a60a397d9b")
+	hash := ah(irk, [3]byte{0x70, 0x81, 0x94})
+	if got := hex.EncodeToString(hash[:]); got != "0dfbaa" {
+		t.Fatalf("ah = %s, want 0dfbaa", got)
+	}
+}
+
+func TestResolvePrivateAddress(t *testing.T) {
+	irk := mustIRK(t, "ec0234a357c8ad05341010a60a397d9b")
+	tests := []struct {
+		address string
+		want    bool
+	}{
+		{"70:81:94:0D:FB:AA", true},
+		{"70:81:94:0D:FB:AB", false}, // Wrong hash
+		{"30:81:94:0D:FB:AA", false}, // Not a resolvable private address
+		{"not an address", false},
+	}
+	for _, tt := range tests {
+		if got := ResolvePrivateAddress(irk, tt.address); got != tt.want {
+			t.Errorf("ResolvePrivateAddress(%s) = %v, want %v", tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestIdentityKeyDeviceIDIgnoresName(t *testing.T) {
+	a := IdentityKey{Name: "AirPods", IRK: mustIRK(t, "ec0234a357c8ad05341010a60a397d9b"), IdentityAddress: "AA:BB:CC:DD:EE:01"}
+	b := IdentityKey{Name: "AirPods", IRK: mustIRK(t, "00112233445566778899aabbccddeeff"), IdentityAddress: "AA:BB:CC:DD:EE:02"}
+	if a.DeviceID() == b.DeviceID() {
+		t.Fatalf("identities with the same name share device ID %s", a.DeviceID())
+	}
+	a.IdentityAddress, b.IdentityAddress = "", ""
+	if a.DeviceID() == b.DeviceID() {
+		t.Fatalf("identities without addresses share device ID %s", a.DeviceID())
+	}
+}
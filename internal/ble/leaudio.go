@@ -0,0 +1,197 @@
+package ble
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// LE Audio broadcast source announcements (Basic Audio Profile and Public
+// Broadcast Profile)
+
+const (
+	broadcastAudioAnnouncementUUID  = 0x1852
+	publicBroadcastAnnouncementUUID = 0x1856
+
+	// ADTypeBroadcastName is the Broadcast_Name AD type
+	ADTypeBroadcastName = 0x30
+)
+
+// Public Broadcast Announcement features
+const (
+	pbaFeatureEncrypted       = 1 << 0
+	pbaFeatureStandardQuality = 1 << 1
+	pbaFeatureHighQuality     = 1 << 2
+)
+
+// BroadcastSource is an LE Audio broadcast source, such as an Auracast
+// transmitter
+type BroadcastSource struct {
+	BroadcastID     uint32 // 24-bit Broadcast_ID
+	Name            string // Broadcast_Name, if advertised
+	Public          bool   // Sends a Public Broadcast Announcement (Auracast)
+	Encrypted       bool   // Broadcast_Code required to listen (PBA only)
+	StandardQuality bool   // Standard Quality configuration present (PBA only)
+	HighQuality     bool   // High Quality configuration present (PBA only)
+	ProgramInfo     string
+	Language        string
+	Metadata        []ADType // All metadata LTVs, decoded where known
+}
+
+// FormatAudioConfig describes the audio configurations a public broadcast
+// offers
+func (b *BroadcastSource) FormatAudioConfig() string {
+	if !b.Public {
+		return "-"
+	}
+	var parts []string
+	if b.StandardQuality {
+		parts = append(parts, "SQ")
+	}
+	if b.HighQuality {
+		parts = append(parts, "HQ")
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, "+")
+}
+
+// FormatEncryption describes whether the broadcast needs a Broadcast_Code
+func (b *BroadcastSource) FormatEncryption() string {
+	switch {
+	case !b.Public:
+		return "unknown"
+	case b.Encrypted:
+		return "encrypted"
+	default:
+		return "open"
+	}
+}
+
+// BroadcastSource returns the broadcast source announced by the
+// advertisement, if any
+func (a *Advertisement) BroadcastSource() (*BroadcastSource, bool) {
+	baa, ok := a.serviceData16(broadcastAudioAnnouncementUUID)
+	if !ok || len(baa) < 3 {
+		return nil, false
+	}
+
+	b := &BroadcastSource{
+		BroadcastID: uint32(baa[0]) | uint32(baa[1])<<8 | uint32(baa[2])<<16,
+	}
+	if name, ok := a.FindAD(ADTypeBroadcastName); ok && utf8.Valid(name) {
+		b.Name = string(name)
+	}
+
+	// Public_Broadcast_Announcement_Features, Metadata_Length, Metadata
+	if pba, ok := a.serviceData16(publicBroadcastAnnouncementUUID); ok && len(pba) >= 2 {
+		b.Public = true
+		b.Encrypted = pba[0]&pbaFeatureEncrypted != 0
+		b.StandardQuality = pba[0]&pbaFeatureStandardQuality != 0
+		b.HighQuality = pba[0]&pbaFeatureHighQuality != 0
+
+		metadata := pba[2:]
+		if n := int(pba[1]); n < len(metadata) {
+			metadata = metadata[:n]
+		}
+		b.parseMetadata(metadata)
+	}
+	return b, true
+}
+
+// parseMetadata decodes LE Audio metadata LTVs (Assigned Numbers 6.12.6)
+func (b *BroadcastSource) parseMetadata(data []byte) {
+	for i := 0; i < len(data); {
+		length := int(data[i])
+		if length == 0 || i+1+length > len(data) {
+			break
+		}
+		ltvType, value := data[i+1], data[i+2:i+1+length]
+		i += 1 + length
+
+		switch ltvType {
+		case 0x01:
+			b.Metadata = append(b.Metadata, ADType{Name: "Preferred Contexts", Value: formatAudioContexts(value)})
+		case 0x02:
+			b.Metadata = append(b.Metadata, ADType{Name: "Streaming Contexts", Value: formatAudioContexts(value)})
+		case 0x03:
+			b.ProgramInfo = string(value)
+			b.Metadata = append(b.Metadata, ADType{Name: "Program Info", Value: b.ProgramInfo})
+		case 0x04:
+			b.Language = string(value)
+			b.Metadata = append(b.Metadata, ADType{Name: "Language", Value: b.Language})
+		case 0x06:
+			if len(value) == 1 {
+				b.Metadata = append(b.Metadata, ADType{Name: "Parental Rating", Value: formatParentalRating(value[0])})
+			}
+		case 0x07:
+			b.Metadata = append(b.Metadata, ADType{Name: "Program Info URI", Value: string(value)})
+		case 0x09:
+			b.Metadata = append(b.Metadata, ADType{Name: "Immediate Rendering", Value: "yes"})
+		default:
+			b.Metadata = append(b.Metadata, ADType{Name: fmt.Sprintf("Metadata 0x%02X", ltvType), Value: fmt.Sprintf("%x", value)})
+		}
+	}
+}
+
+// formatAudioContexts formats a 16-bit Context Type bitfield
+func formatAudioContexts(value []byte) string {
+	if len(value) < 2 {
+		return fmt.Sprintf("%x", value)
+	}
+	contexts := uint16(value[0]) | uint16(value[1])<<8
+	names := []string{
+		"Unspecified", "Conversational", "Media", "Game", "Instructional",
+		"Voice Assistants", "Live", "Sound Effects", "Notifications",
+		"Ringtone", "Alerts", "Emergency Alarm",
+	}
+	var parts []string
+	for bit, name := range names {
+		if contexts&(1<<bit) != 0 {
+			parts = append(parts, name)
+		}
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatParentalRating formats the Parental_Rating metadata value
+func formatParentalRating(v byte) string {
+	switch rating := v & 0x0F; rating {
+	case 0x00:
+		return "no rating"
+	case 0x01:
+		return "any age"
+	default:
+		return fmt.Sprintf("age %d+", int(rating)+3)
+	}
+}
+
+// decodeBroadcastAudio reports broadcast sources in the Decoded column
+func decodeBroadcastAudio(adv *Advertisement) (DecodedPayload, bool) {
+	b, ok := adv.BroadcastSource()
+	if !ok {
+		return DecodedPayload{}, false
+	}
+
+	p := DecodedPayload{
+		Protocol: "LE Audio",
+		Intent:   "broadcast",
+		Name:     b.Name,
+		Fields: []ADType{
+			{Name: "Broadcast ID", Value: fmt.Sprintf("0x%06X", b.BroadcastID)},
+		},
+	}
+	if b.Public {
+		p.Intent = "Auracast broadcast"
+		p.Fields = append(p.Fields,
+			ADType{Name: "Encryption", Value: b.FormatEncryption()},
+			ADType{Name: "Audio Config", Value: b.FormatAudioConfig()},
+		)
+		p.Fields = append(p.Fields, b.Metadata...)
+	}
+	return p, true
+}
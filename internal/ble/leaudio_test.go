@@ -0,0 +1,156 @@
+package ble
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBroadcastSource(t *testing.T) {
+	// Broadcast Audio Announcement for Broadcast_ID 0x123456, named "Jazz"
+	const baa = "0616" + "5218" + "563412" + "0530" + "4a617a7a"
+	tests := []struct {
+		name       string
+		raw        string
+		ok         bool
+		public     bool
+		config     string
+		encryption string
+		language   string
+		program    string
+		metadata   string // Metadata names and values, "name=value" joined by ";"
+	}{
+		{
+			name:       "basic audio announcement",
+			raw:        baa,
+			ok:         true,
+			config:     "-",
+			encryption: "unknown",
+		},
+		{
+			name: "open Auracast broadcast with metadata",
+			raw: baa + "1716" + "5618" + "04" + "12" +
+				"0404656e67" + "05034a617a7a" + "03020400" + "020605",
+			ok:         true,
+			public:     true,
+			config:     "HQ",
+			encryption: "open",
+			language:   "eng",
+			program:    "Jazz",
+			metadata:   "Language=eng;Program Info=Jazz;Streaming Contexts=Media;Parental Rating=age 8+",
+		},
+		{
+			name:       "encrypted with both qualities",
+			raw:        baa + "0516" + "5618" + "07" + "00",
+			ok:         true,
+			public:     true,
+			config:     "SQ+HQ",
+			encryption: "encrypted",
+		},
+		{
+			name:       "no audio configuration",
+			raw:        baa + "0516" + "5618" + "00" + "00",
+			ok:         true,
+			public:     true,
+			config:     "none",
+			encryption: "open",
+		},
+		{
+			name:       "metadata past Metadata_Length ignored",
+			raw:        baa + "0c16" + "5618" + "00" + "05" + "0404656e67" + "020605",
+			ok:         true,
+			public:     true,
+			config:     "none",
+			encryption: "open",
+			language:   "eng",
+			metadata:   "Language=eng",
+		},
+		{
+			name:       "truncated metadata LTV",
+			raw:        baa + "0816" + "5618" + "00" + "03" + "050465",
+			ok:         true,
+			public:     true,
+			config:     "none",
+			encryption: "open",
+		},
+		{
+			name:       "unknown metadata type",
+			raw:        baa + "0816" + "5618" + "00" + "03" + "020aff",
+			ok:         true,
+			public:     true,
+			config:     "none",
+			encryption: "open",
+			metadata:   "Metadata 0x0A=ff",
+		},
+		{
+			name: "announcement too short",
+			raw:  "0516" + "5218" + "5634",
+		},
+		{
+			name: "no announcement",
+			raw:  "020106",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adv := decodedAdvert(t, tt.raw)
+			b, ok := adv.BroadcastSource()
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if b.BroadcastID != 0x123456 || b.Name != "Jazz" {
+				t.Errorf("Broadcast_ID = 0x%06X, name = %q", b.BroadcastID, b.Name)
+			}
+			if b.Public != tt.public || b.FormatAudioConfig() != tt.config || b.FormatEncryption() != tt.encryption {
+				t.Errorf("public = %v, config = %s, encryption = %s, want %v, %s and %s",
+					b.Public, b.FormatAudioConfig(), b.FormatEncryption(), tt.public, tt.config, tt.encryption)
+			}
+			if b.Language != tt.language || b.ProgramInfo != tt.program {
+				t.Errorf("language = %q, program = %q, want %q and %q", b.Language, b.ProgramInfo, tt.language, tt.program)
+			}
+			var metadata []string
+			for _, m := range b.Metadata {
+				metadata = append(metadata, m.Name+"="+m.Value)
+			}
+			if got := strings.Join(metadata, ";"); got != tt.metadata {
+				t.Errorf("metadata = %q, want %q", got, tt.metadata)
+			}
+		})
+	}
+}
+
+func TestDecodeBroadcastAudio(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		summary string
+	}{
+		{
+			name:    "broadcast",
+			raw:     "0616" + "5218" + "563412" + "0530" + "4a617a7a",
+			summary: "LE Audio · broadcast · Jazz",
+		},
+		{
+			name:    "Auracast",
+			raw:     "0616" + "5218" + "563412" + "0516" + "5618" + "02" + "00",
+			summary: "LE Audio · Auracast broadcast",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adv := decodedAdvert(t, tt.raw)
+			p, ok := decodeBroadcastAudio(&adv)
+			if !ok {
+				t.Fatal("not decoded")
+			}
+			if p.Summary() != tt.summary {
+				t.Errorf("summary = %q, want %q", p.Summary(), tt.summary)
+			}
+			if got := fieldValue(p, "Broadcast ID"); got != "0x123456" {
+				t.Errorf("Broadcast ID = %q", got)
+			}
+		})
+	}
+}
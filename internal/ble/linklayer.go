@@ -0,0 +1,63 @@
+package ble
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// AdvertisingAccessAddress is the access address of every packet on the
+// primary advertising channels
+const AdvertisingAccessAddress = 0x8E89BED6
+
+// Legacy advertising channel PDU types (Core spec Vol 6, Part B, 2.3)
+const (
+	pduAdvInd        = 0x0
+	pduAdvDirectInd  = 0x1
+	pduAdvNonconnInd = 0x2
+	pduScanRsp       = 0x4
+	pduAdvScanInd    = 0x6
+)
+
+// ParseAdvertisingPDU decodes a link-layer advertising channel packet
+// (access address, PDU header and payload; a trailing CRC is ignored) as
+// captured by a sniffer. It returns false for PDUs that carry no advertiser
+// data, such as scan and connection requests or extended advertising.
+func ParseAdvertisingPDU(pkt []byte, rssi int16, ts time.Time) (Advertisement, bool, error) {
+	if len(pkt) < 6 {
+		return Advertisement{}, false, fmt.Errorf("link-layer packet: truncated header")
+	}
+	if binary.LittleEndian.Uint32(pkt[0:4]) != AdvertisingAccessAddress {
+		return Advertisement{}, false, nil
+	}
+	pduType := pkt[4] & 0x0F
+	txAdd := pkt[4]&0x40 != 0
+	length := int(pkt[5])
+	if len(pkt) < 6+length {
+		return Advertisement{}, false, fmt.Errorf("link-layer packet: truncated payload")
+	}
+	payload := pkt[6 : 6+length]
+
+	switch pduType {
+	case pduAdvInd, pduAdvDirectInd, pduAdvNonconnInd, pduScanRsp, pduAdvScanInd:
+	default:
+		return Advertisement{}, false, nil
+	}
+	if len(payload) < 6 {
+		return Advertisement{}, false, fmt.Errorf("link-layer packet: missing AdvA")
+	}
+
+	adv := NewAdvertisement()
+	adv.Timestamp = ts
+	adv.Address = hciAddress(payload[0:6])
+	adv.AddressType = ClassifyAddress(adv.Address, txAdd)
+	adv.RSSI = rssi
+	adv.Connectable = pduType == pduAdvInd || pduType == pduAdvDirectInd
+	adv.ScanResponse = pduType == pduScanRsp
+	// ADV_DIRECT_IND carries the target address rather than AD structures
+	if pduType != pduAdvDirectInd {
+		adv.RawData = append([]byte(nil), payload[6:]...)
+	}
+	adv.DecodeRawData()
+	return adv, true, nil
+}
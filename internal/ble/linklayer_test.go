@@ -0,0 +1,104 @@
+package ble
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAdvertisingPDU(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	tests := []struct {
+		name        string
+		pkt         string // Access address, PDU header, payload, CRC
+		ok          bool
+		wantErr     bool
+		address     string
+		addressType AddressType
+		connectable bool
+		scanRsp     bool
+		localName   string
+	}{
+		{
+			name:        "ADV_IND from a random static address",
+			pkt:         "d6be898e" + "400f" + "5544332211c6" + "020106" + "0509" + "54657374" + "aabbcc",
+			ok:          true,
+			address:     "C6:11:22:33:44:55",
+			addressType: AddressTypeRandomStatic,
+			connectable: true,
+			localName:   "Test",
+		},
+		{
+			name:        "ADV_NONCONN_IND from a public address",
+			pkt:         "d6be898e" + "0209" + "665544332211" + "020106" + "aabbcc",
+			ok:          true,
+			address:     "11:22:33:44:55:66",
+			addressType: AddressTypePublic,
+		},
+		{
+			name:        "SCAN_RSP from a resolvable private address",
+			pkt:         "d6be898e" + "440c" + "665544332252" + "0509" + "54657374",
+			ok:          true,
+			address:     "52:22:33:44:55:66",
+			addressType: AddressTypeResolvablePrivate,
+			scanRsp:     true,
+			localName:   "Test",
+		},
+		{
+			name:        "ADV_DIRECT_IND carries no AD structures",
+			pkt:         "d6be898e" + "810c" + "665544332211" + "0c0b0a090807",
+			ok:          true,
+			address:     "11:22:33:44:55:66",
+			addressType: AddressTypePublic,
+			connectable: true,
+		},
+		{
+			name: "SCAN_REQ is skipped",
+			pkt:  "d6be898e" + "c30c" + "665544332211" + "0c0b0a090807",
+		},
+		{
+			name: "data channel packet is skipped",
+			pkt:  "78563412" + "0100",
+		},
+		{
+			name:    "length past the end",
+			pkt:     "d6be898e" + "0020" + "665544332211",
+			wantErr: true,
+		},
+		{
+			name:    "no AdvA",
+			pkt:     "d6be898e" + "0003" + "665544",
+			wantErr: true,
+		},
+		{
+			name:    "truncated header",
+			pkt:     "d6be898e00",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adv, ok, err := ParseAdvertisingPDU(mustHex(t, tt.pkt), -60, ts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if adv.Address != tt.address || adv.AddressType != tt.addressType {
+				t.Errorf("address = %s (%v), want %s (%v)", adv.Address, adv.AddressType, tt.address, tt.addressType)
+			}
+			if adv.Connectable != tt.connectable || adv.ScanResponse != tt.scanRsp {
+				t.Errorf("connectable = %v, scan response = %v, want %v and %v", adv.Connectable, adv.ScanResponse, tt.connectable, tt.scanRsp)
+			}
+			if adv.LocalName != tt.localName {
+				t.Errorf("local name = %q, want %q", adv.LocalName, tt.localName)
+			}
+			if adv.RSSI != -60 || !adv.Timestamp.Equal(ts) {
+				t.Errorf("RSSI = %d, timestamp = %v", adv.RSSI, adv.Timestamp)
+			}
+		})
+	}
+}
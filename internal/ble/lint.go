@@ -0,0 +1,194 @@
+package ble
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// LintSeverity ranks a lint finding
+type LintSeverity int
+
+const (
+	LintWarning LintSeverity = iota // Works with most scanners but isn't spec compliant
+	LintError                       // Malformed; scanners may drop or misparse it
+)
+
+// String returns the severity name
+func (s LintSeverity) String() string {
+	if s == LintError {
+		return "error"
+	}
+	return "warning"
+}
+
+// LintFinding is a single Core Specification Supplement violation
+type LintFinding struct {
+	Severity LintSeverity
+	Code     string // Stable identifier, e.g. "ad-overrun"
+	Offset   int    // Offset into the raw data, -1 if not applicable
+	Message  string
+}
+
+// String formats the finding as "severity code @offset: message"
+func (f LintFinding) String() string {
+	if f.Offset < 0 {
+		return fmt.Sprintf("%s %s: %s", f.Severity, f.Code, f.Message)
+	}
+	return fmt.Sprintf("%s %s @%d: %s", f.Severity, f.Code, f.Offset, f.Message)
+}
+
+// AD types that may appear at most once per advertising data block
+// (Core Specification Supplement Part A, Table 1.1)
+var singleInstanceADTypes = map[uint8]string{
+	0x01: "Flags",
+	0x08: "Shortened Local Name",
+	0x09: "Complete Local Name",
+	0x0A: "TX Power Level",
+	0x0D: "Class of Device",
+	0x19: "Appearance",
+	0x1A: "Advertising Interval",
+	0x1B: "LE Bluetooth Device Address",
+	0x1C: "LE Role",
+	0x24: "URI",
+}
+
+// Fixed AD data lengths (excluding the type octet)
+var fixedADLengths = map[uint8]int{
+	0x0A: 1, // TX Power Level
+	0x0D: 3, // Class of Device
+	0x19: 2, // Appearance
+	0x1B: 7, // LE Bluetooth Device Address
+	0x1C: 1, // LE Role
+}
+
+// UUID list AD types and the size of each UUID
+var uuidListSizes = map[uint8]int{
+	0x02: 2, 0x03: 2, // 16-bit Service UUIDs
+	0x04: 4, 0x05: 4, // 32-bit Service UUIDs
+	0x06: 16, 0x07: 16, // 128-bit Service UUIDs
+	0x14: 2, 0x1F: 4, 0x15: 16, // Service Solicitation UUIDs
+}
+
+// Minimum lengths of service data (UUID only)
+var serviceDataUUIDSizes = map[uint8]int{
+	0x16: 2,
+	0x20: 4,
+	0x21: 16,
+}
+
+// Lint validates an advertisement's raw data against the Core Specification
+// Supplement. It returns nil when the advertisement has no raw data.
+func (a *Advertisement) Lint() []LintFinding {
+	if len(a.RawData) == 0 {
+		return nil
+	}
+
+	var findings []LintFinding
+	add := func(severity LintSeverity, code string, offset int, format string, args ...interface{}) {
+		findings = append(findings, LintFinding{
+			Severity: severity,
+			Code:     code,
+			Offset:   offset,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	raw := a.RawData
+	legacy := a.Extended == nil
+
+	// Payload size
+	if legacy && len(raw) > MaxLegacyAdvData {
+		add(LintError, "legacy-too-long", -1, "%d bytes exceeds the %d-byte legacy PDU limit", len(raw), MaxLegacyAdvData)
+	} else if len(raw) > MaxExtendedAdvData {
+		add(LintError, "ext-too-long", -1, "%d bytes exceeds the %d-byte extended advertising limit", len(raw), MaxExtendedAdvData)
+	}
+
+	structures, err := ParseADStructures(raw)
+	if err != nil {
+		add(LintError, "ad-overrun", -1, "%v", err)
+	}
+
+	// Zero padding: once a zero length octet is seen, everything after it must be zero
+	end := 0
+	if n := len(structures); n > 0 {
+		end = structures[n-1].Offset + 1 + len(structures[n-1].Data) + 1
+	}
+	if err == nil && end < len(raw) {
+		for i := end; i < len(raw); i++ {
+			if raw[i] != 0 {
+				add(LintError, "bad-padding", i, "non-zero byte 0x%02x after zero-length terminator; data following it is ignored", raw[i])
+				break
+			}
+		}
+		if !legacy {
+			add(LintWarning, "ext-padding", end, "zero padding in extended advertising data wastes airtime")
+		}
+	}
+
+	seen := make(map[uint8]int)
+	for _, ad := range structures {
+		seen[ad.Type]++
+		if seen[ad.Type] == 2 {
+			if name, ok := singleInstanceADTypes[ad.Type]; ok {
+				add(LintError, "duplicate-ad", ad.Offset, "%s (0x%02X) appears more than once", name, ad.Type)
+			}
+		}
+
+		if want, ok := fixedADLengths[ad.Type]; ok && len(ad.Data) != want {
+			add(LintError, "bad-length", ad.Offset, "AD type 0x%02X has %d data bytes, expected %d", ad.Type, len(ad.Data), want)
+		}
+		if size, ok := uuidListSizes[ad.Type]; ok && len(ad.Data)%size != 0 {
+			add(LintError, "bad-uuid-length", ad.Offset, "AD type 0x%02X has %d bytes, not a multiple of %d-byte UUIDs", ad.Type, len(ad.Data), size)
+		}
+		if size, ok := serviceDataUUIDSizes[ad.Type]; ok && len(ad.Data) < size {
+			add(LintError, "bad-uuid-length", ad.Offset, "Service Data (0x%02X) is shorter than its %d-byte UUID", ad.Type, size)
+		}
+
+		switch ad.Type {
+		case 0x01:
+			if a.ScanResponse {
+				add(LintError, "flags-in-scan-rsp", ad.Offset, "Flags must not appear in scan response data")
+			}
+			if len(ad.Data) != 1 {
+				add(LintWarning, "bad-length", ad.Offset, "Flags should be 1 byte, got %d", len(ad.Data))
+			}
+			if len(ad.Data) >= 1 && ad.Data[0]&0x03 == 0x03 {
+				add(LintError, "flags-both-discoverable", ad.Offset, "LE Limited and LE General Discoverable are both set")
+			}
+		case 0x08, 0x09:
+			if !utf8.Valid(ad.Data) {
+				add(LintWarning, "bad-name", ad.Offset, "local name is not valid UTF-8")
+			}
+		case ADTypeEncryptedData:
+			if len(ad.Data) < eadRandomizerSize+eadMICSize {
+				add(LintError, "bad-length", ad.Offset, "Encrypted Data has %d bytes, less than the Randomizer and MIC", len(ad.Data))
+			}
+		case 0xFF:
+			if len(ad.Data) < 2 {
+				add(LintError, "bad-length", ad.Offset, "Manufacturer Specific Data has no company identifier")
+			}
+		}
+	}
+
+	// Flags are mandatory in connectable/discoverable advertising data
+	if a.Connectable && !a.ScanResponse && seen[0x01] == 0 {
+		add(LintWarning, "flags-missing", -1, "connectable advertisement has no Flags AD type")
+	}
+	if seen[0x08] > 0 && seen[0x09] > 0 {
+		add(LintWarning, "both-names", -1, "both Shortened and Complete Local Name are present")
+	}
+
+	return findings
+}
+
+// CountLintFindings returns the number of errors and warnings
+func CountLintFindings(findings []LintFinding) (errors, warnings int) {
+	for _, f := range findings {
+		if f.Severity == LintError {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+	return errors, warnings
+}
@@ -0,0 +1,155 @@
+package ble
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name        string
+		raw         string
+		connectable bool
+		scanRsp     bool
+		extended    bool
+		want        []string // Finding codes, in order
+	}{
+		{
+			name:        "compliant advertisement",
+			raw:         "020106" + "0509" + "54657374" + "03020f18",
+			connectable: true,
+		},
+		{
+			name: "no raw data",
+			raw:  "",
+		},
+		{
+			name: "oversize legacy PDU",
+			raw:  "1fff4c00" + strings.Repeat("00", 28),
+			want: []string{"legacy-too-long"},
+		},
+		{
+			name:     "oversize data is fine when extended",
+			raw:      "1fff4c00" + strings.Repeat("00", 28),
+			extended: true,
+		},
+		{
+			name: "structure runs past the end",
+			raw:  "0509" + "5465",
+			want: []string{"ad-overrun"},
+		},
+		{
+			name: "zero padding",
+			raw:  "020106" + "0000",
+		},
+		{
+			name: "data after the terminator",
+			raw:  "020106" + "00" + "05",
+			want: []string{"bad-padding"},
+		},
+		{
+			name:     "zero padding in extended data",
+			raw:      "020106" + "0000",
+			extended: true,
+			want:     []string{"ext-padding"},
+		},
+		{
+			name: "duplicate AD type",
+			raw:  "0509" + "54657374" + "0509" + "54657374",
+			want: []string{"duplicate-ad"},
+		},
+		{
+			name: "repeated manufacturer data is allowed",
+			raw:  "03ff4c00" + "03ff4c00",
+		},
+		{
+			name: "wrong fixed length",
+			raw:  "0419000000" + "030a0000",
+			want: []string{"bad-length", "bad-length"},
+		},
+		{
+			name: "partial UUID",
+			raw:  "0403" + "0f1800",
+			want: []string{"bad-uuid-length"},
+		},
+		{
+			name: "service data shorter than its UUID",
+			raw:  "0216" + "0f",
+			want: []string{"bad-uuid-length"},
+		},
+		{
+			name:    "flags in a scan response",
+			raw:     "020106",
+			scanRsp: true,
+			want:    []string{"flags-in-scan-rsp"},
+		},
+		{
+			name: "both discoverable modes",
+			raw:  "020107",
+			want: []string{"flags-both-discoverable"},
+		},
+		{
+			name: "two-byte flags",
+			raw:  "03010600",
+			want: []string{"bad-length"},
+		},
+		{
+			name: "invalid UTF-8 name",
+			raw:  "0309" + "c328",
+			want: []string{"bad-name"},
+		},
+		{
+			name: "encrypted data too short",
+			raw:  "0531" + "00000000",
+			want: []string{"bad-length"},
+		},
+		{
+			name: "manufacturer data without a company",
+			raw:  "02ff4c",
+			want: []string{"bad-length"},
+		},
+		{
+			name:        "connectable without flags",
+			raw:         "0509" + "54657374",
+			connectable: true,
+			want:        []string{"flags-missing"},
+		},
+		{
+			name: "both names",
+			raw:  "0308" + "5465" + "0509" + "54657374",
+			want: []string{"both-names"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adv := NewAdvertisement()
+			adv.RawData = mustHex(t, tt.raw)
+			adv.Connectable = tt.connectable
+			adv.ScanResponse = tt.scanRsp
+			if tt.extended {
+				adv.Extended = &ExtendedAdvInfo{}
+			}
+			var got []string
+			for _, f := range adv.Lint() {
+				got = append(got, f.Code)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("findings = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountLintFindings(t *testing.T) {
+	findings := []LintFinding{
+		{Severity: LintError, Code: "ad-overrun", Offset: -1},
+		{Severity: LintWarning, Code: "flags-missing", Offset: -1},
+		{Severity: LintError, Code: "bad-length", Offset: 3},
+	}
+	if errors, warnings := CountLintFindings(findings); errors != 2 || warnings != 1 {
+		t.Errorf("errors = %d, warnings = %d, want 2 and 1", errors, warnings)
+	}
+	if got, want := findings[2].String(), "error bad-length @3: "; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
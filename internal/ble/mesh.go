@@ -0,0 +1,304 @@
+package ble
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Bluetooth Mesh advertising bearer and GATT proxy advertisements (Mesh
+// Protocol 1.1). Mesh multi-octet fields are big-endian.
+
+const (
+	meshProvisioningServiceUUID = 0x1827
+	meshProxyServiceUUID        = 0x1828
+
+	ADTypePBADV       = 0x29
+	ADTypeMeshMessage = 0x2A
+	ADTypeMeshBeacon  = 0x2B
+)
+
+// Mesh beacon types
+const (
+	meshBeaconUnprovisioned = 0x00
+	meshBeaconSecureNetwork = 0x01
+	meshBeaconPrivate       = 0x02
+)
+
+// Mesh proxy identification types
+const (
+	meshProxyNetworkID       = 0x00
+	meshProxyNodeIdentity    = 0x01
+	meshProxyPrivateNetwork  = 0x02
+	meshProxyPrivateIdentity = 0x03
+)
+
+// MeshState is the provisioning state inferred from a node's advertisements
+type MeshState int
+
+const (
+	MeshStateNone          MeshState = iota
+	MeshStateUnprovisioned           // Unprovisioned beacon or Provisioning Service
+	MeshStateProvisioning            // PB-ADV link open
+	MeshStateFailed                  // Link closed with an error, or still unprovisioned after a link
+	MeshStateProvisioned             // Secure/private beacon or Proxy Service
+)
+
+// String returns a short state name used in the Mesh column and filters
+func (s MeshState) String() string {
+	switch s {
+	case MeshStateUnprovisioned:
+		return "unprovisioned"
+	case MeshStateProvisioning:
+		return "provisioning"
+	case MeshStateFailed:
+		return "prov-failed"
+	case MeshStateProvisioned:
+		return "provisioned"
+	default:
+		return "-"
+	}
+}
+
+// MeshInfo is the Bluetooth Mesh state accumulated from a node's
+// advertisements
+type MeshInfo struct {
+	State        MeshState
+	DeviceUUID   string // Unprovisioned device UUID
+	OOBInfo      *uint16
+	URIHash      string
+	NetworkID    string // From proxy advertisements or secure network beacons
+	NodeIdentity bool   // Proxy advertising with Node Identity (hash + random)
+	Private      bool   // Private beacons or private proxy identification
+	KeyRefresh   bool
+	IVUpdate     bool
+	IVIndex      *uint32
+	NID          *uint8 // From Network PDUs on the advertising bearer
+	LinkID       *uint32
+	LinkClose    string // Reason of the last PB-ADV Link Close
+}
+
+// FormatOOB lists the OOB information sources
+func (m *MeshInfo) FormatOOB() string {
+	if m.OOBInfo == nil {
+		return "-"
+	}
+	names := []string{
+		"Other", "URI", "2D code", "Bar code", "NFC", "Number", "String",
+		"Certificate", "Records", "", "", "On box", "Inside box",
+		"On paper", "Inside manual", "On device",
+	}
+	var parts []string
+	for bit, name := range names {
+		if name != "" && *m.OOBInfo&(1<<bit) != 0 {
+			parts = append(parts, name)
+		}
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// FormatIVIndex formats the IV index with its update and key refresh flags
+func (m *MeshInfo) FormatIVIndex() string {
+	if m.IVIndex == nil {
+		return "-"
+	}
+	s := fmt.Sprintf("%d", *m.IVIndex)
+	if m.IVUpdate {
+		s += " (IV update)"
+	}
+	if m.KeyRefresh {
+		s += " (key refresh)"
+	}
+	return s
+}
+
+// Fields returns the known fields for the detail view
+func (m *MeshInfo) Fields() []ADType {
+	fields := []ADType{{Name: "State", Value: m.State.String()}}
+	add := func(name, value string) {
+		if value != "" && value != "-" {
+			fields = append(fields, ADType{Name: name, Value: value})
+		}
+	}
+	add("Device UUID", m.DeviceUUID)
+	add("OOB Info", m.FormatOOB())
+	add("URI Hash", m.URIHash)
+	add("Network ID", m.NetworkID)
+	if m.NodeIdentity {
+		add("Proxy", "node identity")
+	}
+	if m.Private {
+		add("Privacy", "private beacons/identity")
+	}
+	add("IV Index", m.FormatIVIndex())
+	if m.NID != nil {
+		add("NID", fmt.Sprintf("0x%02X", *m.NID))
+	}
+	if m.LinkID != nil {
+		add("PB-ADV Link", fmt.Sprintf("0x%08X", *m.LinkID))
+	}
+	add("Link Close", m.LinkClose)
+	return fields
+}
+
+// merge folds newer information into m. A node that goes back to
+// unprovisioned beacons after a provisioning attempt is marked failed.
+func (m *MeshInfo) merge(n *MeshInfo) {
+	switch {
+	case n.State == MeshStateNone:
+	case n.State == MeshStateUnprovisioned && (m.State == MeshStateProvisioning || m.State == MeshStateFailed):
+		m.State = MeshStateFailed
+	default:
+		m.State = n.State
+	}
+	if n.DeviceUUID != "" {
+		m.DeviceUUID = n.DeviceUUID
+	}
+	if n.OOBInfo != nil {
+		m.OOBInfo = n.OOBInfo
+	}
+	if n.URIHash != "" {
+		m.URIHash = n.URIHash
+	}
+	if n.NetworkID != "" {
+		m.NetworkID = n.NetworkID
+	}
+	m.NodeIdentity = m.NodeIdentity || n.NodeIdentity
+	m.Private = m.Private || n.Private
+	if n.IVIndex != nil {
+		m.IVIndex = n.IVIndex
+		m.KeyRefresh = n.KeyRefresh
+		m.IVUpdate = n.IVUpdate
+	}
+	if n.NID != nil {
+		m.NID = n.NID
+	}
+	if n.LinkID != nil {
+		m.LinkID = n.LinkID
+	}
+	if n.LinkClose != "" {
+		m.LinkClose = n.LinkClose
+	}
+}
+
+// MeshInfo decodes the Bluetooth Mesh content of the advertisement, if any
+func (a *Advertisement) MeshInfo() (*MeshInfo, bool) {
+	m := &MeshInfo{}
+	found := false
+
+	// Mesh Provisioning Service: Device UUID, OOB Information
+	if data, ok := a.serviceData16(meshProvisioningServiceUUID); ok && len(data) >= 18 {
+		found = true
+		m.State = MeshStateUnprovisioned
+		m.DeviceUUID = uuid128BigEndian(data[:16])
+		oob := binary.BigEndian.Uint16(data[16:])
+		m.OOBInfo = &oob
+	}
+
+	// Mesh Proxy Service: identification type and value
+	if data, ok := a.serviceData16(meshProxyServiceUUID); ok && len(data) >= 9 {
+		found = true
+		m.State = MeshStateProvisioned
+		switch data[0] {
+		case meshProxyNetworkID:
+			m.NetworkID = fmt.Sprintf("%x", data[1:9])
+		case meshProxyNodeIdentity:
+			m.NodeIdentity = true
+		case meshProxyPrivateNetwork, meshProxyPrivateIdentity:
+			m.Private = true
+		}
+	}
+
+	if data, ok := a.FindAD(ADTypeMeshBeacon); ok && len(data) >= 1 {
+		found = true
+		switch data[0] {
+		case meshBeaconUnprovisioned:
+			m.State = MeshStateUnprovisioned
+			if len(data) >= 19 {
+				m.DeviceUUID = uuid128BigEndian(data[1:17])
+				oob := binary.BigEndian.Uint16(data[17:])
+				m.OOBInfo = &oob
+			}
+			if len(data) >= 23 {
+				m.URIHash = fmt.Sprintf("%x", data[19:23])
+			}
+		case meshBeaconSecureNetwork:
+			m.State = MeshStateProvisioned
+			if len(data) >= 14 {
+				m.KeyRefresh = data[1]&0x01 != 0
+				m.IVUpdate = data[1]&0x02 != 0
+				m.NetworkID = fmt.Sprintf("%x", data[2:10])
+				iv := binary.BigEndian.Uint32(data[10:])
+				m.IVIndex = &iv
+			}
+		case meshBeaconPrivate:
+			m.State = MeshStateProvisioned
+			m.Private = true
+		}
+	}
+
+	// PB-ADV: Link ID, transaction number, Generic Provisioning PDU
+	if data, ok := a.FindAD(ADTypePBADV); ok && len(data) >= 6 {
+		found = true
+		linkID := binary.BigEndian.Uint32(data)
+		m.LinkID = &linkID
+		if pdu := data[5:]; pdu[0]&0x03 == 0x03 {
+			// Provisioning Bearer Control
+			switch pdu[0] >> 2 {
+			case 0x00, 0x01: // Link Open, Link ACK
+				m.State = MeshStateProvisioning
+			case 0x02: // Link Close
+				reason := byte(0xFF)
+				if len(pdu) >= 2 {
+					reason = pdu[1]
+				}
+				switch reason {
+				case 0x00:
+					m.LinkClose = "success"
+					m.State = MeshStateProvisioned
+				case 0x01:
+					m.LinkClose = "timeout"
+					m.State = MeshStateFailed
+				default:
+					m.LinkClose = "fail"
+					m.State = MeshStateFailed
+				}
+			}
+		} else {
+			m.State = MeshStateProvisioning
+		}
+	}
+
+	// Network PDU: IVI and NID are in the clear
+	if data, ok := a.FindAD(ADTypeMeshMessage); ok && len(data) >= 1 {
+		found = true
+		nid := data[0] & 0x7F
+		m.NID = &nid
+	}
+
+	if !found {
+		return nil, false
+	}
+	return m, true
+}
+
+// uuid128BigEndian formats 16 big-endian bytes as a UUID string
+func uuid128BigEndian(b []byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// decodeMesh reports Mesh nodes in the Decoded column
+func decodeMesh(adv *Advertisement) (DecodedPayload, bool) {
+	m, ok := adv.MeshInfo()
+	if !ok {
+		return DecodedPayload{}, false
+	}
+	return DecodedPayload{
+		Protocol: "Mesh",
+		Intent:   m.State.String(),
+		Fields:   m.Fields()[1:],
+	}, true
+}
@@ -0,0 +1,190 @@
+package ble
+
+import "testing"
+
+const meshTestUUID = "00112233445566778899aabbccddeeff"
+
+func TestMeshInfo(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		ok         bool
+		state      MeshState
+		deviceUUID string
+		oob        string
+		uriHash    string
+		networkID  string
+		ivIndex    string
+		private    bool
+		identity   bool
+		linkClose  string
+	}{
+		{
+			name:       "unprovisioned beacon with URI hash",
+			raw:        "182b" + "00" + meshTestUUID + "0006" + "deadbeef",
+			ok:         true,
+			state:      MeshStateUnprovisioned,
+			deviceUUID: "00112233-4455-6677-8899-aabbccddeeff",
+			oob:        "URI, 2D code",
+			uriHash:    "deadbeef",
+			ivIndex:    "-",
+		},
+		{
+			name:      "secure network beacon",
+			raw:       "0f2b" + "01" + "03" + "0102030405060708" + "00000005",
+			ok:        true,
+			state:     MeshStateProvisioned,
+			oob:       "-",
+			networkID: "0102030405060708",
+			ivIndex:   "5 (IV update) (key refresh)",
+		},
+		{
+			name:    "private beacon",
+			raw:     "032b" + "02" + "00",
+			ok:      true,
+			state:   MeshStateProvisioned,
+			oob:     "-",
+			ivIndex: "-",
+			private: true,
+		},
+		{
+			name:       "provisioning service",
+			raw:        "1516" + "2718" + meshTestUUID + "0000",
+			ok:         true,
+			state:      MeshStateUnprovisioned,
+			deviceUUID: "00112233-4455-6677-8899-aabbccddeeff",
+			oob:        "none",
+			ivIndex:    "-",
+		},
+		{
+			name:      "proxy with network ID",
+			raw:       "0c16" + "2818" + "00" + "0102030405060708",
+			ok:        true,
+			state:     MeshStateProvisioned,
+			oob:       "-",
+			networkID: "0102030405060708",
+			ivIndex:   "-",
+		},
+		{
+			name:     "proxy with node identity",
+			raw:      "0c16" + "2818" + "01" + "0102030405060708",
+			ok:       true,
+			state:    MeshStateProvisioned,
+			oob:      "-",
+			ivIndex:  "-",
+			identity: true,
+		},
+		{
+			name:    "PB-ADV link open",
+			raw:     "0829" + "01020304" + "00" + "03" + "aa",
+			ok:      true,
+			state:   MeshStateProvisioning,
+			oob:     "-",
+			ivIndex: "-",
+		},
+		{
+			name:      "PB-ADV link closed on success",
+			raw:       "0829" + "01020304" + "00" + "0b" + "00",
+			ok:        true,
+			state:     MeshStateProvisioned,
+			oob:       "-",
+			ivIndex:   "-",
+			linkClose: "success",
+		},
+		{
+			name:      "PB-ADV link closed on timeout",
+			raw:       "0829" + "01020304" + "00" + "0b" + "01",
+			ok:        true,
+			state:     MeshStateFailed,
+			oob:       "-",
+			ivIndex:   "-",
+			linkClose: "timeout",
+		},
+		{
+			name:    "PB-ADV transaction",
+			raw:     "0829" + "01020304" + "00" + "00" + "aa",
+			ok:      true,
+			state:   MeshStateProvisioning,
+			oob:     "-",
+			ivIndex: "-",
+		},
+		{
+			name:    "network PDU",
+			raw:     "042a" + "85" + "0000",
+			ok:      true,
+			state:   MeshStateNone,
+			oob:     "-",
+			ivIndex: "-",
+		},
+		{
+			name: "short provisioning service data",
+			raw:  "0516" + "2718" + "0011",
+		},
+		{
+			name: "no mesh content",
+			raw:  "020106",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adv := decodedAdvert(t, tt.raw)
+			m, ok := adv.MeshInfo()
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if m.State != tt.state {
+				t.Errorf("state = %s, want %s", m.State, tt.state)
+			}
+			if m.DeviceUUID != tt.deviceUUID || m.FormatOOB() != tt.oob || m.URIHash != tt.uriHash {
+				t.Errorf("device UUID = %q, OOB = %q, URI hash = %q", m.DeviceUUID, m.FormatOOB(), m.URIHash)
+			}
+			if m.NetworkID != tt.networkID || m.FormatIVIndex() != tt.ivIndex {
+				t.Errorf("network ID = %q, IV index = %q", m.NetworkID, m.FormatIVIndex())
+			}
+			if m.Private != tt.private || m.NodeIdentity != tt.identity || m.LinkClose != tt.linkClose {
+				t.Errorf("private = %v, node identity = %v, link close = %q", m.Private, m.NodeIdentity, m.LinkClose)
+			}
+		})
+	}
+}
+
+func TestMeshInfoMerge(t *testing.T) {
+	const (
+		unprovisioned = "182b" + "00" + meshTestUUID + "0006" + "deadbeef"
+		linkOpen      = "0829" + "01020304" + "00" + "03" + "aa"
+		linkSuccess   = "0829" + "01020304" + "00" + "0b" + "00"
+		networkPDU    = "042a" + "85" + "0000"
+	)
+	tests := []struct {
+		name     string
+		sequence []string
+		state    MeshState
+	}{
+		{name: "provisioned", sequence: []string{unprovisioned, linkOpen, linkSuccess}, state: MeshStateProvisioned},
+		{name: "back to unprovisioned after a link", sequence: []string{unprovisioned, linkOpen, unprovisioned}, state: MeshStateFailed},
+		{name: "stays failed", sequence: []string{linkOpen, unprovisioned, unprovisioned}, state: MeshStateFailed},
+		{name: "network PDU keeps the state", sequence: []string{unprovisioned, networkPDU}, state: MeshStateUnprovisioned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := &MeshInfo{}
+			for _, raw := range tt.sequence {
+				adv := decodedAdvert(t, raw)
+				m, ok := adv.MeshInfo()
+				if !ok {
+					t.Fatalf("%s: no mesh content", raw)
+				}
+				merged.merge(m)
+			}
+			if merged.State != tt.state {
+				t.Errorf("state = %s, want %s", merged.State, tt.state)
+			}
+			if merged.DeviceUUID == "" && tt.sequence[0] == unprovisioned {
+				t.Error("device UUID lost in the merge")
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package ble
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Pairing-popup advertisements: Google Fast Pair, Microsoft Swift Pair and
+// Samsung EasySetup
+
+const (
+	fastPairServiceUUID = 0xFE2C
+	companyMicrosoft    = 0x0006
+	companySamsung      = 0x0075
+
+	swiftPairBeaconID = 0x03
+)
+
+// Fast Pair account key data field types (length in the high nibble)
+const (
+	fastPairFieldFilterShowUI  = 0x0
+	fastPairFieldSalt          = 0x1
+	fastPairFieldFilterHideUI  = 0x2
+	fastPairFieldBatteryShowUI = 0x3
+	fastPairFieldBatteryHideUI = 0x4
+)
+
+// decodeFastPair decodes Google Fast Pair service data. Discoverable
+// providers advertise a 3-byte model ID; after pairing they advertise an
+// account key filter that lets previously paired phones recognize them.
+func decodeFastPair(adv *Advertisement) (DecodedPayload, bool) {
+	data, ok := adv.serviceData16(fastPairServiceUUID)
+	if !ok || len(data) == 0 {
+		return DecodedPayload{}, false
+	}
+
+	p := DecodedPayload{Protocol: "Fast Pair"}
+	if len(data) == 3 {
+		p.Model = fmt.Sprintf("0x%02X%02X%02X", data[0], data[1], data[2])
+		p.Intent = "pairing"
+		p.Fields = []ADType{{Name: "Model ID", Value: p.Model}}
+		return p, true
+	}
+
+	// Account key data: version/flags octet, then length/type fields
+	p.Intent = "reconnect"
+	p.Fields = append(p.Fields, ADType{Name: "Version", Value: fmt.Sprintf("%d", data[0]>>4)})
+	for i := 1; i < len(data); {
+		length, fieldType := int(data[i]>>4), data[i]&0x0F
+		i++
+		if i+length > len(data) {
+			p.Fields = append(p.Fields, ADType{Name: "Error", Value: "truncated field"})
+			break
+		}
+		value := data[i : i+length]
+		i += length
+
+		switch fieldType {
+		case fastPairFieldFilterShowUI, fastPairFieldFilterHideUI:
+			if length == 0 {
+				p.Intent = "reconnect (no account keys)"
+			} else if fieldType == fastPairFieldFilterHideUI {
+				p.Intent = "reconnect (hidden)"
+			} else {
+				p.Intent = "reconnect (show UI)"
+			}
+			p.Fields = append(p.Fields, ADType{Name: "Account Key Filter", Value: fmt.Sprintf("%x", value)})
+		case fastPairFieldSalt:
+			p.Fields = append(p.Fields, ADType{Name: "Salt", Value: fmt.Sprintf("%x", value)})
+		case fastPairFieldBatteryShowUI, fastPairFieldBatteryHideUI:
+			p.Fields = append(p.Fields, ADType{Name: "Battery", Value: formatFastPairBattery(value)})
+		default:
+			p.Fields = append(p.Fields, ADType{Name: fmt.Sprintf("Field 0x%X", fieldType), Value: fmt.Sprintf("%x", value)})
+		}
+	}
+	return p, true
+}
+
+// formatFastPairBattery formats left bud, right bud and case battery levels.
+// Bit 7 is the charging flag and 0x7F means unknown.
+func formatFastPairBattery(value []byte) string {
+	labels := []string{"L", "R", "Case"}
+	var parts []string
+	for i, b := range value {
+		if i >= len(labels) {
+			break
+		}
+		level := b & 0x7F
+		if level == 0x7F {
+			parts = append(parts, labels[i]+" ?")
+			continue
+		}
+		s := fmt.Sprintf("%s %d%%", labels[i], level)
+		if b&0x80 != 0 {
+			s += "+"
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// decodeSwiftPair decodes Microsoft Swift Pair beacons: beacon ID 0x03, a
+// sub-scenario, the reserved RSSI octet, then scenario-specific fields and
+// the display name
+func decodeSwiftPair(adv *Advertisement) (DecodedPayload, bool) {
+	data, ok := adv.manufacturerPayload(companyMicrosoft)
+	if !ok || len(data) < 3 || data[0] != swiftPairBeaconID {
+		return DecodedPayload{}, false
+	}
+
+	p := DecodedPayload{Protocol: "Swift Pair"}
+	rest := data[3:]
+	switch data[1] {
+	case 0x00:
+		p.Intent = "pairing (LE)"
+	case 0x01:
+		p.Intent = "pairing (BR/EDR via LE)"
+		if len(rest) < 9 {
+			return DecodedPayload{}, false
+		}
+		addr := rest[:6]
+		p.Fields = append(p.Fields, ADType{Name: "BR/EDR Address", Value: fmt.Sprintf("%02X:%02X:%02X:%02X:%02X:%02X",
+			addr[5], addr[4], addr[3], addr[2], addr[1], addr[0])})
+		p.Model = classOfDeviceMajor(rest[6:9])
+		rest = rest[9:]
+	case 0x02:
+		p.Intent = "pairing (LE and BR/EDR)"
+		if len(rest) < 3 {
+			return DecodedPayload{}, false
+		}
+		p.Model = classOfDeviceMajor(rest[:3])
+		rest = rest[3:]
+	default:
+		p.Intent = fmt.Sprintf("scenario 0x%02X", data[1])
+	}
+
+	if len(rest) > 0 && utf8.Valid(rest) {
+		p.Name = string(rest)
+	}
+	if p.Model != "" {
+		p.Fields = append(p.Fields, ADType{Name: "Device Class", Value: p.Model})
+	}
+	return p, true
+}
+
+// classOfDeviceMajor returns the major device class of a little-endian
+// 3-octet Class of Device
+func classOfDeviceMajor(cod []byte) string {
+	major := cod[1] & 0x1F
+	classes := map[byte]string{
+		0x00: "Miscellaneous",
+		0x01: "Computer",
+		0x02: "Phone",
+		0x03: "Network Access Point",
+		0x04: "Audio/Video",
+		0x05: "Peripheral",
+		0x06: "Imaging",
+		0x07: "Wearable",
+		0x08: "Toy",
+		0x09: "Health",
+		0x1F: "Uncategorized",
+	}
+	if name, ok := classes[major]; ok {
+		return name
+	}
+	return fmt.Sprintf("Class 0x%02X", major)
+}
+
+// decodeEasySetup decodes Samsung EasySetup popups for Galaxy Buds and
+// Galaxy Watch. The format is undocumented; the layouts here are the ones
+// Galaxy devices are observed to send.
+func decodeEasySetup(adv *Advertisement) (DecodedPayload, bool) {
+	data, ok := adv.manufacturerPayload(companySamsung)
+	if !ok {
+		return DecodedPayload{}, false
+	}
+
+	switch {
+	case len(data) >= 13 && data[0] == 0x42 && data[1] == 0x09:
+		model := fmt.Sprintf("0x%02X%02X%02X", data[10], data[11], data[12])
+		return DecodedPayload{
+			Protocol: "EasySetup",
+			Intent:   "pairing (Buds)",
+			Model:    model,
+			Fields:   []ADType{{Name: "Model ID", Value: model}},
+		}, true
+	case len(data) >= 11 && data[0] == 0x01 && data[1] == 0x00 && data[2] == 0x02:
+		model := fmt.Sprintf("0x%02X", data[10])
+		return DecodedPayload{
+			Protocol: "EasySetup",
+			Intent:   "pairing (Watch)",
+			Model:    model,
+			Fields:   []ADType{{Name: "Model ID", Value: model}},
+		}, true
+	}
+	return DecodedPayload{}, false
+}
@@ -0,0 +1,123 @@
+package ble
+
+import "testing"
+
+// decodedAdvert decodes raw advertising data the way a scan would
+func decodedAdvert(t *testing.T, raw string) Advertisement {
+	t.Helper()
+	adv := NewAdvertisement()
+	adv.RawData = mustHex(t, raw)
+	adv.DecodeRawData()
+	return adv
+}
+
+// fieldValue returns the value of the named decoded field, or ""
+func fieldValue(p DecodedPayload, name string) string {
+	for _, f := range p.Fields {
+		if f.Name == name {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+func TestDecodePairingPayloads(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		summary string // "" when nothing is recognized
+		field   string // Decoded field to check, if any
+		value   string
+	}{
+		{
+			name:    "Fast Pair model ID",
+			raw:     "0616" + "2cfe" + "0a0b0c",
+			summary: "Fast Pair · pairing · 0x0A0B0C",
+			field:   "Model ID", value: "0x0A0B0C",
+		},
+		{
+			name:    "Fast Pair account key filter with battery",
+			raw:     "0e16" + "2cfe" + "00" + "30aabbcc" + "1155" + "33d4507f",
+			summary: "Fast Pair · reconnect (show UI)",
+			field:   "Battery", value: "L 84%+, R 80%, Case ?",
+		},
+		{
+			name:    "Fast Pair hidden filter",
+			raw:     "0816" + "2cfe" + "00" + "32aabbcc",
+			summary: "Fast Pair · reconnect (hidden)",
+			field:   "Account Key Filter", value: "aabbcc",
+		},
+		{
+			name:    "Fast Pair without account keys",
+			raw:     "0516" + "2cfe" + "0000",
+			summary: "Fast Pair · reconnect (no account keys)",
+		},
+		{
+			name:    "Fast Pair truncated field",
+			raw:     "0716" + "2cfe" + "00350102",
+			summary: "Fast Pair · reconnect",
+			field:   "Error", value: "truncated field",
+		},
+		{
+			name:    "Swift Pair LE",
+			raw:     "0bff" + "0600" + "030080" + "4d6f757365",
+			summary: "Swift Pair · pairing (LE) · Mouse",
+		},
+		{
+			name:    "Swift Pair BR/EDR",
+			raw:     "11ff" + "0600" + "030180" + "665544332211" + "000500" + "4b42",
+			summary: "Swift Pair · pairing (BR/EDR via LE) · KB",
+			field:   "BR/EDR Address", value: "11:22:33:44:55:66",
+		},
+		{
+			name:    "Swift Pair LE and BR/EDR without a name",
+			raw:     "09ff" + "0600" + "030280" + "000400",
+			summary: "Swift Pair · pairing (LE and BR/EDR) · Audio/Video",
+		},
+		{
+			name: "Swift Pair BR/EDR truncated",
+			raw:  "09ff" + "0600" + "030180" + "665544",
+		},
+		{
+			name: "other Microsoft beacon",
+			raw:  "06ff" + "0600" + "010980",
+		},
+		{
+			name:    "EasySetup Buds",
+			raw:     "10ff" + "7500" + "4209" + "0102030405060708" + "a1b2c3",
+			summary: "EasySetup · pairing (Buds) · 0xA1B2C3",
+		},
+		{
+			name:    "EasySetup Watch",
+			raw:     "0eff" + "7500" + "010002" + "01020304050607" + "1a",
+			summary: "EasySetup · pairing (Watch) · 0x1A",
+		},
+		{
+			name: "other Samsung data",
+			raw:  "06ff" + "7500" + "420901",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adv := decodedAdvert(t, tt.raw)
+			decoded := adv.DecodePayloads()
+			if tt.summary == "" {
+				if len(decoded) != 0 {
+					t.Fatalf("decoded %q, want nothing", decoded[0].Summary())
+				}
+				return
+			}
+			if len(decoded) != 1 {
+				t.Fatalf("decoded %d payloads, want 1", len(decoded))
+			}
+			if got := decoded[0].Summary(); got != tt.summary {
+				t.Errorf("summary = %q, want %q", got, tt.summary)
+			}
+			if tt.field != "" {
+				if got := fieldValue(decoded[0], tt.field); got != tt.value {
+					t.Errorf("%s = %q, want %q (fields %v)", tt.field, got, tt.value, decoded[0].Fields)
+				}
+			}
+		})
+	}
+}
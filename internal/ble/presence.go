@@ -0,0 +1,252 @@
+package ble
+
+import (
+	"sort"
+	"time"
+)
+
+// Presence timeline. Each device's visits, stretches of time it kept
+// advertising without going stale, are recorded as they happen and kept after
+// cleanup removes the device, so arrivals, departures and dwell time cover
+// the whole session.
+
+const (
+	// Visits kept per device; older ones still count toward the totals
+	maxVisits = 500
+	// Devices whose presence is kept, forgetting the longest gone first
+	maxPresenceDevices = 5000
+	// Arrivals and departures kept in the scanner-wide log
+	maxPresenceLog = 10000
+)
+
+// Presence event kinds
+const (
+	PresenceArrival   = "arrival"
+	PresenceDeparture = "departure"
+)
+
+// Visit is a stretch of time a device was present
+type Visit struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`            // Last advertisement of the visit
+	Open  bool      `json:"open,omitempty"` // The device has not departed yet
+}
+
+// Duration returns the time from the first to the last advertisement
+func (v Visit) Duration() time.Duration {
+	return v.End.Sub(v.Start)
+}
+
+// Presence is the visits of one device this session
+type Presence struct {
+	DeviceID string        `json:"device_id"`
+	Address  string        `json:"address"`
+	Name     string        `json:"name,omitempty"`
+	Visits   []Visit       `json:"visits"`      // Oldest first, up to maxVisits
+	Count    int           `json:"visit_count"` // Every visit, including those no longer kept
+	Dwell    time.Duration `json:"dwell_ns"`    // Total duration of every visit
+}
+
+// Present reports whether the device is present now
+func (p Presence) Present() bool {
+	n := len(p.Visits)
+	return n > 0 && p.Visits[n-1].Open
+}
+
+// FirstSeen returns the start of the oldest kept visit
+func (p Presence) FirstSeen() time.Time {
+	if len(p.Visits) == 0 {
+		return time.Time{}
+	}
+	return p.Visits[0].Start
+}
+
+// LastSeen returns the end of the newest visit
+func (p Presence) LastSeen() time.Time {
+	if len(p.Visits) == 0 {
+		return time.Time{}
+	}
+	return p.Visits[len(p.Visits)-1].End
+}
+
+// PresenceEvent is a device arriving or departing
+type PresenceEvent struct {
+	Time     time.Time     `json:"time"`
+	Kind     string        `json:"kind"`
+	DeviceID string        `json:"device_id"`
+	Address  string        `json:"address"`
+	Name     string        `json:"name,omitempty"`
+	Visit    time.Duration `json:"visit_ns,omitempty"` // Length of the visit that ended, on departure
+}
+
+// recordPresence extends the device's current visit with an advertisement,
+// starting a new visit if it had departed or been silent past the stale
+// timeout. Called with s.mu held.
+func (s *Scanner) recordPresence(device *Device, ts time.Time) {
+	id, address, name := device.presenceIdentity()
+	p, ok := s.presence[id]
+	if !ok {
+		s.evictPresence()
+		p = &Presence{DeviceID: id}
+		s.presence[id] = p
+	}
+	p.Address, p.Name = address, name
+
+	if n := len(p.Visits); n > 0 && p.Visits[n-1].Open {
+		v := &p.Visits[n-1]
+		if ts.Sub(v.End) <= DeviceTimeout {
+			if ts.After(v.End) {
+				p.Dwell += ts.Sub(v.End)
+				v.End = ts
+			}
+			return
+		}
+		// Silent past the timeout before cleanup noticed
+		s.departPresence(p)
+	}
+
+	p.Visits = append(p.Visits, Visit{Start: ts, End: ts, Open: true})
+	if len(p.Visits) > maxVisits {
+		p.Visits = p.Visits[len(p.Visits)-maxVisits:]
+	}
+	p.Count++
+	s.logPresence(PresenceEvent{Time: ts, Kind: PresenceArrival, DeviceID: id, Address: address, Name: name})
+}
+
+// departPresence closes a device's current visit, if any, at its last
+// advertisement. Called with s.mu held.
+func (s *Scanner) departPresence(p *Presence) {
+	n := len(p.Visits)
+	if n == 0 || !p.Visits[n-1].Open {
+		return
+	}
+	v := &p.Visits[n-1]
+	v.Open = false
+	s.logPresence(PresenceEvent{
+		Time:     v.End,
+		Kind:     PresenceDeparture,
+		DeviceID: p.DeviceID,
+		Address:  p.Address,
+		Name:     p.Name,
+		Visit:    v.Duration(),
+	})
+}
+
+func (s *Scanner) logPresence(e PresenceEvent) {
+	s.presenceLog = append(s.presenceLog, e)
+	if n := len(s.presenceLog); n > maxPresenceLog {
+		s.presenceLog = s.presenceLog[n-maxPresenceLog:]
+	}
+}
+
+// evictPresence makes room for a new device by forgetting the one that
+// departed longest ago. Called with s.mu held.
+func (s *Scanner) evictPresence() {
+	if len(s.presence) < maxPresenceDevices {
+		return
+	}
+	var oldest string
+	var oldestEnd time.Time
+	for id, p := range s.presence {
+		if p.Present() {
+			continue
+		}
+		if end := p.LastSeen(); oldest == "" || end.Before(oldestEnd) {
+			oldest, oldestEnd = id, end
+		}
+	}
+	if oldest != "" {
+		delete(s.presence, oldest)
+	}
+}
+
+// mergePresence moves fromID's visits into intoID, joining visits that
+// overlap or are closer than the stale timeout. Called with s.mu held.
+func (s *Scanner) mergePresence(fromID, intoID string) {
+	from, ok := s.presence[fromID]
+	if !ok {
+		return
+	}
+	delete(s.presence, fromID)
+	into, ok := s.presence[intoID]
+	if !ok {
+		from.DeviceID = intoID
+		s.presence[intoID] = from
+		return
+	}
+
+	// Dwell of visits no longer kept still counts
+	unkept := into.Dwell - sumDuration(into.Visits) + from.Dwell - sumDuration(from.Visits)
+	visits := append(append([]Visit(nil), into.Visits...), from.Visits...)
+	sort.SliceStable(visits, func(i, j int) bool { return visits[i].Start.Before(visits[j].Start) })
+	var merged []Visit
+	for _, v := range visits {
+		if n := len(merged); n > 0 && v.Start.Sub(merged[n-1].End) <= DeviceTimeout {
+			last := &merged[n-1]
+			if v.End.After(last.End) {
+				last.End = v.End
+			}
+			continue
+		}
+		merged = append(merged, v)
+	}
+	// Only the newest visit can still be going on
+	for i := range merged {
+		merged[i].Open = false
+	}
+	merged[len(merged)-1].Open = into.Present() || from.Present()
+	into.Count += from.Count - (len(visits) - len(merged))
+	into.Dwell = unkept + sumDuration(merged)
+	if len(merged) > maxVisits {
+		merged = merged[len(merged)-maxVisits:]
+	}
+	into.Visits = merged
+}
+
+func sumDuration(visits []Visit) time.Duration {
+	var d time.Duration
+	for _, v := range visits {
+		d += v.Duration()
+	}
+	return d
+}
+
+// presenceIdentity returns the ID, address and display name presence is
+// recorded under
+func (d *Device) presenceIdentity() (id, address, name string) {
+	name = d.GetDisplayName()
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if name == d.Address {
+		name = ""
+	}
+	return d.ID, d.Address, name
+}
+
+// GetPresence returns the visits of every device seen this session, including
+// those that have since been removed, ordered by first arrival
+func (s *Scanner) GetPresence() []Presence {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Presence, 0, len(s.presence))
+	for _, p := range s.presence {
+		c := *p
+		c.Visits = append([]Visit(nil), p.Visits...)
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].FirstSeen().Equal(out[j].FirstSeen()) {
+			return out[i].FirstSeen().Before(out[j].FirstSeen())
+		}
+		return out[i].DeviceID < out[j].DeviceID
+	})
+	return out
+}
+
+// GetPresenceLog returns the arrivals and departures of this session, in the
+// order they were detected
+func (s *Scanner) GetPresenceLog() []PresenceEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]PresenceEvent(nil), s.presenceLog...)
+}
@@ -0,0 +1,150 @@
+package ble
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordPresence(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	tests := []struct {
+		name   string
+		at     []time.Duration // Advertisements after start
+		depart bool            // Device removed after the last advertisement
+		visits int
+		dwell  time.Duration
+		events []string
+	}{
+		{
+			name:   "one visit",
+			at:     []time.Duration{0, 10 * time.Second, 20 * time.Second},
+			visits: 1,
+			dwell:  20 * time.Second,
+			events: []string{PresenceArrival},
+		},
+		{
+			name:   "silent past the timeout starts a new visit",
+			at:     []time.Duration{0, 10 * time.Second, 10*time.Second + DeviceTimeout + time.Second, 50 * time.Second},
+			visits: 2,
+			dwell:  10*time.Second + 50*time.Second - (10*time.Second + DeviceTimeout + time.Second),
+			events: []string{PresenceArrival, PresenceDeparture, PresenceArrival},
+		},
+		{
+			name:   "out of order advertisement doesn't shrink the visit",
+			at:     []time.Duration{0, 10 * time.Second, 5 * time.Second},
+			visits: 1,
+			dwell:  10 * time.Second,
+			events: []string{PresenceArrival},
+		},
+		{
+			name:   "removed device departs",
+			at:     []time.Duration{0, 10 * time.Second},
+			depart: true,
+			visits: 1,
+			dwell:  10 * time.Second,
+			events: []string{PresenceArrival, PresenceDeparture},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScanner()
+			d := NewDevice("C6:11:22:33:44:55")
+			for _, at := range tt.at {
+				s.recordPresence(d, start.Add(at))
+			}
+			if tt.depart {
+				s.departPresence(s.presence[d.ID])
+			}
+
+			presence := s.GetPresence()
+			if len(presence) != 1 {
+				t.Fatalf("presence of %d devices, want 1", len(presence))
+			}
+			p := presence[0]
+			if len(p.Visits) != tt.visits || p.Count != tt.visits || p.Dwell != tt.dwell {
+				t.Errorf("visits = %d, count = %d, dwell = %v, want %d visits of %v", len(p.Visits), p.Count, p.Dwell, tt.visits, tt.dwell)
+			}
+			if p.Present() == tt.depart {
+				t.Errorf("present = %v after departing = %v", p.Present(), tt.depart)
+			}
+			log := s.GetPresenceLog()
+			if len(log) != len(tt.events) {
+				t.Fatalf("events = %+v, want %v", log, tt.events)
+			}
+			for i, e := range log {
+				if e.Kind != tt.events[i] || e.DeviceID != d.ID {
+					t.Errorf("event %d = %s of %s, want %s", i, e.Kind, e.DeviceID, tt.events[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMergePresence(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	visit := func(from, to time.Duration, open bool) Visit {
+		return Visit{Start: start.Add(from), End: start.Add(to), Open: open}
+	}
+	tests := []struct {
+		name    string
+		from    []Visit
+		into    []Visit
+		visits  int
+		dwell   time.Duration
+		present bool
+	}{
+		{
+			name:   "separate visits interleave",
+			from:   []Visit{visit(0, 10*time.Second, false)},
+			into:   []Visit{visit(time.Minute, 2*time.Minute, false)},
+			visits: 2,
+			dwell:  70 * time.Second,
+		},
+		{
+			name:    "overlapping visits join",
+			from:    []Visit{visit(0, time.Minute, false)},
+			into:    []Visit{visit(30*time.Second, 2*time.Minute, true)},
+			visits:  1,
+			dwell:   2 * time.Minute,
+			present: true,
+		},
+		{
+			name:    "visits closer than the timeout join",
+			from:    []Visit{visit(time.Minute, 2*time.Minute, true)},
+			into:    []Visit{visit(0, time.Minute-DeviceTimeout/2, false)},
+			visits:  1,
+			dwell:   2 * time.Minute,
+			present: true,
+		},
+		{
+			name:   "nothing to merge into",
+			from:   []Visit{visit(0, 10*time.Second, false)},
+			visits: 1,
+			dwell:  10 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScanner()
+			s.presence["from"] = &Presence{DeviceID: "from", Visits: tt.from, Count: len(tt.from), Dwell: sumDuration(tt.from)}
+			if tt.into != nil {
+				s.presence["into"] = &Presence{DeviceID: "into", Visits: tt.into, Count: len(tt.into), Dwell: sumDuration(tt.into)}
+			}
+			s.mergePresence("from", "into")
+
+			if _, ok := s.presence["from"]; ok {
+				t.Error("merged-from presence kept")
+			}
+			p := s.presence["into"]
+			if p == nil || p.DeviceID != "into" {
+				t.Fatalf("presence = %+v", p)
+			}
+			if len(p.Visits) != tt.visits || p.Count != tt.visits || p.Dwell != tt.dwell {
+				t.Errorf("visits = %d, count = %d, dwell = %v, want %d visits of %v", len(p.Visits), p.Count, p.Dwell, tt.visits, tt.dwell)
+			}
+			if p.Present() != tt.present {
+				t.Errorf("present = %v, want %v", p.Present(), tt.present)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package ble
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// Packet reception tracking. Every device keeps a short history of how many
+// advertisements were received per time bucket; devices that advertise a
+// running counter also get an exact count of what they sent.
+
+const (
+	// Width and number of reception history buckets
+	ReceptionBucket  = 10 * time.Second
+	receptionBuckets = 60
+
+	eddystoneServiceUUID = 0xFEAA
+	eddystoneTLM         = 0x20
+	ruuviCompanyID       = 0x0499
+	ruuviRAWv2           = 0x05
+
+	// A counter jumping further than this restarted rather than skipped ahead
+	maxAdvCountJump = 100000
+	maxSequenceJump = 1 << 15
+)
+
+// Counter sources
+const (
+	CounterEddystoneTLM = "Eddystone TLM"
+	CounterRuuvi        = "Ruuvi sequence"
+)
+
+// ReceptionCount is the advertisements received in one bucket of time
+type ReceptionCount struct {
+	Start    time.Time
+	Received int
+	Sent     int // Counted by the device, where it advertises a counter
+	Counted  int // Received over the span Sent covers
+}
+
+// ReceptionCounter follows a counter the device advertises, giving an exact
+// count of what it sent
+type ReceptionCounter struct {
+	Source   string
+	Sent     int // Since the counter was first seen
+	Received int // Over the same span
+	Restarts int // Times the counter went backwards, e.g. on reboot
+
+	last    uint32
+	pending int // Advertisements received since the last counter value
+}
+
+// Ratio returns the fraction of sent advertisements that were received
+func (c *ReceptionCounter) Ratio() (float64, bool) {
+	if c.Sent == 0 {
+		return 0, false
+	}
+	return min(1, float64(c.Received)/float64(c.Sent)), true
+}
+
+// advance records a new counter value delta (wrapped) counts past the last
+// one, returning how many advertisements it accounts for and how many of
+// them were received. A delta over limit means the counter restarted.
+// Called with the device lock held.
+func (c *ReceptionCounter) advance(value, delta, limit uint32) (sent, received int) {
+	c.last = value
+	if delta == 0 {
+		return 0, 0
+	}
+	if delta > limit {
+		c.Restarts++
+		c.pending = 0
+		return 0, 0
+	}
+	sent, received = int(delta), c.pending
+	c.Sent += sent
+	c.Received += received
+	c.pending = 0
+	return sent, received
+}
+
+// eddystoneAdvCount returns ADV_CNT of an unencrypted Eddystone-TLM frame:
+// frame type, version, battery, temperature, then the advertisement count
+// since boot
+func (a *Advertisement) eddystoneAdvCount() (uint32, bool) {
+	data, ok := a.serviceData16(eddystoneServiceUUID)
+	if !ok || len(data) < 10 || data[0] != eddystoneTLM || data[1] != 0x00 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(data[6:10]), true
+}
+
+// ruuviSequence returns the measurement sequence number of a Ruuvi RAWv2
+// (data format 5) advertisement
+func (a *Advertisement) ruuviSequence() (uint16, bool) {
+	data, ok := a.manufacturerPayload(ruuviCompanyID)
+	if !ok || len(data) < 24 || data[0] != ruuviRAWv2 {
+		return 0, false
+	}
+	seq := binary.BigEndian.Uint16(data[16:18])
+	return seq, seq != 0xFFFF // 0xFFFF means not available
+}
+
+// updateReception counts an advertisement in the reception history and
+// follows any counter it carries. Called with d.mu held.
+func (d *Device) updateReception(adv Advertisement) {
+	start := adv.Timestamp.Truncate(ReceptionBucket)
+	if n := len(d.ReceptionHistory); n == 0 || d.ReceptionHistory[n-1].Start.Before(start) {
+		d.ReceptionHistory = append(d.ReceptionHistory, ReceptionCount{Start: start})
+		if len(d.ReceptionHistory) > receptionBuckets {
+			d.ReceptionHistory = d.ReceptionHistory[1:]
+		}
+	}
+	bucket := &d.ReceptionHistory[len(d.ReceptionHistory)-1]
+	bucket.Received++
+
+	if d.Counter != nil && d.Counter.Source == CounterEddystoneTLM {
+		// ADV_CNT counts frames of every type, not just TLM
+		d.Counter.pending++
+	}
+
+	var sent, received int
+	if count, ok := adv.eddystoneAdvCount(); ok {
+		if d.Counter == nil || d.Counter.Source != CounterEddystoneTLM {
+			d.Counter = &ReceptionCounter{Source: CounterEddystoneTLM, last: count}
+			return
+		}
+		sent, received = d.Counter.advance(count, count-d.Counter.last, maxAdvCountJump)
+	} else if seq, ok := adv.ruuviSequence(); ok {
+		if d.Counter == nil || d.Counter.Source != CounterRuuvi {
+			d.Counter = &ReceptionCounter{Source: CounterRuuvi, last: uint32(seq)}
+			return
+		}
+		// Each measurement counts once however often it is repeated
+		if uint32(seq) != d.Counter.last {
+			d.Counter.pending++
+		}
+		sent, received = d.Counter.advance(uint32(seq), uint32(seq-uint16(d.Counter.last)), maxSequenceJump)
+	}
+	bucket.Sent += sent
+	bucket.Counted += received
+}
@@ -1,6 +1,8 @@
 package ble
 
 import (
+	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,13 +19,43 @@ type Scanner struct {
 	Updates chan struct{}
 
 	scanning    bool
+	replaying   bool
 	stopChan    chan struct{}
 	cleanupTicker *time.Ticker
+
+	// Optional resolver merging RPAs of known identities into one device
+	resolver *Resolver
+
+	// Optional keys for decrypting Encrypted Advertising Data
+	eadKeys *EADKeyring
+
+	// Recently removed devices, kept as correlation candidates
+	departed []departedDevice
+	// Addresses merged into another device, mapped to that device's ID
+	aliases map[string]string
+
+	// Location trackers seen recently, keyed by current device ID
+	trackers     map[string]*TrackerSighting
+	trackerAlert time.Duration
+
+	// New-address arrivals per payload template, for flood detection
+	templates  map[string]*templateStats
+	floodLimit bool
+
+	// Anomalies of every device this session, oldest first
+	anomalies []Anomaly
+
+	// Visits of every device this session, keyed by device ID, and the
+	// arrivals and departures between them
+	presence    map[string]*Presence
+	presenceLog []PresenceEvent
 }
 
 const (
-	deviceTimeout    = 30 * time.Second
-	cleanupInterval  = 5 * time.Second
+	// DeviceTimeout is how long a device may go without advertising before
+	// it is removed as departed
+	DeviceTimeout   = 30 * time.Second
+	cleanupInterval = 5 * time.Second
 )
 
 // NewScanner creates a new BLE scanner
@@ -33,9 +65,28 @@ func NewScanner() *Scanner {
 		devices:  make(map[string]*Device),
 		Updates:  make(chan struct{}, 100),
 		stopChan: make(chan struct{}),
+		aliases:  make(map[string]string),
+		trackers: make(map[string]*TrackerSighting),
+		templates: make(map[string]*templateStats),
+		presence:  make(map[string]*Presence),
+
+		trackerAlert: DefaultTrackerAlertThreshold,
 	}
 }
 
+// SetResolver installs an IRK resolver. Advertisements from resolvable
+// private addresses of a known identity are merged into a single device.
+// It must be called before Start.
+func (s *Scanner) SetResolver(r *Resolver) {
+	s.resolver = r
+}
+
+// SetEADKeys installs keys for decrypting Encrypted Advertising Data.
+// It must be called before Start.
+func (s *Scanner) SetEADKeys(k *EADKeyring) {
+	s.eadKeys = k
+}
+
 // Start begins scanning for BLE devices
 func (s *Scanner) Start() error {
 	if err := s.adapter.Enable(); err != nil {
@@ -64,6 +115,37 @@ func (s *Scanner) Start() error {
 	return nil
 }
 
+// StartReplay feeds previously captured advertisements through the scanner
+// instead of scanning, preserving their original spacing. Timestamps are
+// shifted to the present so stale-device cleanup behaves as it does live.
+func (s *Scanner) StartReplay(ads []Advertisement) {
+	s.scanning = true
+	s.replaying = true
+
+	go func() {
+		if len(ads) == 0 {
+			return
+		}
+		origin := ads[0].Timestamp
+		start := time.Now()
+		for _, adv := range ads {
+			offset := adv.Timestamp.Sub(origin)
+			if wait := time.Until(start.Add(offset)); wait > 0 {
+				select {
+				case <-s.stopChan:
+					return
+				case <-time.After(wait):
+				}
+			}
+			adv.Timestamp = start.Add(offset)
+			s.ingest(adv)
+		}
+	}()
+
+	s.cleanupTicker = time.NewTicker(cleanupInterval)
+	go s.cleanupStaleDevices()
+}
+
 // Stop stops the BLE scanning
 func (s *Scanner) Stop() {
 	if !s.scanning {
@@ -74,10 +156,13 @@ func (s *Scanner) Stop() {
 	if s.cleanupTicker != nil {
 		s.cleanupTicker.Stop()
 	}
-	_ = s.adapter.StopScan()
+	if !s.replaying {
+		_ = s.adapter.StopScan()
+	}
 }
 
 // cleanupStaleDevices runs periodically to remove devices not seen recently
+// and to refresh correlation suggestions
 func (s *Scanner) cleanupStaleDevices() {
 	for {
 		select {
@@ -87,16 +172,28 @@ func (s *Scanner) cleanupStaleDevices() {
 			now := time.Now()
 			s.mu.Lock()
 			var removed bool
-			for address, device := range s.devices {
+			for id, device := range s.devices {
 				device.mu.RLock()
 				lastSeen := device.LastSeen
 				device.mu.RUnlock()
 
-				if now.Sub(lastSeen) > deviceTimeout {
-					delete(s.devices, address)
+				if now.Sub(lastSeen) > DeviceTimeout {
+					delete(s.devices, id)
+					if p, ok := s.presence[id]; ok {
+						s.departPresence(p)
+					}
+					s.departed = append(s.departed, departedDevice{device: device, departed: now})
+					for addr, aliasID := range s.aliases {
+						if aliasID == id {
+							delete(s.aliases, addr)
+						}
+					}
 					removed = true
 				}
 			}
+			s.updateCorrelations(now)
+			s.expireTrackers(now)
+			s.expireFloods(now)
 			s.mu.Unlock()
 
 			// Notify UI if any devices were removed
@@ -111,13 +208,28 @@ func (s *Scanner) cleanupStaleDevices() {
 	}
 }
 
+// randomBitReported is whether the platform backend reports whether an
+// address is random. CoreBluetooth hides addresses entirely and WinRT never
+// sets the random bit; BlueZ and the HCI backends report it.
+var randomBitReported = runtime.GOOS != "darwin" && runtime.GOOS != "windows"
+
 func (s *Scanner) handleAdvertisement(result bluetooth.ScanResult) {
 	address := result.Address.String()
 
 	adv := NewAdvertisement()
+	adv.Address = address
 	adv.RSSI = result.RSSI
+
+	if randomBitReported {
+		adv.AddressType = ClassifyAddress(address, result.Address.IsRandom())
+	}
 	adv.LocalName = result.LocalName()
 
+	// Raw payload bytes are only exposed by some backends; keep them for linting
+	if raw := result.Bytes(); len(raw) > 0 {
+		adv.RawData = append([]byte(nil), raw...)
+	}
+
 	// Extract manufacturer data
 	mfgData := result.ManufacturerData()
 	if len(mfgData) > 0 {
@@ -165,13 +277,54 @@ func (s *Scanner) handleAdvertisement(result bluetooth.ScanResult) {
 	}
 	adv.ADTypes = adTypes
 
+	s.ingest(adv)
+}
+
+// ingest adds an advertisement to the device table and notifies the UI
+func (s *Scanner) ingest(adv Advertisement) {
+	address := adv.Address
+
+	// Resolve private addresses of known identities to a stable key
+	key := address
+	var identity *IdentityKey
+	if s.resolver != nil && adv.AddressType == AddressTypeResolvablePrivate {
+		if id, ok := s.resolver.Resolve(address); ok {
+			identity = id
+			key = id.DeviceID()
+		}
+	}
+
+	s.decryptEAD(&adv, identity)
+
 	s.mu.Lock()
-	device, exists := s.devices[address]
+	if aliasID, ok := s.aliases[address]; ok && identity == nil {
+		key = aliasID
+	}
+	device, exists := s.devices[key]
 	if !exists {
+		var template string
+		if identity == nil {
+			var suppress bool
+			if template, suppress = s.checkFlood(adv); suppress {
+				s.mu.Unlock()
+				return
+			}
+		}
 		device = NewDevice(address)
-		s.devices[address] = device
+		if identity != nil {
+			device.ID = key
+			device.IdentityName = identity.Name
+		}
+		device.FloodTemplate = template
+		s.devices[key] = device
 	}
+	s.countFloodAdvert(device.FloodTemplate)
 	device.Update(adv)
+	s.recordPresence(device, adv.Timestamp)
+	if info, ok := adv.TrackerInfo(); ok {
+		s.updateTracker(device, info, adv)
+	}
+	s.logAnomalies(device)
 	s.mu.Unlock()
 
 	// Notify UI of update
@@ -182,25 +335,75 @@ func (s *Scanner) handleAdvertisement(result bluetooth.ScanResult) {
 	}
 }
 
-// GetDevices returns a copy of all discovered devices
+// decryptEAD decrypts an Encrypted Data AD structure, if present, and merges
+// the plaintext AD structures into the advertisement
+func (s *Scanner) decryptEAD(adv *Advertisement, identity *IdentityKey) {
+	data, ok := adv.FindAD(ADTypeEncryptedData)
+	if !ok {
+		return
+	}
+	if s.eadKeys == nil {
+		adv.EAD = EADEncrypted
+		return
+	}
+
+	targets := []string{adv.Address}
+	if identity != nil {
+		targets = append(targets, identity.Name, identity.IdentityAddress)
+	}
+	plaintext, status := s.eadKeys.Decrypt(data, targets...)
+	adv.EAD = status
+	if status == EADDecrypted {
+		adv.DecryptedData = plaintext
+		structures, _ := ParseADStructures(plaintext)
+		adv.applyADStructures(structures)
+	}
+}
+
+// GetDevices returns a copy of all discovered devices. Devices belonging to
+// an ongoing advertisement flood are collapsed into one aggregate device.
 func (s *Scanner) GetDevices() []Device {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	devices := make([]Device, 0, len(s.devices))
+	collapsed := make(map[string]bool)
 	for _, d := range s.devices {
+		d.mu.RLock()
+		template := d.FloodTemplate
+		d.mu.RUnlock()
+		if f, ok := s.flooding(template); ok {
+			if !collapsed[template] {
+				collapsed[template] = true
+				devices = append(devices, s.floodAggregate(f).Copy())
+			}
+			continue
+		}
 		devices = append(devices, d.Copy())
 	}
 	return devices
 }
 
-// GetDevice returns a copy of a specific device by address
-func (s *Scanner) GetDevice(address string) (Device, bool) {
+// GetDevice returns a copy of a specific device by ID
+func (s *Scanner) GetDevice(id string) (Device, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	if d, exists := s.devices[address]; exists {
-		return d.Copy(), true
+	if d, exists := s.devices[id]; exists {
+		copy := d.Copy()
+		copy.Windows = d.WindowStats(time.Now())
+		copy.Arrivals = d.RecentArrivals()
+		copy.Advertisements = d.AllAdvertisements()
+		copy.SecondTrend, copy.MinuteTrend = d.Trend()
+		if p, ok := s.presence[id]; ok {
+			presence := *p
+			presence.Visits = append([]Visit(nil), p.Visits...)
+			copy.Presence = &presence
+		}
+		return copy, true
+	}
+	if f, ok := s.flooding(strings.TrimPrefix(id, "flood:")); ok && strings.HasPrefix(id, "flood:") {
+		return s.floodAggregate(f).Copy(), true
 	}
 	return Device{}, false
 }
@@ -217,4 +420,11 @@ func (s *Scanner) Clear() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.devices = make(map[string]*Device)
+	s.departed = nil
+	s.aliases = make(map[string]string)
+	s.trackers = make(map[string]*TrackerSighting)
+	s.templates = make(map[string]*templateStats)
+	s.anomalies = nil
+	s.presence = make(map[string]*Presence)
+	s.presenceLog = nil
 }
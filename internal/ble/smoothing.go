@@ -0,0 +1,243 @@
+package ble
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RSSI smoothing. Single readings swing by 10 dB or more from multipath
+// fading, so every device runs its readings through a filter whose output is
+// what the RSSI column, sorting, filters and distance estimates use.
+
+// Smoothing filter kinds
+const (
+	SmoothingNone   = "none"
+	SmoothingEMA    = "ema"
+	SmoothingKalman = "kalman"
+	SmoothingMedian = "median"
+)
+
+// SmoothingKinds lists the filter kinds in the order the UI cycles through them
+var SmoothingKinds = []string{SmoothingKalman, SmoothingEMA, SmoothingMedian, SmoothingNone}
+
+// SmoothingConfig selects and tunes the RSSI smoothing filter
+type SmoothingConfig struct {
+	Kind             string
+	Alpha            float64 // EMA weight of each new reading, 0 to 1
+	ProcessNoise     float64 // Kalman: expected variance of the true RSSI between readings, dB²
+	MeasurementNoise float64 // Kalman: variance of a single reading, dB²
+	Window           int     // Median: readings in the sliding window
+}
+
+// DefaultSmoothingConfig returns the smoothing used when nothing is configured
+func DefaultSmoothingConfig() SmoothingConfig {
+	return SmoothingConfig{
+		Kind:             SmoothingKalman,
+		Alpha:            0.3,
+		ProcessNoise:     1,
+		MeasurementNoise: 16,
+		Window:           5,
+	}
+}
+
+// Validate checks the configuration for out-of-range parameters
+func (c SmoothingConfig) Validate() error {
+	switch c.Kind {
+	case SmoothingNone, SmoothingEMA, SmoothingKalman, SmoothingMedian:
+	default:
+		return fmt.Errorf("unknown smoothing %q (want %s)", c.Kind, strings.Join(SmoothingKinds, ", "))
+	}
+	if c.Alpha <= 0 || c.Alpha > 1 {
+		return fmt.Errorf("EMA alpha %v out of range 0-1", c.Alpha)
+	}
+	if c.ProcessNoise <= 0 || c.MeasurementNoise <= 0 {
+		return fmt.Errorf("Kalman noise must be positive")
+	}
+	if c.Window < 1 || c.Window > maxRSSIHistory {
+		return fmt.Errorf("median window %d out of range 1-%d", c.Window, maxRSSIHistory)
+	}
+	return nil
+}
+
+// String describes the filter and its parameters, e.g. "Kalman (Q 1, R 16)"
+func (c SmoothingConfig) String() string {
+	switch c.Kind {
+	case SmoothingEMA:
+		return fmt.Sprintf("EMA (alpha %g)", c.Alpha)
+	case SmoothingKalman:
+		return fmt.Sprintf("Kalman (Q %g, R %g)", c.ProcessNoise, c.MeasurementNoise)
+	case SmoothingMedian:
+		return fmt.Sprintf("median of %d", c.Window)
+	default:
+		return "none (raw)"
+	}
+}
+
+var (
+	smoothingMu         sync.RWMutex
+	smoothing           = DefaultSmoothingConfig()
+	smoothingGeneration int // Incremented on every change so devices rebuild their filter
+)
+
+// RSSISmoothing returns the smoothing configuration in use
+func RSSISmoothing() SmoothingConfig {
+	smoothingMu.RLock()
+	defer smoothingMu.RUnlock()
+	return smoothing
+}
+
+func currentSmoothing() (SmoothingConfig, int) {
+	smoothingMu.RLock()
+	defer smoothingMu.RUnlock()
+	return smoothing, smoothingGeneration
+}
+
+// SetRSSISmoothing changes the smoothing filter used by every device. It is
+// safe to call while scanning; devices pick the new filter up on their next
+// advertisement, or immediately when set through Scanner.SetRSSISmoothing.
+func SetRSSISmoothing(c SmoothingConfig) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	smoothingMu.Lock()
+	defer smoothingMu.Unlock()
+	smoothing = c
+	smoothingGeneration++
+	return nil
+}
+
+// SetRSSISmoothing changes the smoothing filter and re-smooths every device's
+// recent readings with it, so the change shows at once
+func (s *Scanner) SetRSSISmoothing(c SmoothingConfig) error {
+	if err := SetRSSISmoothing(c); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, d := range s.devices {
+		d.mu.Lock()
+		d.resmooth()
+		d.mu.Unlock()
+	}
+	return nil
+}
+
+// rssiFilter turns a stream of raw readings into a smoothed estimate
+type rssiFilter interface {
+	add(rssi int16) float64
+}
+
+func newRSSIFilter(c SmoothingConfig) rssiFilter {
+	switch c.Kind {
+	case SmoothingEMA:
+		return &emaFilter{alpha: c.Alpha}
+	case SmoothingKalman:
+		return &kalmanFilter{q: c.ProcessNoise, r: c.MeasurementNoise}
+	case SmoothingMedian:
+		return &medianFilter{window: c.Window}
+	default:
+		return rawFilter{}
+	}
+}
+
+// rawFilter passes readings through unchanged
+type rawFilter struct{}
+
+func (rawFilter) add(rssi int16) float64 {
+	return float64(rssi)
+}
+
+// emaFilter is an exponential moving average
+type emaFilter struct {
+	alpha   float64
+	value   float64
+	started bool
+}
+
+func (f *emaFilter) add(rssi int16) float64 {
+	if !f.started {
+		f.value = float64(rssi)
+		f.started = true
+	} else {
+		f.value += f.alpha * (float64(rssi) - f.value)
+	}
+	return f.value
+}
+
+// kalmanFilter is a one-dimensional Kalman filter modelling the true RSSI
+// as a random walk observed through noisy readings
+type kalmanFilter struct {
+	q, r     float64 // Process and measurement noise variances
+	estimate float64
+	variance float64 // Variance of the estimate
+	started  bool
+}
+
+func (f *kalmanFilter) add(rssi int16) float64 {
+	z := float64(rssi)
+	if !f.started {
+		f.estimate = z
+		f.variance = f.r
+		f.started = true
+		return f.estimate
+	}
+	// Predict: the true RSSI may have drifted since the last reading
+	f.variance += f.q
+	// Correct: weigh the reading by how much we trust it relative to the estimate
+	gain := f.variance / (f.variance + f.r)
+	f.estimate += gain * (z - f.estimate)
+	f.variance *= 1 - gain
+	return f.estimate
+}
+
+// medianFilter is the median of a sliding window of readings, which ignores
+// isolated deep fades entirely
+type medianFilter struct {
+	window   int
+	readings []int16
+}
+
+func (f *medianFilter) add(rssi int16) float64 {
+	f.readings = append(f.readings, rssi)
+	if len(f.readings) > f.window {
+		f.readings = f.readings[1:]
+	}
+	sorted := append([]int16(nil), f.readings...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	n := len(sorted)
+	if n%2 == 0 {
+		return (float64(sorted[n/2-1]) + float64(sorted[n/2])) / 2
+	}
+	return float64(sorted[n/2])
+}
+
+// smoothRSSI feeds a reading through the device's filter, rebuilding the
+// filter from recent readings if the configuration changed. Called with d.mu
+// held, after the reading has been added to RSSIHistory.
+func (d *Device) smoothRSSI(rssi int16) {
+	config, generation := currentSmoothing()
+	if d.smoother == nil || d.smoothingGeneration != generation {
+		d.rebuildSmoother(config, generation)
+		return
+	}
+	d.RSSISmoothed = d.smoother.add(rssi)
+}
+
+// resmooth rebuilds the device's filter with the current configuration.
+// Called with d.mu held.
+func (d *Device) resmooth() {
+	config, generation := currentSmoothing()
+	d.rebuildSmoother(config, generation)
+}
+
+// rebuildSmoother starts a new filter and replays the recent readings
+// through it
+func (d *Device) rebuildSmoother(config SmoothingConfig, generation int) {
+	d.smoother = newRSSIFilter(config)
+	d.smoothingGeneration = generation
+	for _, rssi := range d.RSSIHistory {
+		d.RSSISmoothed = d.smoother.add(rssi)
+	}
+}
@@ -0,0 +1,112 @@
+package ble
+
+import (
+	"math"
+	"testing"
+)
+
+// repeatRSSI returns n readings of rssi
+func repeatRSSI(n int, rssi int16) []int16 {
+	readings := make([]int16, n)
+	for i := range readings {
+		readings[i] = rssi
+	}
+	return readings
+}
+
+func TestRSSIFilters(t *testing.T) {
+	config := func(kind string) SmoothingConfig {
+		c := DefaultSmoothingConfig()
+		c.Kind = kind
+		c.Alpha = 0.5
+		c.Window = 3
+		return c
+	}
+	tests := []struct {
+		name     string
+		config   SmoothingConfig
+		readings []int16
+		want     float64
+		within   float64 // Tolerance, 0 for exact
+	}{
+		{name: "raw", config: config(SmoothingNone), readings: []int16{-60, -70}, want: -70},
+		{name: "EMA first reading", config: config(SmoothingEMA), readings: []int16{-60}, want: -60},
+		{name: "EMA", config: config(SmoothingEMA), readings: []int16{-60, -70, -80}, want: -72.5},
+		{name: "median of an even count", config: config(SmoothingMedian), readings: []int16{-60, -62}, want: -61},
+		{name: "median ignores a deep fade", config: config(SmoothingMedian), readings: []int16{-60, -95, -62}, want: -62},
+		{name: "median window slides", config: config(SmoothingMedian), readings: []int16{-60, -90, -62, -64}, want: -64},
+		{name: "Kalman first reading", config: config(SmoothingKalman), readings: []int16{-60}, want: -60},
+		{name: "Kalman second reading", config: config(SmoothingKalman), readings: []int16{-60, -70}, want: -60 - 10*17.0/33, within: 1e-9},
+		{
+			name:     "Kalman converges on a new level",
+			config:   config(SmoothingKalman),
+			readings: append([]int16{-60}, repeatRSSI(50, -70)...),
+			want:     -70,
+			within:   0.1,
+		},
+		{
+			name:     "Kalman damps a single fade",
+			config:   config(SmoothingKalman),
+			readings: append(repeatRSSI(20, -60), -95),
+			want:     -60,
+			within:   10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newRSSIFilter(tt.config)
+			var got float64
+			for _, rssi := range tt.readings {
+				got = f.add(rssi)
+			}
+			if math.Abs(got-tt.want) > tt.within {
+				t.Errorf("smoothed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSmoothingConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(c *SmoothingConfig)
+		wantErr bool
+	}{
+		{name: "default", change: func(c *SmoothingConfig) {}},
+		{name: "unknown kind", change: func(c *SmoothingConfig) { c.Kind = "lowpass" }, wantErr: true},
+		{name: "alpha of 1", change: func(c *SmoothingConfig) { c.Alpha = 1 }},
+		{name: "alpha of 0", change: func(c *SmoothingConfig) { c.Alpha = 0 }, wantErr: true},
+		{name: "negative process noise", change: func(c *SmoothingConfig) { c.ProcessNoise = -1 }, wantErr: true},
+		{name: "zero measurement noise", change: func(c *SmoothingConfig) { c.MeasurementNoise = 0 }, wantErr: true},
+		{name: "window of 1", change: func(c *SmoothingConfig) { c.Window = 1 }},
+		{name: "window longer than the history", change: func(c *SmoothingConfig) { c.Window = maxRSSIHistory + 1 }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultSmoothingConfig()
+			tt.change(&c)
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSmoothingConfigString(t *testing.T) {
+	tests := []struct {
+		kind string
+		want string
+	}{
+		{SmoothingKalman, "Kalman (Q 1, R 16)"},
+		{SmoothingEMA, "EMA (alpha 0.3)"},
+		{SmoothingMedian, "median of 5"},
+		{SmoothingNone, "none (raw)"},
+	}
+	for _, tt := range tests {
+		c := DefaultSmoothingConfig()
+		c.Kind = tt.kind
+		if got := c.String(); got != tt.want {
+			t.Errorf("%s: String() = %q, want %q", tt.kind, got, tt.want)
+		}
+	}
+}
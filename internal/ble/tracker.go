@@ -0,0 +1,257 @@
+package ble
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Unwanted tracker detection: Apple Find My (including AirTag), Samsung
+// SmartTag, Tile and the DULT (Detecting Unwanted Location Trackers) format
+
+const (
+	findMyAdvType        = 0x12
+	findMySeparatedLen   = 0x19 // Offline finding payload: owner not nearby
+	findMyNearOwnerLen   = 0x02 // Short payload: owner nearby
+	companyTile          = 0x0310
+	tileServiceUUID      = 0xFEED
+	tileAltServiceUUID   = 0xFEEC
+	smartTagServiceUUID  = 0xFD5A
+	dultServiceUUID      = 0xFCB2
+	dultNearOwnerBit     = 0x80
+	smartTagStateOffline = 0x02
+	smartTagStateOverdue = 0x03
+)
+
+const (
+	// How long a tracker sighting is remembered after it was last heard
+	trackerMemory = 15 * time.Minute
+	// A new tracker appearing within this long after another of the same
+	// kind went quiet is treated as the same tracker with a new address.
+	// Longer silences don't count toward the time a tracker has been near.
+	trackerHandoffGap = 30 * time.Second
+
+	// DefaultTrackerAlertThreshold is how long a separated tracker must
+	// follow before it raises an alert
+	DefaultTrackerAlertThreshold = 10 * time.Minute
+)
+
+// TrackerInfo identifies a location tracker from one advertisement
+type TrackerInfo struct {
+	Kind      string // "AirTag", "Find My", "SmartTag", "Tile", "DULT"
+	Separated *bool  // Whether the tracker reports being away from its owner; nil if unknown
+	Detail    string // Protocol-specific status
+}
+
+// FormatState describes whether the tracker is with its owner
+func (t *TrackerInfo) FormatState() string {
+	switch {
+	case t.Separated == nil:
+		return "owner unknown"
+	case *t.Separated:
+		return "separated"
+	default:
+		return "near owner"
+	}
+}
+
+// TrackerInfo returns the tracker the advertisement comes from, if any
+func (a *Advertisement) TrackerInfo() (*TrackerInfo, bool) {
+	separated := func(v bool) *bool { return &v }
+
+	if data, ok := a.manufacturerPayload(companyApple); ok && len(data) >= 2 && data[0] == findMyAdvType {
+		t := &TrackerInfo{Kind: "Find My"}
+		switch data[1] {
+		case findMySeparatedLen:
+			t.Separated = separated(true)
+			if len(data) >= 3 {
+				// Status byte bits 4-5: device type (AirGuard research)
+				switch (data[2] >> 4) & 0x03 {
+				case 0x00:
+					t.Detail = "Apple device"
+				case 0x01:
+					t.Kind = "AirTag"
+				case 0x02:
+					t.Detail = "Find My accessory"
+				case 0x03:
+					t.Detail = "AirPods"
+				}
+			}
+		case findMyNearOwnerLen:
+			t.Separated = separated(false)
+		}
+		return t, true
+	}
+
+	if data, ok := a.serviceData16(dultServiceUUID); ok && len(data) >= 2 {
+		t := &TrackerInfo{Kind: "DULT", Separated: separated(data[1]&dultNearOwnerBit == 0)}
+		switch data[0] {
+		case 0x01:
+			t.Detail = "Apple network"
+		case 0x02:
+			t.Detail = "Google network"
+		default:
+			t.Detail = fmt.Sprintf("network 0x%02X", data[0])
+		}
+		return t, true
+	}
+
+	if data, ok := a.serviceData16(smartTagServiceUUID); ok && len(data) >= 1 {
+		// Advertisement state in bits 3-1 of the first octet (published
+		// reverse engineering); offline states mean the owner's phone is away
+		t := &TrackerInfo{Kind: "SmartTag"}
+		switch state := (data[0] >> 1) & 0x07; state {
+		case smartTagStateOffline, smartTagStateOverdue:
+			t.Separated = separated(true)
+			t.Detail = "offline"
+		default:
+			t.Detail = fmt.Sprintf("state %d", state)
+		}
+		return t, true
+	}
+
+	_, tileMfg := a.manufacturerPayload(companyTile)
+	_, tileData := a.serviceData16(tileServiceUUID)
+	_, tileAltData := a.serviceData16(tileAltServiceUUID)
+	if tileMfg || tileData || tileAltData || a.hasService16(tileServiceUUID) || a.hasService16(tileAltServiceUUID) {
+		// Tile doesn't advertise whether it is near its owner
+		return &TrackerInfo{Kind: "Tile"}, true
+	}
+
+	return nil, false
+}
+
+// hasService16 reports whether a 16-bit service UUID is advertised
+func (a *Advertisement) hasService16(uuid uint16) bool {
+	for _, s := range a.ServiceUUIDs {
+		if short, ok := ShortUUID(s); ok && short == uuid {
+			return true
+		}
+	}
+	return false
+}
+
+// TrackerSighting follows one physical tracker over time, across the
+// addresses it rotates through
+type TrackerSighting struct {
+	DeviceID  string // Current device in the scanner's table
+	Kind      string
+	State     string
+	Separated *bool
+	Name      string
+	Addresses []string // Every address attributed to this tracker, oldest first
+	FirstSeen time.Time
+	LastSeen  time.Time
+	RSSI      int16
+	Alert     bool // Separated and following longer than the threshold
+	// Owner state not advertised (Tile, most SmartTag states) and following
+	// longer than the threshold; shown, but not raised as an alert
+	OwnerUnknown bool
+
+	near time.Duration // Time heard, not counting silences over trackerHandoffGap
+}
+
+// Dwell returns how long the tracker has been near: the time since it was
+// first heard, less any silences too long to have been following
+func (t *TrackerSighting) Dwell() time.Duration {
+	return t.near
+}
+
+// SetTrackerAlertThreshold sets how long a separated tracker must follow
+// before it is flagged. It must be called before Start.
+func (s *Scanner) SetTrackerAlertThreshold(d time.Duration) {
+	s.trackerAlert = d
+}
+
+// TrackerAlertThreshold returns the configured alert threshold
+func (s *Scanner) TrackerAlertThreshold() time.Duration {
+	return s.trackerAlert
+}
+
+// updateTracker records a tracker sighting for device. Called with s.mu held.
+func (s *Scanner) updateTracker(device *Device, info *TrackerInfo, adv Advertisement) {
+	sighting, ok := s.trackers[device.ID]
+	if !ok {
+		sighting = s.trackerHandoff(info.Kind, adv.Timestamp)
+		if sighting == nil {
+			sighting = &TrackerSighting{FirstSeen: adv.Timestamp}
+		} else {
+			delete(s.trackers, sighting.DeviceID)
+		}
+		sighting.DeviceID = device.ID
+		s.trackers[device.ID] = sighting
+	}
+
+	sighting.Kind = info.Kind
+	sighting.State = info.FormatState()
+	sighting.Separated = info.Separated
+	sighting.Name = device.GetDisplayName()
+	if gap := adv.Timestamp.Sub(sighting.LastSeen); !sighting.LastSeen.IsZero() && gap > 0 && gap <= trackerHandoffGap {
+		sighting.near += gap
+	}
+	sighting.LastSeen = adv.Timestamp
+	sighting.RSSI = adv.RSSI
+	if n := len(sighting.Addresses); n == 0 || sighting.Addresses[n-1] != adv.Address {
+		sighting.Addresses = append(sighting.Addresses, adv.Address)
+		if len(sighting.Addresses) > maxAddressHistory {
+			sighting.Addresses = sighting.Addresses[1:]
+		}
+	}
+	following := sighting.Dwell() >= s.trackerAlert
+	sighting.Alert = following && info.Separated != nil && *info.Separated
+	sighting.OwnerUnknown = following && info.Separated == nil
+}
+
+// trackerHandoff finds the single sighting of the same kind that went quiet
+// just before now, i.e. the likely previous address of a rotating tracker.
+// Called with s.mu held.
+func (s *Scanner) trackerHandoff(kind string, now time.Time) *TrackerSighting {
+	var candidate *TrackerSighting
+	for _, t := range s.trackers {
+		gap := now.Sub(t.LastSeen)
+		if t.Kind != kind || gap < 0 || gap > trackerHandoffGap {
+			continue
+		}
+		// Still advertising under its own address: not a rotation
+		if d, ok := s.devices[t.DeviceID]; ok {
+			d.mu.RLock()
+			lastSeen := d.LastSeen
+			d.mu.RUnlock()
+			if now.Sub(lastSeen) < trackerHandoffGap/2 {
+				continue
+			}
+		}
+		if candidate != nil {
+			return nil // Ambiguous
+		}
+		candidate = t
+	}
+	return candidate
+}
+
+// expireTrackers forgets trackers not heard for trackerMemory. Called with
+// s.mu held.
+func (s *Scanner) expireTrackers(now time.Time) {
+	for id, t := range s.trackers {
+		if now.Sub(t.LastSeen) > trackerMemory {
+			delete(s.trackers, id)
+		}
+	}
+}
+
+// GetTrackers returns all remembered trackers, longest dwell first
+func (s *Scanner) GetTrackers() []TrackerSighting {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	trackers := make([]TrackerSighting, 0, len(s.trackers))
+	for _, t := range s.trackers {
+		c := *t
+		c.Addresses = append([]string(nil), t.Addresses...)
+		trackers = append(trackers, c)
+	}
+	sort.Slice(trackers, func(i, j int) bool {
+		return trackers[i].Dwell() > trackers[j].Dwell()
+	})
+	return trackers
+}
@@ -0,0 +1,50 @@
+package ble
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrackerAlert(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	separated := func(v bool) *bool { return &v }
+	tests := []struct {
+		name         string
+		info         TrackerInfo
+		near         time.Duration
+		alert        bool
+		ownerUnknown bool
+	}{
+		{name: "separated AirTag", info: TrackerInfo{Kind: "AirTag", Separated: separated(true)}, near: 11 * time.Minute, alert: true},
+		{name: "separated under the threshold", info: TrackerInfo{Kind: "AirTag", Separated: separated(true)}, near: 9 * time.Minute},
+		{name: "near its owner", info: TrackerInfo{Kind: "Find My", Separated: separated(false)}, near: 11 * time.Minute},
+		{name: "Tile", info: TrackerInfo{Kind: "Tile"}, near: 11 * time.Minute, ownerUnknown: true},
+		{name: "Tile under the threshold", info: TrackerInfo{Kind: "Tile"}, near: 9 * time.Minute},
+		{name: "SmartTag in an unknown state", info: TrackerInfo{Kind: "SmartTag", Detail: "state 1"}, near: 11 * time.Minute, ownerUnknown: true},
+		{name: "offline SmartTag", info: TrackerInfo{Kind: "SmartTag", Separated: separated(true), Detail: "offline"}, near: 11 * time.Minute, alert: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScanner()
+			d := NewDevice("C6:11:22:33:44:55")
+			for at := time.Duration(0); at <= tt.near; at += 10 * time.Second {
+				adv := NewAdvertisement()
+				adv.Address = d.Address
+				adv.Timestamp = start.Add(at)
+				s.updateTracker(d, &tt.info, adv)
+			}
+
+			trackers := s.GetTrackers()
+			if len(trackers) != 1 {
+				t.Fatalf("trackers = %d, want 1", len(trackers))
+			}
+			tr := trackers[0]
+			if tr.Alert != tt.alert || tr.OwnerUnknown != tt.ownerUnknown {
+				t.Errorf("alert = %v, owner unknown = %v after %v, want %v and %v", tr.Alert, tr.OwnerUnknown, tr.Dwell(), tt.alert, tt.ownerUnknown)
+			}
+			if tr.State != tt.info.FormatState() {
+				t.Errorf("state = %q, want %q", tr.State, tt.info.FormatState())
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package capture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// btsnoop datalink types
+const (
+	btsnoopHCIUnencapsulated = 1001 // H1: no packet indicator, type in flags
+	btsnoopHCIUART           = 1002 // H4: packet indicator byte first
+	btsnoopLinuxMonitor      = 2001 // btmon: opcode in the upper flags bits
+)
+
+// btsnoopEpochDelta is the number of microseconds between 0 AD (btsnoop's
+// epoch) and the Unix epoch
+const btsnoopEpochDelta = 0x00dcddb30f2f8000
+
+// btmon opcode for received events
+const monitorEventPacket = 0x0003
+
+// readBTSnoop reads a btsnoop file (RFC 1761 style, as written by Android
+// and btmon -w). The 8-byte magic has been peeked but not consumed.
+func readBTSnoop(r io.Reader, d *decoder) error {
+	var header [16]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return fmt.Errorf("btsnoop header: %w", err)
+	}
+	datalink := binary.BigEndian.Uint32(header[12:16])
+	switch datalink {
+	case btsnoopHCIUnencapsulated, btsnoopHCIUART, btsnoopLinuxMonitor:
+	default:
+		return fmt.Errorf("btsnoop: unsupported datalink type %d", datalink)
+	}
+
+	for {
+		var rec [24]byte
+		if _, err := io.ReadFull(r, rec[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("btsnoop record: %w", err)
+		}
+		inclLen := binary.BigEndian.Uint32(rec[4:8])
+		flags := binary.BigEndian.Uint32(rec[8:12])
+		micros := int64(binary.BigEndian.Uint64(rec[16:24])) - btsnoopEpochDelta
+		ts := time.UnixMicro(micros)
+
+		pkt := make([]byte, inclLen)
+		if _, err := io.ReadFull(r, pkt); err != nil {
+			return fmt.Errorf("btsnoop record: %w", err)
+		}
+
+		var event []byte
+		switch datalink {
+		case btsnoopHCIUnencapsulated:
+			// Bit 1 set: command/event; bit 0 set: received
+			if flags&0x03 == 0x03 {
+				event = pkt
+			}
+		case btsnoopHCIUART:
+			if len(pkt) > 0 && pkt[0] == hciEventPacket {
+				event = pkt[1:]
+			}
+		case btsnoopLinuxMonitor:
+			if flags&0xFFFF == monitorEventPacket {
+				event = pkt
+			}
+		}
+		if event != nil {
+			if err := d.event(event, ts); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Package capture reads advertisements from HCI capture files (btsnoop and
+// pcap), including fragmented Bluetooth 5 extended advertising reports, and
+// from link-layer pcap captures made with a sniffer.
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/buckleypaul/blescan/internal/ble"
+)
+
+// HCI packet types (H4 packet indicators)
+const (
+	hciEventPacket = 0x04
+)
+
+// HCI event codes
+const (
+	hciEventLEMeta = 0x3E
+)
+
+// ReadFile reads all advertising reports from a btsnoop or pcap file
+func ReadFile(path string) ([]ble.Advertisement, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read detects the capture format and returns all advertisements in it
+func Read(r io.Reader) ([]ble.Advertisement, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(8)
+	if err != nil {
+		return nil, fmt.Errorf("reading capture header: %w", err)
+	}
+
+	d := newDecoder()
+	switch {
+	case bytes.Equal(magic, []byte("btsnoop\x00")):
+		err = readBTSnoop(br, d)
+	case isPcapMagic(magic[:4]):
+		err = readPcap(br, d)
+	default:
+		return nil, fmt.Errorf("unrecognized capture format (expected btsnoop or pcap)")
+	}
+	return d.ads, err
+}
+
+// decoder turns HCI events into advertisements
+type decoder struct {
+	reassembler *ble.Reassembler
+	ads         []ble.Advertisement
+}
+
+func newDecoder() *decoder {
+	return &decoder{reassembler: ble.NewReassembler()}
+}
+
+// linkLayer handles a link-layer packet captured by a sniffer. Sniffers
+// record corrupted packets too, so ones that don't parse are skipped rather
+// than ending the capture.
+func (d *decoder) linkLayer(pkt []byte, rssi int16, ts time.Time) {
+	if adv, ok, err := ble.ParseAdvertisingPDU(pkt, rssi, ts); ok && err == nil {
+		d.ads = append(d.ads, adv)
+	}
+}
+
+// event handles an HCI event packet without the H4 packet indicator:
+// [event code][parameter length][parameters]
+func (d *decoder) event(pkt []byte, ts time.Time) error {
+	if len(pkt) < 3 || pkt[0] != hciEventLEMeta {
+		return nil
+	}
+	params := pkt[2:]
+	if int(pkt[1]) < len(params) {
+		params = params[:pkt[1]]
+	}
+	if len(params) == 0 {
+		return nil
+	}
+
+	switch params[0] {
+	case ble.HCISubeventAdvertisingReport:
+		ads, err := ble.ParseAdvertisingReports(params[1:], ts)
+		d.ads = append(d.ads, ads...)
+		return err
+	case ble.HCISubeventExtendedAdvertisingReport:
+		reports, err := ble.ParseExtendedAdvertisingReports(params[1:])
+		for _, report := range reports {
+			if adv, ok := d.reassembler.Add(report, ts); ok {
+				d.ads = append(d.ads, adv)
+			}
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// leMeta wraps LE Meta event parameters in an HCI event packet without the
+// H4 packet indicator
+func leMeta(subevent byte, params []byte) []byte {
+	return append([]byte{hciEventLEMeta, byte(1 + len(params)), subevent}, params...)
+}
+
+// record is one packet of a capture
+type record struct {
+	flags uint32 // btsnoop only
+	pkt   []byte
+}
+
+// btsnoopFile builds a btsnoop capture with records a second apart from ts
+func btsnoopFile(datalink uint32, ts time.Time, records ...record) []byte {
+	var b bytes.Buffer
+	b.WriteString("btsnoop\x00")
+	binary.Write(&b, binary.BigEndian, uint32(1))
+	binary.Write(&b, binary.BigEndian, datalink)
+	for i, r := range records {
+		at := ts.Add(time.Duration(i) * time.Second).UnixMicro()
+		binary.Write(&b, binary.BigEndian, uint32(len(r.pkt)))
+		binary.Write(&b, binary.BigEndian, uint32(len(r.pkt)))
+		binary.Write(&b, binary.BigEndian, r.flags)
+		binary.Write(&b, binary.BigEndian, uint32(0))
+		binary.Write(&b, binary.BigEndian, uint64(at+btsnoopEpochDelta))
+		b.Write(r.pkt)
+	}
+	return b.Bytes()
+}
+
+// pcapFile builds a pcap capture with records a second apart from ts
+func pcapFile(order binary.ByteOrder, nanos bool, linktype uint32, ts time.Time, records ...record) []byte {
+	var b bytes.Buffer
+	magic := uint32(0xa1b2c3d4)
+	if nanos {
+		magic = 0xa1b23c4d
+	}
+	binary.Write(&b, order, magic)
+	binary.Write(&b, order, uint16(2))
+	binary.Write(&b, order, uint16(4))
+	binary.Write(&b, order, uint32(0))
+	binary.Write(&b, order, uint32(0))
+	binary.Write(&b, order, uint32(65535))
+	binary.Write(&b, order, linktype)
+	for i, r := range records {
+		at := ts.Add(time.Duration(i) * time.Second)
+		frac := uint32(at.Nanosecond() / 1000)
+		if nanos {
+			frac = uint32(at.Nanosecond())
+		}
+		binary.Write(&b, order, uint32(at.Unix()))
+		binary.Write(&b, order, frac)
+		binary.Write(&b, order, uint32(len(r.pkt)))
+		binary.Write(&b, order, uint32(len(r.pkt)))
+		b.Write(r.pkt)
+	}
+	return b.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	ts := time.Unix(1700000000, 123456000)
+
+	// ADV_IND from public 11:22:33:44:55:66 named "Test" at -60 dBm
+	legacy := leMeta(0x02, mustHex(t, "01"+"00"+"00"+"665544332211"+"06"+"050954657374"+"c4"))
+	// The same name as an extended advertisement from random static
+	// C6:11:22:33:44:55 at -55 dBm, in two fragments
+	extHeader := func(eventType string, dataLen int) string {
+		return "01" + eventType + "01" + "5544332211c6" + "0102" + "01" + "7f" + "c9" + "0000" + "00000000000000" + hex.EncodeToString([]byte{byte(dataLen)})
+	}
+	extFirst := leMeta(0x0D, mustHex(t, extHeader("2000", 4)+"05095465"))
+	extLast := leMeta(0x0D, mustHex(t, extHeader("0000", 2)+"7374"))
+	// Sniffed ADV_IND from random static C6:11:22:33:44:55 named "Test"
+	ll := mustHex(t, "d6be898e"+"400f"+"5544332211c6"+"020106"+"0509"+"54657374"+"aabbcc")
+	// BLUETOOTH_LE_LL_WITH_PHDR: channel 37, -70 dBm, CRC checked and valid
+	phdr := func(flags uint16) []byte {
+		h := []byte{37, 0xBA, 0, 0, 0xd6, 0xbe, 0x89, 0x8e, byte(flags), byte(flags >> 8)}
+		return append(h, ll...)
+	}
+
+	tests := []struct {
+		name    string
+		file    []byte
+		adverts int
+		address string
+		rssi    int16
+		at      time.Duration // Of the advertisement after the capture start
+		wantErr bool
+	}{
+		{
+			name:    "btsnoop H1 received event",
+			file:    btsnoopFile(btsnoopHCIUnencapsulated, ts, record{flags: 3, pkt: legacy}),
+			adverts: 1, address: "11:22:33:44:55:66", rssi: -60,
+		},
+		{
+			name: "btsnoop H1 sent command skipped",
+			file: btsnoopFile(btsnoopHCIUnencapsulated, ts, record{flags: 2, pkt: legacy}),
+		},
+		{
+			name:    "btsnoop H4",
+			file:    btsnoopFile(btsnoopHCIUART, ts, record{pkt: append([]byte{hciEventPacket}, legacy...)}),
+			adverts: 1, address: "11:22:33:44:55:66", rssi: -60,
+		},
+		{
+			name:    "btsnoop Linux monitor",
+			file:    btsnoopFile(btsnoopLinuxMonitor, ts, record{flags: monitorEventPacket, pkt: legacy}),
+			adverts: 1, address: "11:22:33:44:55:66", rssi: -60,
+		},
+		{
+			name: "btsnoop fragmented extended report",
+			file: btsnoopFile(btsnoopHCIUnencapsulated, ts,
+				record{flags: 3, pkt: extFirst}, record{flags: 3, pkt: extLast}),
+			adverts: 1, address: "C6:11:22:33:44:55", rssi: -55, at: time.Second,
+		},
+		{
+			name:    "btsnoop unsupported datalink",
+			file:    btsnoopFile(1003, ts),
+			wantErr: true,
+		},
+		{
+			name:    "btsnoop truncated header",
+			file:    btsnoopFile(btsnoopHCIUART, ts)[:12],
+			wantErr: true,
+		},
+		{
+			name:    "btsnoop truncated record",
+			file:    btsnoopFile(btsnoopHCIUnencapsulated, ts, record{flags: 3, pkt: legacy})[:40],
+			wantErr: true,
+		},
+		{
+			name:    "pcap H4",
+			file:    pcapFile(binary.LittleEndian, false, linktypeBluetoothHCIH4, ts, record{pkt: append([]byte{hciEventPacket}, legacy...)}),
+			adverts: 1, address: "11:22:33:44:55:66", rssi: -60,
+		},
+		{
+			name:    "pcap big-endian",
+			file:    pcapFile(binary.BigEndian, false, linktypeBluetoothHCIH4, ts, record{pkt: append([]byte{hciEventPacket}, legacy...)}),
+			adverts: 1, address: "11:22:33:44:55:66", rssi: -60,
+		},
+		{
+			name:    "pcap nanosecond timestamps",
+			file:    pcapFile(binary.LittleEndian, true, linktypeBluetoothHCIH4, ts, record{pkt: append([]byte{hciEventPacket}, legacy...)}),
+			adverts: 1, address: "11:22:33:44:55:66", rssi: -60,
+		},
+		{
+			name:    "pcap H4 with direction header",
+			file:    pcapFile(binary.LittleEndian, false, linktypeBluetoothHCIH4WithPHDR, ts, record{pkt: append([]byte{0, 0, 0, 1, hciEventPacket}, legacy...)}),
+			adverts: 1, address: "11:22:33:44:55:66", rssi: -60,
+		},
+		{
+			name:    "pcap Linux monitor",
+			file:    pcapFile(binary.LittleEndian, false, linktypeBluetoothLinuxMonitor, ts, record{pkt: append([]byte{0, 0, 0, monitorEventPacket}, legacy...)}),
+			adverts: 1, address: "11:22:33:44:55:66", rssi: -60,
+		},
+		{
+			name: "pcap fragmented extended report",
+			file: pcapFile(binary.LittleEndian, false, linktypeBluetoothHCIH4, ts,
+				record{pkt: append([]byte{hciEventPacket}, extFirst...)}, record{pkt: append([]byte{hciEventPacket}, extLast...)}),
+			adverts: 1, address: "C6:11:22:33:44:55", rssi: -55, at: time.Second,
+		},
+		{
+			name:    "pcap LE link layer",
+			file:    pcapFile(binary.LittleEndian, false, linktypeBluetoothLELL, ts, record{pkt: ll}),
+			adverts: 1, address: "C6:11:22:33:44:55", rssi: 0,
+		},
+		{
+			name:    "pcap LE link layer with pseudo-header",
+			file:    pcapFile(binary.LittleEndian, false, linktypeBluetoothLELLWithPHDR, ts, record{pkt: phdr(llPHDRSignalPowerValid | llPHDRCRCChecked | llPHDRCRCValid)}),
+			adverts: 1, address: "C6:11:22:33:44:55", rssi: -70,
+		},
+		{
+			name: "pcap LE link layer with a bad CRC skipped",
+			file: pcapFile(binary.LittleEndian, false, linktypeBluetoothLELLWithPHDR, ts, record{pkt: phdr(llPHDRSignalPowerValid | llPHDRCRCChecked)}),
+		},
+		{
+			name: "pcap corrupt link-layer packet skipped",
+			file: pcapFile(binary.LittleEndian, false, linktypeBluetoothLELL, ts, record{pkt: ll[:8]}, record{pkt: ll}),
+			// Only the second packet; the first doesn't stop the capture
+			adverts: 1, address: "C6:11:22:33:44:55", rssi: 0, at: time.Second,
+		},
+		{
+			name:    "pcap unsupported link type",
+			file:    pcapFile(binary.LittleEndian, false, 1, ts),
+			wantErr: true,
+		},
+		{
+			name:    "pcap truncated record",
+			file:    pcapFile(binary.LittleEndian, false, linktypeBluetoothHCIH4, ts, record{pkt: append([]byte{hciEventPacket}, legacy...)})[:44],
+			wantErr: true,
+		},
+		{
+			name:    "unrecognized format",
+			file:    []byte("not a capture file"),
+			wantErr: true,
+		},
+		{
+			name:    "shorter than a magic number",
+			file:    []byte("btsn"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ads, err := Read(bytes.NewReader(tt.file))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if len(ads) != tt.adverts {
+				t.Fatalf("adverts = %d, want %d", len(ads), tt.adverts)
+			}
+			for _, adv := range ads {
+				if adv.Address != tt.address || adv.RSSI != tt.rssi || adv.LocalName != "Test" {
+					t.Errorf("address = %s, RSSI = %d, name = %q, want %s, %d and \"Test\"", adv.Address, adv.RSSI, adv.LocalName, tt.address, tt.rssi)
+				}
+				if want := ts.Add(tt.at); !adv.Timestamp.Equal(want) {
+					t.Errorf("timestamp = %v, want %v", adv.Timestamp, want)
+				}
+			}
+		})
+	}
+}
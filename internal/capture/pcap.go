@@ -0,0 +1,116 @@
+package capture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// pcap link types carrying HCI traffic, or link-layer packets from a sniffer
+const (
+	linktypeBluetoothHCIH4         = 187
+	linktypeBluetoothHCIH4WithPHDR = 201
+	linktypeBluetoothLELL          = 251
+	linktypeBluetoothLinuxMonitor  = 254
+	linktypeBluetoothLELLWithPHDR  = 256
+)
+
+// Flags of the BLUETOOTH_LE_LL_WITH_PHDR pseudo-header
+const (
+	llPHDRSignalPowerValid = 0x0002
+	llPHDRCRCChecked       = 0x0400
+	llPHDRCRCValid         = 0x0800
+)
+
+func isPcapMagic(b []byte) bool {
+	m := binary.LittleEndian.Uint32(b)
+	return m == 0xa1b2c3d4 || m == 0xd4c3b2a1 || m == 0xa1b23c4d || m == 0x4d3cb2a1
+}
+
+// readPcap reads a classic libpcap file with an HCI or Bluetooth LE
+// link-layer link type
+func readPcap(r io.Reader, d *decoder) error {
+	var header [24]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return fmt.Errorf("pcap header: %w", err)
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	magic := order.Uint32(header[0:4])
+	if magic == 0xd4c3b2a1 || magic == 0x4d3cb2a1 {
+		order = binary.BigEndian
+		magic = order.Uint32(header[0:4])
+	}
+	nanos := magic == 0xa1b23c4d
+
+	linktype := order.Uint32(header[20:24])
+	switch linktype {
+	case linktypeBluetoothHCIH4, linktypeBluetoothHCIH4WithPHDR, linktypeBluetoothLinuxMonitor,
+		linktypeBluetoothLELL, linktypeBluetoothLELLWithPHDR:
+	default:
+		return fmt.Errorf("pcap: unsupported link type %d (need Bluetooth HCI H4, Linux monitor or LE link layer)", linktype)
+	}
+
+	for {
+		var rec [16]byte
+		if _, err := io.ReadFull(r, rec[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("pcap record: %w", err)
+		}
+		sec := int64(order.Uint32(rec[0:4]))
+		frac := int64(order.Uint32(rec[4:8]))
+		inclLen := order.Uint32(rec[8:12])
+		if !nanos {
+			frac *= 1000
+		}
+		ts := time.Unix(sec, frac)
+
+		pkt := make([]byte, inclLen)
+		if _, err := io.ReadFull(r, pkt); err != nil {
+			return fmt.Errorf("pcap record: %w", err)
+		}
+
+		var event []byte
+		switch linktype {
+		case linktypeBluetoothHCIH4:
+			if len(pkt) > 0 && pkt[0] == hciEventPacket {
+				event = pkt[1:]
+			}
+		case linktypeBluetoothHCIH4WithPHDR:
+			// 4-byte big-endian direction header, then H4
+			if len(pkt) > 4 && pkt[4] == hciEventPacket {
+				event = pkt[5:]
+			}
+		case linktypeBluetoothLinuxMonitor:
+			// Big-endian adapter index and opcode, then the packet
+			if len(pkt) > 4 && binary.BigEndian.Uint16(pkt[2:4]) == monitorEventPacket {
+				event = pkt[4:]
+			}
+		case linktypeBluetoothLELL:
+			d.linkLayer(pkt, 0, ts)
+		case linktypeBluetoothLELLWithPHDR:
+			// RF channel, signal power, noise power, access address
+			// offenses, reference access address and little-endian flags
+			if len(pkt) < 10 {
+				continue
+			}
+			flags := binary.LittleEndian.Uint16(pkt[8:10])
+			if flags&llPHDRCRCChecked != 0 && flags&llPHDRCRCValid == 0 {
+				continue
+			}
+			var rssi int16
+			if flags&llPHDRSignalPowerValid != 0 {
+				rssi = int16(int8(pkt[1]))
+			}
+			d.linkLayer(pkt[10:], rssi, ts)
+		}
+		if event != nil {
+			if err := d.event(event, ts); err != nil {
+				return err
+			}
+		}
+	}
+}
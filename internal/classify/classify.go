@@ -0,0 +1,346 @@
+package classify
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Device categories. Rule files may introduce others.
+const (
+	Phone      = "phone"
+	Laptop     = "laptop"
+	Headphones = "headphones"
+	Watch      = "watch"
+	Fitness    = "fitness"
+	Beacon     = "beacon"
+	Tracker    = "tracker"
+	Sensor     = "sensor"
+	Media      = "media"
+	Unknown    = "unknown"
+)
+
+// Categories lists the built-in categories in display order
+var Categories = []string{Phone, Laptop, Headphones, Watch, Fitness, Beacon, Tracker, Sensor, Media, Unknown}
+
+// rulesJSON is the built-in rule set
+//
+//go:embed rules.json
+var rulesJSON []byte
+
+// RulesFile is the on-disk format of a rule set. The same format is used for
+// the built-in rules and user rule files.
+type RulesFile struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule assigns a category with some confidence when every condition it
+// specifies matches. A list condition matches if any of its values does.
+type Rule struct {
+	Name       string  `json:"name"`
+	Category   string  `json:"category"`
+	Confidence float64 `json:"confidence"`
+
+	AppearanceCategory []string `json:"appearance_category,omitempty"` // 10-bit appearance categories ("0x001")
+	Appearance         []string `json:"appearance,omitempty"`          // Full 16-bit appearance values ("0x0083")
+	Company            []string `json:"company,omitempty"`             // Company IDs ("0x004C")
+	MfgPrefix          []string `json:"mfg_prefix,omitempty"`          // Hex prefix of manufacturer data, company ID first ("4c0007")
+	Service            []string `json:"service,omitempty"`             // 16-bit ("180d") or 128-bit service UUIDs
+	Protocol           []string `json:"protocol,omitempty"`            // Decoded protocol, intent or model
+	NamePattern        string   `json:"name_pattern,omitempty"`        // Regular expression matched against the name
+	Tracker            *bool    `json:"tracker,omitempty"`             // Whether the device is a location tracker
+
+	appearanceCategories []uint16
+	appearances          []uint16
+	companies            []uint16
+	prefixes             [][]byte
+	name                 *regexp.Regexp
+}
+
+// Input is what a device is classified from
+type Input struct {
+	Appearance       *uint16
+	CompanyID        *uint16
+	ManufacturerData []byte   // Company ID (little endian) followed by the payload
+	Services         []string // Advertised service UUIDs and service data UUIDs
+	Protocols        []string // Decoded protocols, intents and models
+	Name             string
+	Tracker          bool
+}
+
+// Result is a device's classification
+type Result struct {
+	Category   string
+	Confidence float64  // 0 to 1
+	Reasons    []string // Names of the matching rules of the category
+}
+
+// String formats the result as "phone 90%"
+func (r Result) String() string {
+	if r.Category == "" || r.Category == Unknown {
+		return Unknown
+	}
+	return fmt.Sprintf("%s %.0f%%", r.Category, r.Confidence*100)
+}
+
+var rules = mustLoadRules()
+
+func mustLoadRules() []*Rule {
+	var f RulesFile
+	if err := json.Unmarshal(rulesJSON, &f); err != nil {
+		panic(fmt.Sprintf("classify: invalid built-in rules: %v", err))
+	}
+	r, err := compileRules(nil, f.Rules)
+	if err != nil {
+		panic(fmt.Sprintf("classify: invalid built-in rules: %v", err))
+	}
+	return r
+}
+
+// LoadRules merges a user rule file on top of the built-in rules. A rule
+// with the same name as an existing one replaces it; a confidence of 0
+// disables it. It must be called before scanning starts.
+func LoadRules(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var f RulesFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	merged, err := compileRules(rules, f.Rules)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	rules = merged
+	return nil
+}
+
+// compileRules validates added rules and merges them into existing ones
+func compileRules(existing []*Rule, added []Rule) ([]*Rule, error) {
+	merged := append([]*Rule(nil), existing...)
+	for i := range added {
+		r := added[i]
+		if err := r.compile(); err != nil {
+			if r.Name != "" {
+				return nil, fmt.Errorf("rule %q: %w", r.Name, err)
+			}
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		replaced := false
+		for j, e := range merged {
+			if r.Name != "" && e.Name == r.Name {
+				merged[j] = &r
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, &r)
+		}
+	}
+	return merged, nil
+}
+
+func (r *Rule) compile() error {
+	if r.Category == "" {
+		return fmt.Errorf("missing category")
+	}
+	r.Category = strings.ToLower(r.Category)
+	if r.Confidence < 0 || r.Confidence > 1 {
+		return fmt.Errorf("confidence %v out of range 0-1", r.Confidence)
+	}
+
+	var err error
+	if r.appearanceCategories, err = parseHexList(r.AppearanceCategory, 0x3FF); err != nil {
+		return fmt.Errorf("appearance_category: %w", err)
+	}
+	if r.appearances, err = parseHexList(r.Appearance, 0xFFFF); err != nil {
+		return fmt.Errorf("appearance: %w", err)
+	}
+	if r.companies, err = parseHexList(r.Company, 0xFFFF); err != nil {
+		return fmt.Errorf("company: %w", err)
+	}
+	r.prefixes = nil
+	for _, p := range r.MfgPrefix {
+		b, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(p), "0x"))
+		if err != nil || len(b) == 0 {
+			return fmt.Errorf("mfg_prefix: invalid hex %q", p)
+		}
+		r.prefixes = append(r.prefixes, b)
+	}
+	for i, s := range r.Service {
+		r.Service[i] = strings.TrimPrefix(strings.ToLower(s), "0x")
+	}
+	if r.NamePattern != "" {
+		if r.name, err = regexp.Compile(r.NamePattern); err != nil {
+			return fmt.Errorf("name_pattern: %w", err)
+		}
+	}
+
+	// A rule that only disables a built-in one needs no conditions
+	if r.Confidence > 0 && len(r.appearanceCategories) == 0 && len(r.appearances) == 0 && len(r.companies) == 0 &&
+		len(r.prefixes) == 0 && len(r.Service) == 0 && len(r.Protocol) == 0 && r.name == nil && r.Tracker == nil {
+		return fmt.Errorf("no conditions")
+	}
+	return nil
+}
+
+func parseHexList(values []string, limit uint64) ([]uint16, error) {
+	var out []uint16
+	for _, v := range values {
+		n, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(v), "0x"), 16, 16)
+		if err != nil || n > limit {
+			return nil, fmt.Errorf("invalid value %q", v)
+		}
+		out = append(out, uint16(n))
+	}
+	return out, nil
+}
+
+// matches reports whether every condition of the rule holds for in
+func (r *Rule) matches(in Input) bool {
+	if len(r.appearanceCategories) > 0 && (in.Appearance == nil || !containsUint16(r.appearanceCategories, *in.Appearance>>6)) {
+		return false
+	}
+	if len(r.appearances) > 0 && (in.Appearance == nil || !containsUint16(r.appearances, *in.Appearance)) {
+		return false
+	}
+	if len(r.companies) > 0 && (in.CompanyID == nil || !containsUint16(r.companies, *in.CompanyID)) {
+		return false
+	}
+	if len(r.prefixes) > 0 {
+		found := false
+		for _, p := range r.prefixes {
+			if bytes.HasPrefix(in.ManufacturerData, p) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.Service) > 0 && !matchesService(r.Service, in.Services) {
+		return false
+	}
+	if len(r.Protocol) > 0 && !matchesProtocol(r.Protocol, in.Protocols) {
+		return false
+	}
+	if r.name != nil && (in.Name == "" || !r.name.MatchString(in.Name)) {
+		return false
+	}
+	if r.Tracker != nil && *r.Tracker != in.Tracker {
+		return false
+	}
+	return true
+}
+
+func containsUint16(values []uint16, v uint16) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesService compares 16-bit rule UUIDs with the short form of
+// Bluetooth Base UUIDs, and 128-bit rule UUIDs exactly
+func matchesService(want, have []string) bool {
+	for _, h := range have {
+		h = strings.ToLower(h)
+		short := h
+		if len(h) == 36 && strings.HasPrefix(h, "0000") && strings.HasSuffix(h, "-0000-1000-8000-00805f9b34fb") {
+			short = h[4:8]
+		}
+		for _, w := range want {
+			if w == h || w == short {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchesProtocol(want, have []string) bool {
+	for _, h := range have {
+		for _, w := range want {
+			if strings.EqualFold(w, h) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Classify picks the category best supported by the matching rules. The
+// confidences of several rules agreeing on a category are combined as
+// independent evidence: 1 - (1-c1)(1-c2)...
+func Classify(in Input) Result {
+	type evidence struct {
+		doubt   float64
+		reasons []string
+	}
+	byCategory := make(map[string]*evidence)
+	for _, r := range rules {
+		if r.Confidence == 0 || !r.matches(in) {
+			continue
+		}
+		e, ok := byCategory[r.Category]
+		if !ok {
+			e = &evidence{doubt: 1}
+			byCategory[r.Category] = e
+		}
+		e.doubt *= 1 - r.Confidence
+		e.reasons = append(e.reasons, r.Name)
+	}
+
+	best := Result{Category: Unknown}
+	for _, category := range sortedCategories(byCategory) {
+		e := byCategory[category]
+		if confidence := 1 - e.doubt; confidence > best.Confidence {
+			best = Result{Category: category, Confidence: confidence, Reasons: e.reasons}
+		}
+	}
+	return best
+}
+
+// sortedCategories orders categories by display order, then by name, so
+// ties are broken the same way every time
+func sortedCategories[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		oi, oj := CategoryOrder(keys[i]), CategoryOrder(keys[j])
+		if oi != oj {
+			return oi < oj
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// CategoryOrder returns the position of a category in display order;
+// categories from rule files sort after the built-in ones but before unknown
+func CategoryOrder(category string) int {
+	for i, c := range Categories {
+		if c == category {
+			if c == Unknown {
+				return len(Categories)
+			}
+			return i
+		}
+	}
+	return len(Categories) - 1
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// appName is the directory name used under the user's config directory
+const appName = "blescan"
+
+// Dir returns the blescan configuration directory
+// ($XDG_CONFIG_HOME/blescan on Linux, ~/Library/Application Support/blescan on macOS)
+func Dir() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, appName), nil
+}
+
+// Path returns the path of a file inside the configuration directory
+func Path(name string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// EnsureDir creates the configuration directory if it doesn't exist
+func EnsureDir() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
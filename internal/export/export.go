@@ -0,0 +1,23 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Filename returns a timestamped file name for an export of the given kind,
+// such as "blescan-anomalies-20240131-150405.json"
+func Filename(kind string, t time.Time) string {
+	return fmt.Sprintf("blescan-%s-%s.json", kind, t.Format("20060102-150405"))
+}
+
+// WriteJSON writes v to path as indented JSON
+func WriteJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
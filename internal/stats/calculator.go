@@ -8,8 +8,10 @@ import (
 
 // FilterConfig defines filtering criteria for devices
 type FilterConfig struct {
-	NameContains string // Case-insensitive substring match
-	MinRSSI      *int16 // Only show devices with RSSI >= this
+	NameContains string       // Case-insensitive substring match
+	MinRSSI      *int16       // Only show devices with smoothed RSSI >= this
+	Query        string       // Raw "field:value" query
+	Terms        []FilterTerm // Parsed Query
 }
 
 // MatchesFilter checks if a device matches the filter criteria
@@ -20,7 +22,10 @@ func MatchesFilter(d *ble.Device, f FilterConfig) bool {
 			return false
 		}
 	}
-	if f.MinRSSI != nil && d.RSSICurrent < *f.MinRSSI {
+	if f.MinRSSI != nil && d.RSSISmoothed < float64(*f.MinRSSI) {
+		return false
+	}
+	if !MatchesTerms(d, f.Terms) {
 		return false
 	}
 	return true
@@ -114,13 +119,19 @@ func SignalStrengthLabel(s SignalStrength) string {
 }
 
 // CalculateDeviceStats calculates statistics for a device
-func CalculateDeviceStats(d ble.Device) DeviceStats {
+func CalculateDeviceStats(d *ble.Device) DeviceStats {
 	stats := DeviceStats{
 		TimeSinceLastSeen: time.Since(d.LastSeen),
 		SignalStrength:    GetSignalStrength(d.RSSICurrent),
 	}
 
-	// Calculate advertisements per second over last 10 seconds
+	// Rate over the shortest rolling window, when the scanner provided them
+	if len(d.Windows) > 0 {
+		stats.AdvertisementsPerSecond = d.Windows[0].Rate
+		return stats
+	}
+
+	// Otherwise estimate it from the advertisements of the last 10 seconds
 	if len(d.Advertisements) > 1 {
 		cutoff := time.Now().Add(-10 * time.Second)
 		count := 0
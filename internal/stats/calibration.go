@@ -0,0 +1,116 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// Calibration is a path loss model fitted from RSSI measured at known
+// distances
+type Calibration struct {
+	MeasuredPower float64   `json:"measured_power"` // Fitted RSSI at 1 m
+	EnvFactor     float64   `json:"env_factor"`     // Fitted path loss exponent
+	RSquared      float64   `json:"r_squared"`      // Goodness of fit, 0 to 1
+	Samples       int       `json:"samples"`
+	Calibrated    time.Time `json:"calibrated"`
+}
+
+// CalibrationPoint is the RSSI collected at one distance
+type CalibrationPoint struct {
+	Meters float64
+	RSSI   []int16
+}
+
+// FitPathLoss fits RSSI = P - 10 n log10(d) to the collected readings by
+// least squares over every reading, so distances with more readings weigh
+// more. At least two distinct distances are needed.
+func FitPathLoss(points []CalibrationPoint) (Calibration, error) {
+	var n, sumX, sumY, sumXX, sumXY float64
+	distances := make(map[float64]bool)
+	for _, p := range points {
+		if p.Meters <= 0 || len(p.RSSI) == 0 {
+			continue
+		}
+		distances[p.Meters] = true
+		x := math.Log10(p.Meters)
+		for _, r := range p.RSSI {
+			y := float64(r)
+			n++
+			sumX += x
+			sumY += y
+			sumXX += x * x
+			sumXY += x * y
+		}
+	}
+	if len(distances) < 2 {
+		return Calibration{}, fmt.Errorf("need readings at two or more distances")
+	}
+
+	slope := (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+	intercept := (sumY - slope*sumX) / n
+	if slope >= 0 {
+		return Calibration{}, fmt.Errorf("RSSI doesn't fall with distance (slope %.1f dB/decade)", slope)
+	}
+
+	// Coefficient of determination
+	mean := sumY / n
+	var ssTot, ssRes float64
+	for _, p := range points {
+		if p.Meters <= 0 {
+			continue
+		}
+		predicted := intercept + slope*math.Log10(p.Meters)
+		for _, r := range p.RSSI {
+			y := float64(r)
+			ssTot += (y - mean) * (y - mean)
+			ssRes += (y - predicted) * (y - predicted)
+		}
+	}
+	rSquared := 1.0
+	if ssTot > 0 {
+		rSquared = 1 - ssRes/ssTot
+	}
+
+	return Calibration{
+		MeasuredPower: intercept,
+		EnvFactor:     -slope / 10,
+		RSquared:      rSquared,
+		Samples:       int(n),
+		Calibrated:    time.Now(),
+	}, nil
+}
+
+// SavePathLossModel writes a model to a JSON file
+func SavePathLossModel(path string, m PathLossModel) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// WithDeviceCalibration returns a copy of the model using c for one device
+func (m PathLossModel) WithDeviceCalibration(id string, c Calibration) PathLossModel {
+	m.Devices = copyCalibrations(m.Devices)
+	m.Devices[id] = c
+	return m
+}
+
+// WithTypeCalibration returns a copy of the model using c for every device
+// of a type
+func (m PathLossModel) WithTypeCalibration(category string, c Calibration) PathLossModel {
+	m.Types = copyCalibrations(m.Types)
+	m.Types[category] = c
+	return m
+}
+
+func copyCalibrations(src map[string]Calibration) map[string]Calibration {
+	dst := make(map[string]Calibration, len(src)+1)
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
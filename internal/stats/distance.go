@@ -0,0 +1,234 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/buckleypaul/blescan/internal/ble"
+)
+
+// Distance estimation with the log-distance path loss model:
+//
+//	RSSI = P - 10 n log10(d)
+//
+// where P is the RSSI measured at 1 m and n the environment factor (2 in
+// free space, up to ~4 indoors with obstructions).
+
+const (
+	// DefaultEnvFactor is the path loss exponent for open indoor spaces
+	DefaultEnvFactor = 2.0
+	// DefaultMeasuredPower is a typical phone-class RSSI at 1 m
+	DefaultMeasuredPower = -59
+	// defaultShadowing is the spread of real RSSI around the model, in dB
+	defaultShadowing = 4.0
+	// txPowerPathLoss converts transmit power to RSSI at 1 m (free-space
+	// loss at 2.4 GHz over 1 m)
+	txPowerPathLoss = 41
+	// minDistanceSamples is how many RSSI readings an estimate needs
+	minDistanceSamples = 3
+)
+
+// PathLossModel holds the distance estimation parameters
+type PathLossModel struct {
+	EnvFactor     float64                `json:"env_factor"`               // Path loss exponent n
+	MeasuredPower map[string]int         `json:"measured_power,omitempty"` // RSSI at 1 m per device type (see the Type column)
+	Shadowing     float64                `json:"shadowing,omitempty"`      // Spread of RSSI around the model, in dB
+	Devices       map[string]Calibration `json:"devices,omitempty"`        // Calibrated models per device ID
+	Types         map[string]Calibration `json:"types,omitempty"`          // Calibrated models per device type
+}
+
+// DefaultPathLossModel returns the model used when nothing is configured
+func DefaultPathLossModel() PathLossModel {
+	return PathLossModel{EnvFactor: DefaultEnvFactor, Shadowing: defaultShadowing}
+}
+
+var pathLoss = DefaultPathLossModel()
+
+// SetPathLossModel replaces the model used by EstimateDistance. It must be
+// called before the UI starts.
+func SetPathLossModel(m PathLossModel) {
+	if m.EnvFactor <= 0 {
+		m.EnvFactor = DefaultEnvFactor
+	}
+	if m.Shadowing <= 0 {
+		m.Shadowing = defaultShadowing
+	}
+	pathLoss = m
+}
+
+// CurrentPathLossModel returns the model used by EstimateDistance
+func CurrentPathLossModel() PathLossModel {
+	return pathLoss
+}
+
+// LoadPathLossModel reads a model from a JSON file. Missing fields keep
+// their defaults.
+func LoadPathLossModel(path string) (PathLossModel, error) {
+	m := DefaultPathLossModel()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return m, fmt.Errorf("%s: %w", path, err)
+	}
+	if m.EnvFactor <= 0 {
+		return m, fmt.Errorf("%s: env_factor must be positive", path)
+	}
+	return m, nil
+}
+
+// Proximity is a coarse distance band
+type Proximity int
+
+const (
+	ProximityUnknown   Proximity = iota
+	ProximityImmediate           // Under 0.5 m
+	ProximityNear                // 0.5 to 3 m
+	ProximityFar                 // Over 3 m
+)
+
+func (p Proximity) String() string {
+	switch p {
+	case ProximityImmediate:
+		return "immediate"
+	case ProximityNear:
+		return "near"
+	case ProximityFar:
+		return "far"
+	default:
+		return "unknown"
+	}
+}
+
+// DistanceEstimate is a distance with its uncertainty range
+type DistanceEstimate struct {
+	Meters        float64
+	Low           float64 // One standard deviation closer
+	High          float64 // One standard deviation further
+	RSSI          float64 // Smoothed RSSI the estimate is based on
+	MeasuredPower float64 // RSSI at 1 m used
+	EnvFactor     float64 // Path loss exponent used
+	PowerSource   string  // Where MeasuredPower came from
+	Proximity     Proximity
+}
+
+// String formats the estimate as "2.3 m (1.4-3.8)"
+func (e DistanceEstimate) String() string {
+	return fmt.Sprintf("%s m (%s-%s)", formatRange(e.Meters), formatRange(e.Low), formatRange(e.High))
+}
+
+func formatRange(m float64) string {
+	if m < 10 {
+		return fmt.Sprintf("%.1f", m)
+	}
+	return fmt.Sprintf("%.0f", m)
+}
+
+// EstimateDistance estimates how far away a device is from its smoothed RSSI
+func EstimateDistance(d *ble.Device) (DistanceEstimate, bool) {
+	return pathLoss.Estimate(d)
+}
+
+// Estimate estimates how far away a device is. Single readings swing by
+// 10 dB or more, so the estimate is based on the output of the configured
+// smoothing filter and its range widens with the spread of recent readings.
+func (m PathLossModel) Estimate(d *ble.Device) (DistanceEstimate, bool) {
+	if len(d.RSSIHistory) < minDistanceSamples {
+		return DistanceEstimate{}, false
+	}
+
+	rssi := d.RSSISmoothed
+	spread := stddev(d.RSSIHistory)
+	power, n, source := m.parameters(d)
+
+	// Model error plus the uncertainty of the smoothed reading
+	sigma := math.Sqrt(m.Shadowing*m.Shadowing + spread*spread/float64(len(d.RSSIHistory)))
+
+	e := DistanceEstimate{
+		Meters:        distance(power, n, rssi),
+		Low:           distance(power, n, rssi+sigma),
+		High:          distance(power, n, rssi-sigma),
+		RSSI:          rssi,
+		MeasuredPower: power,
+		EnvFactor:     n,
+		PowerSource:   source,
+	}
+	switch {
+	case e.Meters < 0.5:
+		e.Proximity = ProximityImmediate
+	case e.Meters < 3:
+		e.Proximity = ProximityNear
+	default:
+		e.Proximity = ProximityFar
+	}
+	return e, true
+}
+
+func distance(power, n, rssi float64) float64 {
+	return math.Pow(10, (power-rssi)/(10*n))
+}
+
+// parameters picks the RSSI at 1 m and path loss exponent: a calibration of
+// the device or its type, then a configured value for the type, then
+// calibration the device advertises itself, then a default
+func (m PathLossModel) parameters(d *ble.Device) (float64, float64, string) {
+	if c, ok := m.Devices[d.ID]; ok {
+		return c.MeasuredPower, c.EnvFactor, "calibrated for this device"
+	}
+	if c, ok := m.Types[d.Class.Category]; ok && d.Class.Category != "" {
+		return c.MeasuredPower, c.EnvFactor, "calibrated for " + d.Class.Category
+	}
+	if p, ok := m.MeasuredPower[d.Class.Category]; ok && d.Class.Category != "" {
+		return float64(p), m.EnvFactor, "configured for " + d.Class.Category
+	}
+	if p, ok := iBeaconMeasuredPower(d.ManufacturerData); ok {
+		return float64(p), m.EnvFactor, "iBeacon calibration"
+	}
+	if p, ok := eddystoneMeasuredPower(d.ServiceData); ok {
+		return float64(p), m.EnvFactor, "Eddystone calibration"
+	}
+	if d.TxPowerLevel != nil {
+		return float64(int(*d.TxPowerLevel) - txPowerPathLoss), m.EnvFactor, "TX power level"
+	}
+	return DefaultMeasuredPower, m.EnvFactor, "default"
+}
+
+// iBeaconMeasuredPower reads the calibrated RSSI at 1 m from an iBeacon:
+// Apple company ID, type 0x02, length 0x15, UUID, major, minor, power
+func iBeaconMeasuredPower(mfg []byte) (int, bool) {
+	if len(mfg) != 25 || mfg[0] != 0x4C || mfg[1] != 0x00 || mfg[2] != 0x02 || mfg[3] != 0x15 {
+		return 0, false
+	}
+	return int(int8(mfg[24])), true
+}
+
+// eddystoneMeasuredPower reads the ranging data of an Eddystone-UID or -URL
+// frame, calibrated at 0 m
+func eddystoneMeasuredPower(serviceData map[string][]byte) (int, bool) {
+	for uuid, data := range serviceData {
+		if short, ok := ble.ShortUUID(uuid); !ok || short != 0xFEAA {
+			continue
+		}
+		if len(data) >= 2 && (data[0] == 0x00 || data[0] == 0x10) {
+			return int(int8(data[1])) - txPowerPathLoss, true
+		}
+	}
+	return 0, false
+}
+
+// stddev returns the standard deviation of readings
+func stddev(values []int16) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += float64(v)
+	}
+	mean := sum / float64(len(values))
+	var variance float64
+	for _, v := range values {
+		variance += (float64(v) - mean) * (float64(v) - mean)
+	}
+	return math.Sqrt(variance / float64(len(values)))
+}
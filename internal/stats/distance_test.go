@@ -0,0 +1,181 @@
+package stats
+
+import (
+	"math"
+	"testing"
+
+	"github.com/buckleypaul/blescan/internal/ble"
+	"github.com/buckleypaul/blescan/internal/classify"
+)
+
+// steadyDevice returns a device whose smoothed RSSI is rssi, with a few
+// readings around it
+func steadyDevice(id string, rssi float64) *ble.Device {
+	r := int16(rssi)
+	return &ble.Device{
+		ID:           id,
+		RSSIHistory:  []int16{r - 2, r, r + 2, r},
+		RSSISmoothed: rssi,
+	}
+}
+
+func TestEstimateDistance(t *testing.T) {
+	iBeacon := append([]byte{0x4C, 0x00, 0x02, 0x15}, make([]byte, 20)...)
+	iBeacon = append(iBeacon, 0xBF) // -65 dBm at 1 m
+	txPower := int8(0)
+
+	model := DefaultPathLossModel()
+	model.MeasuredPower = map[string]int{"phone": -50}
+	model.Devices = map[string]Calibration{"calibrated": {MeasuredPower: -70, EnvFactor: 3}}
+	model.Types = map[string]Calibration{"tracker": {MeasuredPower: -60, EnvFactor: 2.5}}
+
+	tests := []struct {
+		name      string
+		device    func() *ble.Device
+		ok        bool
+		meters    float64
+		source    string
+		proximity Proximity
+	}{
+		{
+			name:      "default measured power",
+			device:    func() *ble.Device { return steadyDevice("a", -59) },
+			ok:        true,
+			meters:    1,
+			source:    "default",
+			proximity: ProximityNear,
+		},
+		{
+			name:      "20 dB weaker is ten times further",
+			device:    func() *ble.Device { return steadyDevice("a", -79) },
+			ok:        true,
+			meters:    10,
+			source:    "default",
+			proximity: ProximityFar,
+		},
+		{
+			name:      "immediate",
+			device:    func() *ble.Device { return steadyDevice("a", -40) },
+			ok:        true,
+			meters:    math.Pow(10, -19.0/20),
+			source:    "default",
+			proximity: ProximityImmediate,
+		},
+		{
+			name: "iBeacon calibration",
+			device: func() *ble.Device {
+				d := steadyDevice("a", -65)
+				d.ManufacturerData = iBeacon
+				return d
+			},
+			ok:        true,
+			meters:    1,
+			source:    "iBeacon calibration",
+			proximity: ProximityNear,
+		},
+		{
+			name: "Eddystone calibration at 0 m",
+			device: func() *ble.Device {
+				d := steadyDevice("a", -59)
+				d.ServiceData = map[string][]byte{"0000feaa-0000-1000-8000-00805f9b34fb": {0x00, 0xEE}}
+				return d
+			},
+			ok:        true,
+			meters:    1,
+			source:    "Eddystone calibration",
+			proximity: ProximityNear,
+		},
+		{
+			name: "TX power level",
+			device: func() *ble.Device {
+				d := steadyDevice("a", -41)
+				d.TxPowerLevel = &txPower
+				return d
+			},
+			ok:        true,
+			meters:    1,
+			source:    "TX power level",
+			proximity: ProximityNear,
+		},
+		{
+			name: "configured for the device type",
+			device: func() *ble.Device {
+				d := steadyDevice("a", -70)
+				d.Class = classify.Result{Category: "phone"}
+				d.TxPowerLevel = &txPower
+				return d
+			},
+			ok:        true,
+			meters:    10,
+			source:    "configured for phone",
+			proximity: ProximityFar,
+		},
+		{
+			name: "calibrated for the device type",
+			device: func() *ble.Device {
+				d := steadyDevice("a", -85)
+				d.Class = classify.Result{Category: "tracker"}
+				return d
+			},
+			ok:        true,
+			meters:    10,
+			source:    "calibrated for tracker",
+			proximity: ProximityFar,
+		},
+		{
+			name: "calibrated for the device",
+			device: func() *ble.Device {
+				d := steadyDevice("calibrated", -100)
+				d.Class = classify.Result{Category: "phone"}
+				return d
+			},
+			ok:        true,
+			meters:    10,
+			source:    "calibrated for this device",
+			proximity: ProximityFar,
+		},
+		{
+			name: "too few readings",
+			device: func() *ble.Device {
+				d := steadyDevice("a", -59)
+				d.RSSIHistory = d.RSSIHistory[:minDistanceSamples-1]
+				return d
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, ok := model.Estimate(tt.device())
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if math.Abs(e.Meters-tt.meters) > 0.01*tt.meters {
+				t.Errorf("distance = %.2f m, want %.2f m", e.Meters, tt.meters)
+			}
+			if e.PowerSource != tt.source || e.Proximity != tt.proximity {
+				t.Errorf("source = %q, proximity = %s, want %q and %s", e.PowerSource, e.Proximity, tt.source, tt.proximity)
+			}
+			if !(e.Low < e.Meters && e.Meters < e.High) {
+				t.Errorf("range %.2f-%.2f doesn't contain %.2f", e.Low, e.High, e.Meters)
+			}
+		})
+	}
+}
+
+func TestDistanceEstimateString(t *testing.T) {
+	tests := []struct {
+		e    DistanceEstimate
+		want string
+	}{
+		{DistanceEstimate{Meters: 2.34, Low: 1.41, High: 3.86}, "2.3 m (1.4-3.9)"},
+		{DistanceEstimate{Meters: 12.4, Low: 7.6, High: 20.2}, "12 m (7.6-20)"},
+	}
+	for _, tt := range tests {
+		if got := tt.e.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
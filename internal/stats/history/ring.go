@@ -0,0 +1,103 @@
+// Package history keeps a device's long-term history in bounded memory: ring
+// buffers of raw values, and aggregates per second and per minute that
+// outlive the raw values by hours or days.
+package history
+
+// Ring is a bounded buffer that overwrites its oldest element once full. Its
+// storage grows as elements arrive, so a ring that only ever sees a few costs
+// little, and stops allocating once it reaches capacity.
+type Ring[T any] struct {
+	buf      []T
+	start    int // Index of the oldest element, once full
+	capacity int
+}
+
+// NewRing returns an empty ring holding up to capacity elements
+func NewRing[T any](capacity int) *Ring[T] {
+	return &Ring[T]{capacity: max(1, capacity)}
+}
+
+// Cap returns the most elements the ring holds
+func (r *Ring[T]) Cap() int {
+	if r == nil {
+		return 0
+	}
+	return r.capacity
+}
+
+// Len returns the number of elements in the ring
+func (r *Ring[T]) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.buf)
+}
+
+// Push adds v as the newest element, dropping the oldest if the ring is full
+func (r *Ring[T]) Push(v T) {
+	if len(r.buf) < r.capacity {
+		r.grow(len(r.buf) + 1)
+		r.buf = append(r.buf, v)
+		return
+	}
+	r.buf[r.start] = v
+	r.start = (r.start + 1) % len(r.buf)
+}
+
+// grow makes room for n elements, doubling the storage but never past the
+// ring's capacity
+func (r *Ring[T]) grow(n int) {
+	if n <= cap(r.buf) {
+		return
+	}
+	buf := make([]T, len(r.buf), min(r.capacity, max(n, 2*cap(r.buf), 8)))
+	copy(buf, r.buf)
+	r.buf = buf
+}
+
+// At returns the i-th element, oldest first
+func (r *Ring[T]) At(i int) T {
+	return r.buf[(r.start+i)%len(r.buf)]
+}
+
+// Ptr returns a pointer to the i-th element, oldest first, for updating it
+// in place
+func (r *Ring[T]) Ptr(i int) *T {
+	return &r.buf[(r.start+i)%len(r.buf)]
+}
+
+// Last returns the newest element
+func (r *Ring[T]) Last() (T, bool) {
+	if r.Len() == 0 {
+		var zero T
+		return zero, false
+	}
+	return r.At(len(r.buf) - 1), true
+}
+
+// Tail returns a copy of the newest n elements, oldest first
+func (r *Ring[T]) Tail(n int) []T {
+	n = min(n, r.Len())
+	out := make([]T, n)
+	for i := range out {
+		out[i] = r.At(len(r.buf) - n + i)
+	}
+	return out
+}
+
+// Slice returns a copy of every element, oldest first
+func (r *Ring[T]) Slice() []T {
+	return r.Tail(r.Len())
+}
+
+// Reset replaces the contents with the newest capacity elements of values,
+// which must be oldest first
+func (r *Ring[T]) Reset(values []T) {
+	if len(values) > r.capacity {
+		values = values[len(values)-r.capacity:]
+	}
+	r.start = 0
+	r.buf = r.buf[:0]
+	r.grow(len(values))
+	r.buf = append(r.buf, values...)
+}
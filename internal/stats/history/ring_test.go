@@ -0,0 +1,94 @@
+package history
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRing(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		push     []int
+		want     []int
+		tail     []int // Tail(2)
+	}{
+		{name: "empty", capacity: 3, want: []int{}, tail: []int{}},
+		{name: "partly full", capacity: 3, push: []int{1, 2}, want: []int{1, 2}, tail: []int{1, 2}},
+		{name: "full", capacity: 3, push: []int{1, 2, 3}, want: []int{1, 2, 3}, tail: []int{2, 3}},
+		{name: "wrapped", capacity: 3, push: []int{1, 2, 3, 4, 5}, want: []int{3, 4, 5}, tail: []int{4, 5}},
+		{name: "zero capacity holds one", capacity: 0, push: []int{1, 2}, want: []int{2}, tail: []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRing[int](tt.capacity)
+			for _, v := range tt.push {
+				r.Push(v)
+			}
+			if got := r.Slice(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Slice() = %v, want %v", got, tt.want)
+			}
+			if got := r.Tail(2); !reflect.DeepEqual(got, tt.tail) {
+				t.Errorf("Tail(2) = %v, want %v", got, tt.tail)
+			}
+			last, ok := r.Last()
+			if ok != (len(tt.want) > 0) || (ok && last != tt.want[len(tt.want)-1]) {
+				t.Errorf("Last() = %v, %v", last, ok)
+			}
+			if cap(r.buf) > r.Cap() {
+				t.Errorf("storage of %d exceeds capacity %d", cap(r.buf), r.Cap())
+			}
+		})
+	}
+}
+
+func TestRingGrowsOnDemand(t *testing.T) {
+	r := NewRing[int](1000)
+	r.Push(1)
+	if cap(r.buf) > 8 {
+		t.Errorf("one element allocated storage for %d", cap(r.buf))
+	}
+	for i := 0; i < 2000; i++ {
+		r.Push(i)
+	}
+	if r.Len() != 1000 || cap(r.buf) != 1000 || r.At(0) != 1000 {
+		t.Errorf("len = %d, storage = %d, oldest = %d", r.Len(), cap(r.buf), r.At(0))
+	}
+	*r.Ptr(0) = -1
+	if r.At(0) != -1 {
+		t.Error("Ptr doesn't point into the ring")
+	}
+
+	var nilRing *Ring[int]
+	if nilRing.Len() != 0 || nilRing.Cap() != 0 {
+		t.Error("nil ring isn't empty")
+	}
+}
+
+func TestRingReset(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []int
+		want   []int
+	}{
+		{name: "fewer than capacity", values: []int{1, 2}, want: []int{1, 2}},
+		{name: "more than capacity keeps the newest", values: []int{1, 2, 3, 4, 5}, want: []int{3, 4, 5}},
+		{name: "nothing", values: nil, want: []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRing[int](3)
+			for _, v := range []int{7, 8, 9, 10} {
+				r.Push(v)
+			}
+			r.Reset(tt.values)
+			if got := r.Slice(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Slice() = %v, want %v", got, tt.want)
+			}
+			r.Push(6)
+			if last, _ := r.Last(); last != 6 {
+				t.Errorf("Push after Reset: last = %d", last)
+			}
+		})
+	}
+}
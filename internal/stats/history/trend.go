@@ -0,0 +1,187 @@
+package history
+
+import (
+	"bytes"
+	"sort"
+	"time"
+)
+
+// Point aggregates the advertisements of one period
+type Point struct {
+	Start   time.Time
+	Adverts int
+
+	RSSIMin int16
+	RSSIMax int16
+	RSSISum float64
+
+	// Payload changes within the period, counting the first advertisement
+	// if its payload differs from the period before
+	PayloadChanges int
+	// Payload of the last advertisement of the period, for decoding fields
+	ManufacturerData []byte
+	ServiceData      map[string][]byte
+}
+
+// RSSIMean returns the mean RSSI over the period
+func (p Point) RSSIMean() float64 {
+	if p.Adverts == 0 {
+		return 0
+	}
+	return p.RSSISum / float64(p.Adverts)
+}
+
+// merge adds another point of the same period to p, keeping the payload of
+// whichever is newer
+func (p *Point) merge(o Point, oNewer bool) {
+	if o.Adverts == 0 {
+		return
+	}
+	if p.Adverts == 0 || o.RSSIMin < p.RSSIMin {
+		p.RSSIMin = o.RSSIMin
+	}
+	if p.Adverts == 0 || o.RSSIMax > p.RSSIMax {
+		p.RSSIMax = o.RSSIMax
+	}
+	p.Adverts += o.Adverts
+	p.RSSISum += o.RSSISum
+	p.PayloadChanges += o.PayloadChanges
+	if oNewer {
+		p.ManufacturerData = o.ManufacturerData
+		p.ServiceData = o.ServiceData
+	}
+}
+
+// tier is a ring of points of one period length
+type tier struct {
+	period time.Duration
+	points *Ring[Point]
+}
+
+func (t *tier) add(ts time.Time, rssi int16, changed bool, mfr []byte, svc map[string][]byte) {
+	start := ts.Truncate(t.period)
+	last, ok := t.points.Last()
+	if !ok || start.After(last.Start) {
+		t.points.Push(Point{Start: start, RSSIMin: rssi, RSSIMax: rssi})
+	}
+	// Late advertisements count toward the newest period
+	p := t.points.Ptr(t.points.Len() - 1)
+	if rssi < p.RSSIMin {
+		p.RSSIMin = rssi
+	}
+	if rssi > p.RSSIMax {
+		p.RSSIMax = rssi
+	}
+	p.Adverts++
+	p.RSSISum += float64(rssi)
+	if changed {
+		p.PayloadChanges++
+	}
+	p.ManufacturerData = mfr
+	p.ServiceData = svc
+}
+
+// merge combines another tier of the same period into t
+func (t *tier) merge(o *tier) {
+	points := append(t.points.Slice(), o.points.Slice()...)
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Start.Before(points[j].Start) })
+	var merged []Point
+	for _, p := range points {
+		if n := len(merged); n > 0 && merged[n-1].Start.Equal(p.Start) {
+			merged[n-1].merge(p, true)
+			continue
+		}
+		merged = append(merged, p)
+	}
+	t.points.Reset(merged)
+}
+
+// Trend keeps per-second and per-minute aggregates of a device's
+// advertisements
+type Trend struct {
+	seconds tier
+	minutes tier
+
+	lastMfr []byte
+	lastSvc map[string][]byte
+	started bool
+}
+
+// NewTrend returns an empty Trend keeping up to seconds per-second and
+// minutes per-minute points
+func NewTrend(seconds, minutes int) *Trend {
+	return &Trend{
+		seconds: tier{period: time.Second, points: NewRing[Point](seconds)},
+		minutes: tier{period: time.Minute, points: NewRing[Point](minutes)},
+	}
+}
+
+// Add records an advertisement received at t with its payloads. The
+// payload slices are kept, not copied; while the payload doesn't change,
+// points share the first advertisement's slices.
+func (tr *Trend) Add(t time.Time, rssi int16, mfr []byte, svc map[string][]byte) {
+	changed := tr.started && !samePayload(tr.lastMfr, tr.lastSvc, mfr, svc)
+	if tr.started && !changed {
+		mfr, svc = tr.lastMfr, tr.lastSvc
+	}
+	tr.started = true
+	tr.lastMfr, tr.lastSvc = mfr, svc
+	tr.seconds.add(t, rssi, changed, mfr, svc)
+	tr.minutes.add(t, rssi, changed, mfr, svc)
+}
+
+// Seconds returns the per-second points, oldest first
+func (tr *Trend) Seconds() []Point {
+	return tr.seconds.points.Slice()
+}
+
+// Minutes returns the per-minute points, oldest first
+func (tr *Trend) Minutes() []Point {
+	return tr.minutes.points.Slice()
+}
+
+// Merge adds another Trend's points to tr, for devices found to be the same
+// transmitter
+func (tr *Trend) Merge(o *Trend) {
+	tr.seconds.merge(&o.seconds)
+	tr.minutes.merge(&o.minutes)
+	if !tr.started {
+		tr.lastMfr, tr.lastSvc, tr.started = o.lastMfr, o.lastSvc, o.started
+	}
+}
+
+func samePayload(mfrA []byte, svcA map[string][]byte, mfrB []byte, svcB map[string][]byte) bool {
+	if !bytes.Equal(mfrA, mfrB) || len(svcA) != len(svcB) {
+		return false
+	}
+	for uuid, data := range svcA {
+		other, ok := svcB[uuid]
+		if !ok || !bytes.Equal(data, other) {
+			return false
+		}
+	}
+	return true
+}
+
+// Resample merges points of the given period into at most columns points
+// spanning from the first point to the last, for charting. Columns no
+// advertisement fell into have no adverts. It also returns the period each
+// column covers.
+func Resample(points []Point, period time.Duration, columns int) ([]Point, time.Duration) {
+	if len(points) == 0 || columns < 1 {
+		return nil, period
+	}
+	start := points[0].Start
+	periods := int(points[len(points)-1].Start.Sub(start)/period) + 1
+	perColumn := (periods + columns - 1) / columns
+	out := make([]Point, (periods+perColumn-1)/perColumn)
+	width := time.Duration(perColumn) * period
+	for i := range out {
+		out[i].Start = start.Add(time.Duration(i) * width)
+	}
+	for _, p := range points {
+		i := int(p.Start.Sub(start) / width)
+		out[i].merge(p, true)
+	}
+	return out, width
+}
@@ -0,0 +1,161 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+// advert is one advertisement fed to a Trend
+type advert struct {
+	at   time.Duration // After the start of a minute
+	rssi int16
+	mfr  []byte
+}
+
+func trendOf(start time.Time, adverts ...advert) *Trend {
+	tr := NewTrend(100, 10)
+	for _, a := range adverts {
+		tr.Add(start.Add(a.at), a.rssi, a.mfr, nil)
+	}
+	return tr
+}
+
+func TestTrend(t *testing.T) {
+	start := time.Unix(1700000040, 0) // On a minute boundary
+	payload := []byte{0x4C, 0x00, 0x01}
+	changed := []byte{0x4C, 0x00, 0x02}
+	tests := []struct {
+		name    string
+		adverts []advert
+		seconds []int // Adverts per second point
+		changes []int // Payload changes per second point
+		minutes []int // Adverts per minute point
+	}{
+		{
+			name:    "one second",
+			adverts: []advert{{0, -60, payload}, {300 * time.Millisecond, -70, payload}},
+			seconds: []int{2},
+			changes: []int{0},
+			minutes: []int{2},
+		},
+		{
+			name: "payload changes",
+			adverts: []advert{
+				{0, -60, payload}, {100 * time.Millisecond, -60, changed},
+				{time.Second, -60, payload}, {1100 * time.Millisecond, -60, payload},
+			},
+			seconds: []int{2, 2},
+			changes: []int{1, 1},
+			minutes: []int{4},
+		},
+		{
+			name:    "quiet seconds leave no points",
+			adverts: []advert{{0, -60, payload}, {5 * time.Second, -60, payload}, {70 * time.Second, -60, payload}},
+			seconds: []int{1, 1, 1},
+			changes: []int{0, 0, 0},
+			minutes: []int{2, 1},
+		},
+		{
+			name:    "late advertisement counts toward the newest period",
+			adverts: []advert{{2 * time.Second, -60, payload}, {time.Second, -60, payload}},
+			seconds: []int{2},
+			changes: []int{0},
+			minutes: []int{2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := trendOf(start, tt.adverts...)
+			seconds := tr.Seconds()
+			if len(seconds) != len(tt.seconds) {
+				t.Fatalf("second points = %d, want %d", len(seconds), len(tt.seconds))
+			}
+			for i, p := range seconds {
+				if p.Adverts != tt.seconds[i] || p.PayloadChanges != tt.changes[i] {
+					t.Errorf("second %d: adverts = %d, changes = %d, want %d and %d", i, p.Adverts, p.PayloadChanges, tt.seconds[i], tt.changes[i])
+				}
+			}
+			minutes := tr.Minutes()
+			if len(minutes) != len(tt.minutes) {
+				t.Fatalf("minute points = %d, want %d", len(minutes), len(tt.minutes))
+			}
+			for i, p := range minutes {
+				if p.Adverts != tt.minutes[i] {
+					t.Errorf("minute %d: adverts = %d, want %d", i, p.Adverts, tt.minutes[i])
+				}
+			}
+		})
+	}
+
+	tr := trendOf(start, advert{0, -60, payload}, advert{100 * time.Millisecond, -70, payload})
+	p := tr.Seconds()[0]
+	if p.RSSIMin != -70 || p.RSSIMax != -60 || p.RSSIMean() != -65 {
+		t.Errorf("RSSI min = %d, max = %d, mean = %v", p.RSSIMin, p.RSSIMax, p.RSSIMean())
+	}
+}
+
+func TestTrendMerge(t *testing.T) {
+	start := time.Unix(1700000040, 0)
+	a := trendOf(start, advert{0, -60, nil}, advert{2 * time.Second, -60, nil})
+	b := trendOf(start, advert{500 * time.Millisecond, -80, nil}, advert{time.Second, -80, nil})
+	a.Merge(b)
+
+	want := []int{2, 1, 1}
+	seconds := a.Seconds()
+	if len(seconds) != len(want) {
+		t.Fatalf("second points = %d, want %d", len(seconds), len(want))
+	}
+	for i, p := range seconds {
+		if p.Adverts != want[i] {
+			t.Errorf("second %d: adverts = %d, want %d", i, p.Adverts, want[i])
+		}
+	}
+	if p := seconds[0]; p.RSSIMin != -80 || p.RSSIMax != -60 {
+		t.Errorf("merged RSSI range = %d to %d, want -80 to -60", p.RSSIMin, p.RSSIMax)
+	}
+	if m := a.Minutes(); len(m) != 1 || m[0].Adverts != 4 {
+		t.Errorf("minute points = %+v, want one of 4 adverts", m)
+	}
+}
+
+func TestResample(t *testing.T) {
+	start := time.Unix(1700000040, 0)
+	points := func(seconds ...int) []Point {
+		var out []Point
+		for _, s := range seconds {
+			out = append(out, Point{Start: start.Add(time.Duration(s) * time.Second), Adverts: 1, RSSIMin: -60, RSSIMax: -60, RSSISum: -60})
+		}
+		return out
+	}
+	tests := []struct {
+		name    string
+		points  []Point
+		columns int
+		width   time.Duration
+		adverts []int
+	}{
+		{name: "no points", points: nil, columns: 10, width: time.Second},
+		{name: "fewer periods than columns", points: points(0, 1, 3), columns: 10, width: time.Second, adverts: []int{1, 1, 0, 1}},
+		{name: "two periods per column", points: points(0, 1, 2, 5), columns: 3, width: 2 * time.Second, adverts: []int{2, 1, 1}},
+		{name: "one column", points: points(0, 9), columns: 1, width: 10 * time.Second, adverts: []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, width := Resample(tt.points, time.Second, tt.columns)
+			if width != tt.width {
+				t.Errorf("column width = %v, want %v", width, tt.width)
+			}
+			if len(out) != len(tt.adverts) {
+				t.Fatalf("columns = %d, want %d", len(out), len(tt.adverts))
+			}
+			for i, p := range out {
+				if p.Adverts != tt.adverts[i] {
+					t.Errorf("column %d: adverts = %d, want %d", i, p.Adverts, tt.adverts[i])
+				}
+				if want := start.Add(time.Duration(i) * tt.width); !p.Start.Equal(want) {
+					t.Errorf("column %d starts at %v, want %v", i, p.Start, want)
+				}
+			}
+		})
+	}
+}
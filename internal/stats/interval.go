@@ -0,0 +1,386 @@
+package stats
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Advertising interval analysis. A BLE advertiser transmits every
+// advInterval plus a random advDelay of 0 to 10 ms, so the gap between two
+// received advertisements is a whole number of those periods: one when
+// nothing was missed, more when the scanner was listening elsewhere.
+
+const (
+	// Gaps outside this range aren't advertising intervals
+	minIntervalGap = 10 * time.Millisecond
+	maxIntervalGap = 10 * time.Second
+
+	// Highest multiple of the base interval treated as missed advertisements
+	maxIntervalMultiple = 8
+	// Fewest gaps an analysis needs
+	minIntervalGaps = 10
+	// Gaps per segment when looking for interval changes
+	phaseSegment = 10
+	// Fewest gaps in a phase
+	minPhaseGaps = 30
+	// Consecutive segments whose intervals differ by less than this ratio
+	// belong to the same phase
+	phaseRatio = 1.3
+
+	// Mean of the 0 to 10 ms advDelay added to every advertising event
+	meanAdvDelay = 5 * time.Millisecond
+	// advInterval is a multiple of this
+	advIntervalUnit = 625 * time.Microsecond
+)
+
+// IntervalAnalysis describes how a device spaces its advertisements
+type IntervalAnalysis struct {
+	Gaps []time.Duration // Inter-arrival times, oldest first
+
+	Base       time.Duration // Fundamental advertising period, including advDelay
+	Configured time.Duration // Likely configured advInterval: Base less the mean advDelay
+	Explained  float64       // Fraction of gaps that are whole multiples of Base
+	Missed     int           // Advertisements estimated missed between received ones
+	Loss       float64       // Missed as a fraction of all advertisements sent
+
+	Jitter      time.Duration // Standard deviation of single-interval gaps
+	DelaySpread time.Duration // Estimated advDelay range; the spec allows 0 to 10 ms
+
+	Phases []IntervalPhase // Steady stretches, oldest first; several mean the interval switched
+	Others []time.Duration // Gap clusters that aren't multiples of Base: other advertising sets or transmitters
+}
+
+// IntervalPhase is a stretch of advertising at a steady interval
+type IntervalPhase struct {
+	Start    time.Time
+	End      time.Time
+	Interval time.Duration
+	Gaps     int
+}
+
+// Switched reports whether the device changed its advertising interval
+func (a IntervalAnalysis) Switched() bool {
+	return len(a.Phases) > 1
+}
+
+// MultipleSets reports whether gaps that don't fit the base interval suggest
+// more than one advertising set
+func (a IntervalAnalysis) MultipleSets() bool {
+	return len(a.Others) > 0 || a.Explained < 0.85
+}
+
+// AnalyzeIntervals analyzes the gaps between advertisement arrival times.
+// The base interval and its statistics describe the most recent phase.
+func AnalyzeIntervals(arrivals []time.Time) (IntervalAnalysis, bool) {
+	var a IntervalAnalysis
+	var starts []time.Time // Arrival that ends each gap
+	for i := 1; i < len(arrivals); i++ {
+		gap := arrivals[i].Sub(arrivals[i-1])
+		if gap >= minIntervalGap && gap < maxIntervalGap {
+			a.Gaps = append(a.Gaps, gap)
+			starts = append(starts, arrivals[i])
+		}
+	}
+	if len(a.Gaps) < minIntervalGaps {
+		return a, false
+	}
+
+	a.Phases = findPhases(a.Gaps, starts)
+	last := a.Phases[len(a.Phases)-1]
+	current := a.Gaps[len(a.Gaps)-last.Gaps:]
+
+	a.Base = baseInterval(current)
+	a.Configured = (a.Base - meanAdvDelay).Round(advIntervalUnit)
+	if a.Configured < 20*time.Millisecond {
+		a.Configured = a.Base.Round(advIntervalUnit)
+	}
+
+	var single []time.Duration
+	var unexplained []time.Duration
+	explained := 0
+	for _, gap := range current {
+		k, ok := multipleOf(gap, a.Base)
+		if !ok {
+			unexplained = append(unexplained, gap)
+			continue
+		}
+		explained++
+		a.Missed += k - 1
+		if k == 1 {
+			single = append(single, gap)
+		}
+	}
+	a.Explained = float64(explained) / float64(len(current))
+	if explained > 0 {
+		a.Loss = float64(a.Missed) / float64(a.Missed+explained)
+	}
+
+	if len(single) >= 3 {
+		sort.Slice(single, func(i, j int) bool { return single[i] < single[j] })
+		a.Jitter = durationStdDev(single)
+		// advDelay is uniform, so the middle 90% of gaps spans 90% of its range
+		spread := percentileDuration(single, 0.95) - percentileDuration(single, 0.05)
+		a.DelaySpread = time.Duration(float64(spread) / 0.9)
+	}
+
+	for _, c := range gapClusters(unexplained) {
+		if len(c)*10 >= len(current) && len(c) >= 5 {
+			a.Others = append(a.Others, medianDuration(c))
+		}
+	}
+	return a, true
+}
+
+// baseInterval finds the fundamental period of gaps, which may include
+// whole multiples of it from missed advertisements. Every cluster of gaps,
+// divided by 1 to 4, is a candidate. Short candidates explain many gaps by
+// chance, so each is scored by the gaps it explains beyond that, and must
+// explain some gaps as single intervals. The longest candidate scoring
+// (nearly) as well as the best wins, so a period beats its fractions.
+func baseInterval(gaps []time.Duration) time.Duration {
+	var candidates []time.Duration
+	for _, c := range gapClusters(gaps) {
+		if len(c) < 2 && len(gaps) >= minIntervalGaps {
+			continue
+		}
+		center := medianDuration(c)
+		for m := 1; m <= 4; m++ {
+			if t := center / time.Duration(m); t >= 20*time.Millisecond {
+				candidates = append(candidates, t)
+			}
+		}
+	}
+
+	scores := make([]float64, len(candidates))
+	top := 0.0
+	for i, t := range candidates {
+		explained, single := 0, 0
+		for _, gap := range gaps {
+			if k, ok := multipleOf(gap, t); ok {
+				explained++
+				if k == 1 {
+					single++
+				}
+			}
+		}
+		if single*10 < len(gaps) {
+			scores[i] = -1
+			continue
+		}
+		// A random gap lands within tolerance of some multiple this often
+		chance := math.Min(1, 2*(float64(6*time.Millisecond)/float64(t)+0.04))
+		scores[i] = float64(explained)/float64(len(gaps)) - chance
+		top = math.Max(top, scores[i])
+	}
+	var best time.Duration
+	for i, t := range candidates {
+		if scores[i] >= top-0.02 && t > best {
+			best = t
+		}
+	}
+	if best == 0 {
+		return medianDuration(gaps)
+	}
+
+	// Refine: the mean length of one advertising event over explained gaps
+	var sum float64
+	var events int
+	for _, gap := range gaps {
+		if k, ok := multipleOf(gap, best); ok {
+			sum += float64(gap)
+			events += k
+		}
+	}
+	if events == 0 {
+		return best
+	}
+	return time.Duration(sum / float64(events))
+}
+
+// multipleOf returns how many periods of base a gap spans, if it is close
+// enough to a whole number of them. Each period carries its own advDelay, so
+// the tolerance grows with the multiple.
+func multipleOf(gap, base time.Duration) (int, bool) {
+	if base <= 0 {
+		return 0, false
+	}
+	k := int(math.Round(float64(gap) / float64(base)))
+	if k < 1 || k > maxIntervalMultiple {
+		return 0, false
+	}
+	tolerance := 6*time.Millisecond + time.Duration(float64(k)*float64(base)*0.04)
+	diff := gap - time.Duration(k)*base
+	if diff < 0 {
+		diff = -diff
+	}
+	return k, diff <= tolerance
+}
+
+// findPhases splits gaps into stretches of steady advertising. Each stretch
+// is characterized by its 25th percentile gap, which stays at the base
+// interval unless most advertisements are missed. Stretches start as
+// segments of phaseSegment gaps; similar neighbours are joined, and
+// stretches too short to be a phase are absorbed by the closer neighbour.
+func findPhases(gaps []time.Duration, ends []time.Time) []IntervalPhase {
+	type run struct {
+		start, end int
+		interval   time.Duration
+	}
+	measure := func(r *run) {
+		sorted := append([]time.Duration(nil), gaps[r.start:r.end]...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		r.interval = percentileDuration(sorted, 0.25)
+	}
+	similar := func(a, b time.Duration) bool {
+		ratio := float64(a) / float64(b)
+		return ratio < phaseRatio && ratio > 1/phaseRatio
+	}
+
+	var runs []run
+	for start := 0; start < len(gaps); start += phaseSegment {
+		end := min(start+phaseSegment, len(gaps))
+		if end-start < phaseSegment/2 && len(runs) > 0 {
+			runs[len(runs)-1].end = end
+			measure(&runs[len(runs)-1])
+			break
+		}
+		r := run{start: start, end: end}
+		measure(&r)
+		runs = append(runs, r)
+	}
+
+	for {
+		// Join neighbours with similar intervals
+		joined := runs[:1]
+		for _, r := range runs[1:] {
+			prev := &joined[len(joined)-1]
+			if similar(r.interval, prev.interval) {
+				prev.end = r.end
+				measure(prev)
+				continue
+			}
+			joined = append(joined, r)
+		}
+		runs = joined
+
+		// Absorb the shortest run if it is too short to be a phase
+		shortest := 0
+		for i, r := range runs {
+			if r.end-r.start < runs[shortest].end-runs[shortest].start {
+				shortest = i
+			}
+		}
+		if len(runs) == 1 || runs[shortest].end-runs[shortest].start >= minPhaseGaps {
+			break
+		}
+		into := shortest - 1
+		if into < 0 || (shortest+1 < len(runs) && intervalDistance(runs[shortest+1].interval, runs[shortest].interval) < intervalDistance(runs[into].interval, runs[shortest].interval)) {
+			into = shortest + 1
+		}
+		target := &runs[into]
+		target.start = min(target.start, runs[shortest].start)
+		target.end = max(target.end, runs[shortest].end)
+		measure(target)
+		runs = append(runs[:shortest], runs[shortest+1:]...)
+	}
+
+	phases := make([]IntervalPhase, 0, len(runs))
+	for _, r := range runs {
+		phases = append(phases, IntervalPhase{
+			Start:    ends[r.start].Add(-gaps[r.start]),
+			End:      ends[r.end-1],
+			Interval: r.interval,
+			Gaps:     r.end - r.start,
+		})
+	}
+	return phases
+}
+
+// intervalDistance is how far apart two intervals are, as a ratio
+func intervalDistance(a, b time.Duration) float64 {
+	return math.Abs(math.Log(float64(a) / float64(b)))
+}
+
+// gapClusters groups gaps whose sorted values are within 15% (plus the
+// advDelay range) of their neighbours
+func gapClusters(gaps []time.Duration) [][]time.Duration {
+	if len(gaps) == 0 {
+		return nil
+	}
+	sorted := append([]time.Duration(nil), gaps...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var clusters [][]time.Duration
+	start := 0
+	for i := 1; i <= len(sorted); i++ {
+		if i == len(sorted) || sorted[i] > sorted[i-1]+sorted[i-1]*15/100+10*time.Millisecond {
+			clusters = append(clusters, sorted[start:i])
+			start = i
+		}
+	}
+	return clusters
+}
+
+// HistogramBin counts gaps in [Low, High)
+type HistogramBin struct {
+	Low   time.Duration
+	High  time.Duration
+	Count int
+}
+
+// IntervalHistogram divides gaps into bins of equal width from the shortest
+// gap to the 98th percentile; longer gaps are counted in the last bin
+func IntervalHistogram(gaps []time.Duration, bins int) []HistogramBin {
+	if len(gaps) == 0 || bins < 1 {
+		return nil
+	}
+	sorted := append([]time.Duration(nil), gaps...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	low := sorted[0].Truncate(time.Millisecond)
+	high := percentileDuration(sorted, 0.98)
+	width := ((high - low) / time.Duration(bins)).Round(time.Millisecond)
+	if width < time.Millisecond {
+		width = time.Millisecond
+	}
+
+	hist := make([]HistogramBin, bins)
+	for i := range hist {
+		hist[i].Low = low + time.Duration(i)*width
+		hist[i].High = hist[i].Low + width
+	}
+	for _, gap := range sorted {
+		i := int((gap - low) / width)
+		if i >= bins {
+			i = bins - 1
+		}
+		hist[i].Count++
+	}
+	return hist
+}
+
+// percentileDuration returns the p-th fraction of sorted durations
+func percentileDuration(sorted []time.Duration, p float64) time.Duration {
+	i := int(p * float64(len(sorted)-1))
+	return sorted[i]
+}
+
+func medianDuration(durations []time.Duration) time.Duration {
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+func durationStdDev(durations []time.Duration) time.Duration {
+	var sum float64
+	for _, d := range durations {
+		sum += float64(d)
+	}
+	mean := sum / float64(len(durations))
+	var variance float64
+	for _, d := range durations {
+		variance += (float64(d) - mean) * (float64(d) - mean)
+	}
+	return time.Duration(math.Sqrt(variance / float64(len(durations))))
+}
@@ -0,0 +1,152 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+// delayedGaps returns n gaps of an advertiser at interval, each with an
+// advDelay cycling through 0 to 10 ms, with every missEvery-th one (if any)
+// spanning two advertising events
+func delayedGaps(n int, interval time.Duration, missEvery int) []time.Duration {
+	gaps := make([]time.Duration, n)
+	for i := range gaps {
+		gaps[i] = interval + time.Duration(i%11)*time.Millisecond
+		if missEvery > 0 && (i+1)%missEvery == 0 {
+			gaps[i] = 2*interval + 10*time.Millisecond
+		}
+	}
+	return gaps
+}
+
+func TestAnalyzeIntervals(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	tests := []struct {
+		name       string
+		gaps       []time.Duration
+		ok         bool
+		configured time.Duration
+		missed     int
+		phases     int
+		multiple   bool
+	}{
+		{
+			name:       "steady",
+			gaps:       delayedGaps(60, 100*time.Millisecond, 0),
+			ok:         true,
+			configured: 100 * time.Millisecond,
+			phases:     1,
+		},
+		{
+			name:       "every fifth missed",
+			gaps:       delayedGaps(60, 100*time.Millisecond, 5),
+			ok:         true,
+			configured: 100 * time.Millisecond,
+			missed:     12,
+			phases:     1,
+		},
+		{
+			name:       "switched from slow to fast advertising",
+			gaps:       append(delayedGaps(44, time.Second, 0), delayedGaps(44, 100*time.Millisecond, 0)...),
+			ok:         true,
+			configured: 100 * time.Millisecond,
+			phases:     2,
+		},
+		{
+			name:       "a second advertising set",
+			gaps:       append(delayedGaps(44, 200*time.Millisecond, 0), repeatGaps(20, 37*time.Millisecond, 0)...),
+			ok:         true,
+			configured: 200 * time.Millisecond,
+			phases:     1,
+			multiple:   true,
+		},
+		{
+			name: "gaps outside the advertising range ignored",
+			gaps: append(repeatGaps(20, 5*time.Millisecond, 0), repeatGaps(5, 20*time.Second, 0)...),
+		},
+		{
+			name: "too few gaps",
+			gaps: delayedGaps(minIntervalGaps-1, 100*time.Millisecond, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, ok := AnalyzeIntervals(arrivalsAt(start, tt.gaps...))
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if a.Configured != tt.configured {
+				t.Errorf("configured interval = %v (base %v), want %v", a.Configured, a.Base, tt.configured)
+			}
+			if a.Missed != tt.missed {
+				t.Errorf("missed = %d, want %d", a.Missed, tt.missed)
+			}
+			if len(a.Phases) != tt.phases || a.Switched() != (tt.phases > 1) {
+				t.Errorf("phases = %d, want %d", len(a.Phases), tt.phases)
+			}
+			if a.MultipleSets() != tt.multiple {
+				t.Errorf("multiple sets = %v (explained %.2f, others %v), want %v", a.MultipleSets(), a.Explained, a.Others, tt.multiple)
+			}
+			if a.DelaySpread < 5*time.Millisecond || a.DelaySpread > 15*time.Millisecond {
+				t.Errorf("advDelay spread = %v, want about 10ms", a.DelaySpread)
+			}
+		})
+	}
+}
+
+func TestMultipleOf(t *testing.T) {
+	base := 100 * time.Millisecond
+	tests := []struct {
+		gap time.Duration
+		k   int
+		ok  bool
+	}{
+		{gap: 100 * time.Millisecond, k: 1, ok: true},
+		{gap: 109 * time.Millisecond, k: 1, ok: true},
+		{gap: 150 * time.Millisecond, k: 2, ok: false},
+		{gap: 210 * time.Millisecond, k: 2, ok: true},
+		{gap: 800 * time.Millisecond, k: 8, ok: true},
+		{gap: 900 * time.Millisecond, ok: false},
+		{gap: 30 * time.Millisecond, ok: false},
+	}
+	for _, tt := range tests {
+		k, ok := multipleOf(tt.gap, base)
+		if ok != tt.ok || (ok && k != tt.k) {
+			t.Errorf("multipleOf(%v) = %d, %v, want %d, %v", tt.gap, k, ok, tt.k, tt.ok)
+		}
+	}
+}
+
+func TestIntervalHistogram(t *testing.T) {
+	gaps := []time.Duration{
+		100 * time.Millisecond, 101 * time.Millisecond, 104 * time.Millisecond,
+		108 * time.Millisecond, 110 * time.Millisecond, 900 * time.Millisecond,
+	}
+	tests := []struct {
+		name   string
+		gaps   []time.Duration
+		bins   int
+		counts []int
+	}{
+		{name: "no gaps", gaps: nil, bins: 4},
+		{name: "no bins", gaps: gaps, bins: 0},
+		{name: "one bin", gaps: gaps, bins: 1, counts: []int{6}},
+		{name: "outliers in the last bin", gaps: gaps, bins: 2, counts: []int{3, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hist := IntervalHistogram(tt.gaps, tt.bins)
+			if len(hist) != len(tt.counts) {
+				t.Fatalf("bins = %d, want %d", len(hist), len(tt.counts))
+			}
+			for i, b := range hist {
+				if b.Count != tt.counts[i] {
+					t.Errorf("bin %d [%v, %v) count = %d, want %d", i, b.Low, b.High, b.Count, tt.counts[i])
+				}
+			}
+		})
+	}
+}
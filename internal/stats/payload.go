@@ -0,0 +1,237 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/buckleypaul/blescan/internal/ble"
+)
+
+// Payload change tracking. Successive manufacturer and service data payloads
+// of a device are lined up byte by byte; the history of each byte position
+// suggests what it holds.
+
+const (
+	// Fewest payloads a byte position is classified from
+	minPayloadSamples = 4
+	// Largest step between successive values of a counter
+	counterStep = 16
+	// Fraction of a counter's changes that must be small steps forward
+	counterForward = 0.9
+	// Mean step between successive values of a random byte; uniformly random
+	// bytes average 64
+	randomStep = 40
+)
+
+// ByteClass is what a byte position appears to hold
+type ByteClass int
+
+const (
+	ByteUnknown  ByteClass = iota // Too few payloads to tell
+	ByteConstant                  // Never changes
+	ByteCounter                   // Steps forward by small amounts, wrapping
+	ByteRandom                    // Jumps anywhere: nonces, hashes, encrypted data
+	ByteSensor                    // Wanders up and down: measurements
+)
+
+// String returns the class name
+func (c ByteClass) String() string {
+	switch c {
+	case ByteConstant:
+		return "constant"
+	case ByteCounter:
+		return "counter"
+	case ByteRandom:
+		return "random"
+	case ByteSensor:
+		return "sensor"
+	}
+	return "unknown"
+}
+
+// PayloadSample is one payload of a stream
+type PayloadSample struct {
+	Timestamp time.Time
+	Data      []byte
+}
+
+// PayloadStream is the successive payloads of one data source: the
+// manufacturer data, or the service data of one UUID
+type PayloadStream struct {
+	Key     string // "mfr", or the service UUID
+	Label   string
+	Samples []PayloadSample // Oldest first
+}
+
+// PayloadStreams splits advertisements into one stream per data source,
+// manufacturer data first and then service data by UUID
+func PayloadStreams(advs []ble.Advertisement) []PayloadStream {
+	byKey := make(map[string]*PayloadStream)
+	var keys []string
+	add := func(key, label string, t time.Time, data []byte) {
+		s, ok := byKey[key]
+		if !ok {
+			s = &PayloadStream{Key: key, Label: label}
+			byKey[key] = s
+			keys = append(keys, key)
+		}
+		s.Samples = append(s.Samples, PayloadSample{Timestamp: t, Data: data})
+	}
+	for _, adv := range advs {
+		if len(adv.ManufacturerData) > 0 {
+			add("mfr", "Manufacturer Data", adv.Timestamp, adv.ManufacturerData)
+		}
+		for uuid, data := range adv.ServiceData {
+			if len(data) > 0 {
+				add(uuid, "Service Data "+ble.FormatUUID(uuid), adv.Timestamp, data)
+			}
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i] == "mfr" || keys[j] == "mfr" {
+			return keys[i] == "mfr"
+		}
+		return keys[i] < keys[j]
+	})
+	streams := make([]PayloadStream, len(keys))
+	for i, key := range keys {
+		streams[i] = *byKey[key]
+	}
+	return streams
+}
+
+// BytePosition describes the history of one byte position in a stream
+type BytePosition struct {
+	Index    int
+	Class    ByteClass
+	Samples  int // Payloads long enough to hold this position
+	Distinct int // Different values seen
+	Changes  int // Times the value differed from the payload before
+	Min      byte
+	Max      byte
+}
+
+// ClassifyBytes classifies every byte position of a stream's payloads, up to
+// the longest payload
+func ClassifyBytes(samples []PayloadSample) []BytePosition {
+	length := 0
+	for _, s := range samples {
+		length = max(length, len(s.Data))
+	}
+
+	positions := make([]BytePosition, length)
+	for i := range positions {
+		p := BytePosition{Index: i}
+		var seen [256]bool
+		var prev byte
+		forward, stepSum := 0, 0
+		for _, s := range samples {
+			if i >= len(s.Data) {
+				continue
+			}
+			v := s.Data[i]
+			if p.Samples == 0 || v < p.Min {
+				p.Min = v
+			}
+			if p.Samples == 0 || v > p.Max {
+				p.Max = v
+			}
+			if !seen[v] {
+				seen[v] = true
+				p.Distinct++
+			}
+			if p.Samples > 0 && v != prev {
+				p.Changes++
+				// Wrapped step, so 0xFF to 0x00 is one forward
+				step := int(int8(v - prev))
+				if step > 0 && step <= counterStep {
+					forward++
+				}
+				if step < 0 {
+					step = -step
+				}
+				stepSum += step
+			}
+			prev = v
+			p.Samples++
+		}
+
+		switch {
+		case p.Samples < minPayloadSamples:
+			p.Class = ByteUnknown
+		case p.Changes == 0:
+			p.Class = ByteConstant
+		case p.Changes >= 3 && float64(forward) >= counterForward*float64(p.Changes):
+			p.Class = ByteCounter
+		case stepSum >= randomStep*p.Changes && p.Distinct*2 >= p.Changes:
+			p.Class = ByteRandom
+		default:
+			p.Class = ByteSensor
+		}
+		positions[i] = p
+	}
+	return positions
+}
+
+// PayloadSegment is a run of advertisements sharing one payload structure
+type PayloadSegment struct {
+	Start     time.Time
+	End       time.Time
+	Structure string
+	Adverts   int
+}
+
+// PayloadTimeline returns the runs of advertisements with the same payload
+// structure, oldest first. A structure is the data sources present with the
+// length and leading type byte of each, so a new frame type or layout starts
+// a new segment while changing values don't. Advertisements without
+// manufacturer or service data, such as most scan responses, are skipped.
+func PayloadTimeline(advs []ble.Advertisement) []PayloadSegment {
+	var segments []PayloadSegment
+	for _, adv := range advs {
+		structure := payloadStructure(adv)
+		if structure == "" {
+			continue
+		}
+		if n := len(segments); n > 0 && segments[n-1].Structure == structure {
+			segments[n-1].End = adv.Timestamp
+			segments[n-1].Adverts++
+			continue
+		}
+		segments = append(segments, PayloadSegment{
+			Start:     adv.Timestamp,
+			End:       adv.Timestamp,
+			Structure: structure,
+			Adverts:   1,
+		})
+	}
+	return segments
+}
+
+// payloadStructure describes the layout of an advertisement's payloads
+func payloadStructure(adv ble.Advertisement) string {
+	var parts []string
+	if data := adv.ManufacturerData; len(data) >= 2 {
+		company := uint16(data[0]) | uint16(data[1])<<8
+		part := fmt.Sprintf("0x%04X %dB", company, len(data)-2)
+		if len(data) > 2 {
+			part += fmt.Sprintf(" type 0x%02X", data[2])
+		}
+		parts = append(parts, part)
+	}
+	var uuids []string
+	for uuid, data := range adv.ServiceData {
+		if len(data) > 0 {
+			uuids = append(uuids, uuid)
+		}
+	}
+	sort.Strings(uuids)
+	for _, uuid := range uuids {
+		data := adv.ServiceData[uuid]
+		parts = append(parts, fmt.Sprintf("%s %dB type 0x%02X", ble.FormatUUID(uuid), len(data), data[0]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,162 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/buckleypaul/blescan/internal/ble"
+)
+
+// samplesOf returns one-byte payloads holding values, a second apart
+func samplesOf(values ...byte) []PayloadSample {
+	start := time.Unix(1700000000, 0)
+	samples := make([]PayloadSample, len(values))
+	for i, v := range values {
+		samples[i] = PayloadSample{Timestamp: start.Add(time.Duration(i) * time.Second), Data: []byte{v}}
+	}
+	return samples
+}
+
+func TestClassifyBytes(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []byte
+		class    ByteClass
+		distinct int
+		changes  int
+	}{
+		{name: "constant", values: []byte{5, 5, 5, 5}, class: ByteConstant, distinct: 1},
+		{name: "counter wrapping", values: []byte{0xFE, 0xFF, 0x00, 0x01, 0x02}, class: ByteCounter, distinct: 5, changes: 4},
+		{name: "counter with missed values", values: []byte{10, 13, 14, 20, 21, 21}, class: ByteCounter, distinct: 5, changes: 4},
+		{name: "random", values: []byte{0x12, 0xA7, 0x3C, 0xF1, 0x58, 0x09, 0xC4}, class: ByteRandom, distinct: 7, changes: 6},
+		{name: "sensor", values: []byte{20, 21, 20, 22, 21, 19}, class: ByteSensor, distinct: 4, changes: 5},
+		{name: "too few payloads", values: []byte{1, 2, 3}, class: ByteUnknown, distinct: 3, changes: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			positions := ClassifyBytes(samplesOf(tt.values...))
+			if len(positions) != 1 {
+				t.Fatalf("positions = %d, want 1", len(positions))
+			}
+			p := positions[0]
+			if p.Class != tt.class {
+				t.Errorf("class = %s, want %s", p.Class, tt.class)
+			}
+			if p.Samples != len(tt.values) || p.Distinct != tt.distinct || p.Changes != tt.changes {
+				t.Errorf("samples = %d, distinct = %d, changes = %d, want %d, %d and %d",
+					p.Samples, p.Distinct, p.Changes, len(tt.values), tt.distinct, tt.changes)
+			}
+		})
+	}
+}
+
+func TestClassifyBytesUnevenLengths(t *testing.T) {
+	samples := samplesOf(1, 1, 1, 1)
+	samples[3].Data = []byte{1, 0x40}
+	positions := ClassifyBytes(samples)
+	if len(positions) != 2 {
+		t.Fatalf("positions = %d, want 2", len(positions))
+	}
+	if positions[0].Class != ByteConstant || positions[1].Class != ByteUnknown || positions[1].Samples != 1 {
+		t.Errorf("positions = %+v", positions)
+	}
+	if positions[1].Min != 0x40 || positions[1].Max != 0x40 {
+		t.Errorf("second position range = %#x-%#x, want 0x40", positions[1].Min, positions[1].Max)
+	}
+}
+
+func TestPayloadTimeline(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	advert := func(i int, mfr []byte, service map[string][]byte) ble.Advertisement {
+		adv := ble.NewAdvertisement()
+		adv.Timestamp = start.Add(time.Duration(i) * time.Second)
+		adv.ManufacturerData = mfr
+		for k, v := range service {
+			adv.ServiceData[k] = v
+		}
+		return adv
+	}
+	eddystone := "0000feaa-0000-1000-8000-00805f9b34fb"
+	tests := []struct {
+		name       string
+		advs       []ble.Advertisement
+		structures []string
+		adverts    []int
+	}{
+		{
+			name: "changing values keep the segment",
+			advs: []ble.Advertisement{
+				advert(0, []byte{0x4C, 0x00, 0x10, 0x01}, nil),
+				advert(1, []byte{0x4C, 0x00, 0x10, 0x02}, nil),
+			},
+			structures: []string{"0x004C 2B type 0x10"},
+			adverts:    []int{2},
+		},
+		{
+			name: "new frame type starts a segment",
+			advs: []ble.Advertisement{
+				advert(0, nil, map[string][]byte{eddystone: {0x00, 0xEE}}),
+				advert(1, nil, map[string][]byte{eddystone: {0x10, 0xEE}}),
+				advert(2, nil, map[string][]byte{eddystone: {0x00, 0xEE}}),
+			},
+			adverts: []int{1, 1, 1},
+		},
+		{
+			name: "advertisements without payloads are skipped",
+			advs: []ble.Advertisement{
+				advert(0, []byte{0x06, 0x00}, nil),
+				advert(1, nil, nil),
+				advert(2, []byte{0x06, 0x00}, nil),
+			},
+			structures: []string{"0x0006 0B"},
+			adverts:    []int{2},
+		},
+		{
+			name: "no payloads",
+			advs: []ble.Advertisement{advert(0, nil, nil)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segments := PayloadTimeline(tt.advs)
+			if len(segments) != len(tt.adverts) {
+				t.Fatalf("segments = %d, want %d", len(segments), len(tt.adverts))
+			}
+			for i, s := range segments {
+				if s.Adverts != tt.adverts[i] {
+					t.Errorf("segment %d has %d adverts, want %d", i, s.Adverts, tt.adverts[i])
+				}
+				if tt.structures != nil && s.Structure != tt.structures[i] {
+					t.Errorf("segment %d structure = %q, want %q", i, s.Structure, tt.structures[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPayloadStreams(t *testing.T) {
+	adv := ble.NewAdvertisement()
+	adv.ManufacturerData = []byte{0x4C, 0x00, 0x10}
+	adv.ServiceData["0000feaa-0000-1000-8000-00805f9b34fb"] = []byte{0x00}
+	adv.ServiceData["0000fe2c-0000-1000-8000-00805f9b34fb"] = []byte{0x01}
+	adv.ServiceData["0000180f-0000-1000-8000-00805f9b34fb"] = nil
+
+	streams := PayloadStreams([]ble.Advertisement{adv, adv})
+	var keys []string
+	for _, s := range streams {
+		keys = append(keys, s.Key)
+		if len(s.Samples) != 2 {
+			t.Errorf("%s: %d samples, want 2", s.Key, len(s.Samples))
+		}
+	}
+	want := []string{"mfr", "0000fe2c-0000-1000-8000-00805f9b34fb", "0000feaa-0000-1000-8000-00805f9b34fb"}
+	if len(keys) != len(want) {
+		t.Fatalf("streams = %v, want %v", keys, want)
+	}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("streams = %v, want %v", keys, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/buckleypaul/blescan/internal/ble"
+)
+
+// FilterField describes a device attribute that can be matched in a filter query
+type FilterField struct {
+	Key         string                       // Query key: "addrtype", "company", etc.
+	Description string                       // Shown in help text
+	Exact       bool                         // Match whole values instead of substrings
+	Values      func(d *ble.Device) []string // Values to match against (lowercased by the matcher)
+}
+
+// FilterFields lists all fields accepted in filter queries
+var FilterFields = []FilterField{
+	{
+		Key:         "name",
+		Description: "device name or address",
+		Values: func(d *ble.Device) []string {
+			return []string{d.GetDisplayName()}
+		},
+	},
+	{
+		Key:         "addr",
+		Description: "device address",
+		Values: func(d *ble.Device) []string {
+			return []string{d.Address}
+		},
+	},
+	{
+		Key:         "addrtype",
+		Description: "public, static, rpa, nrpa, random (any random type)",
+		Exact:       true,
+		Values: func(d *ble.Device) []string {
+			values := []string{d.AddressType.ShortString()}
+			if d.AddressType.IsRandom() {
+				values = append(values, "random")
+			}
+			return values
+		},
+	},
+	{
+		Key:         "identity",
+		Description: "name of the IRK that resolved the device",
+		Values: func(d *ble.Device) []string {
+			return []string{d.IdentityName}
+		},
+	},
+	{
+		Key:         "vendor",
+		Description: "IEEE OUI vendor of public addresses",
+		Values: func(d *ble.Device) []string {
+			return []string{d.FormatAddressVendor()}
+		},
+	},
+	{
+		Key:         "company",
+		Description: "Bluetooth SIG company name or ID (0x004c)",
+		Values: func(d *ble.Device) []string {
+			if d.ManufacturerID == nil {
+				return nil
+			}
+			return []string{
+				ble.GetManufacturerName(*d.ManufacturerID),
+				fmt.Sprintf("0x%04x", *d.ManufacturerID),
+			}
+		},
+	},
+	{
+		Key:         "service",
+		Description: "service UUID or assigned name",
+		Values: func(d *ble.Device) []string {
+			var values []string
+			for _, uuid := range d.ServiceUUIDs {
+				values = append(values, uuid, ble.FormatUUID(uuid))
+			}
+			for uuid := range d.ServiceData {
+				values = append(values, uuid, ble.FormatUUID(uuid))
+			}
+			return values
+		},
+	},
+	{
+		Key:         "mesh",
+		Description: "mesh state: unprovisioned, provisioning, prov-failed, provisioned",
+		Exact:       true,
+		Values: func(d *ble.Device) []string {
+			if d.Mesh == nil {
+				return nil
+			}
+			return []string{d.Mesh.State.String()}
+		},
+	},
+	{
+		Key:         "meshnet",
+		Description: "mesh network ID or unprovisioned device UUID",
+		Values: func(d *ble.Device) []string {
+			if d.Mesh == nil {
+				return nil
+			}
+			return []string{d.Mesh.NetworkID, d.Mesh.DeviceUUID}
+		},
+	},
+	{
+		Key:         "commission",
+		Description: "smart-home commissioning: ready, paired, matter, homekit",
+		Exact:       true,
+		Values: func(d *ble.Device) []string {
+			if d.Commissioning == nil {
+				return nil
+			}
+			return []string{d.Commissioning.Status(), d.Commissioning.Protocol}
+		},
+	},
+	{
+		Key:         "tracker",
+		Description: "tracker kind or state (airtag, findmy, smarttag, tile, dult, separated)",
+		Values: func(d *ble.Device) []string {
+			if d.Tracker == nil {
+				return nil
+			}
+			return []string{d.Tracker.Kind, strings.ReplaceAll(d.Tracker.Kind, " ", ""), d.Tracker.FormatState()}
+		},
+	},
+	{
+		Key:         "proto",
+		Description: "decoded protocol, intent or model (fast, swift, easysetup, pairing)",
+		Values: func(d *ble.Device) []string {
+			var values []string
+			for _, p := range d.Decoded {
+				values = append(values, p.Protocol, p.Intent, p.Model, p.Name)
+			}
+			return values
+		},
+	},
+	{
+		Key:         "type",
+		Description: "device type (phone, laptop, headphones, watch, fitness, beacon, tracker, sensor, media, unknown)",
+		Exact:       true,
+		Values: func(d *ble.Device) []string {
+			if d.Class.Category == "" {
+				return []string{"unknown"}
+			}
+			return []string{d.Class.Category}
+		},
+	},
+	{
+		Key:         "anomaly",
+		Description: "anomaly kind (layout-change, name-change, interleaved-intervals, rssi-jump, rssi-out-of-range, interval-change) or any",
+		Values: func(d *ble.Device) []string {
+			if d.AnomalyCount == 0 {
+				return nil
+			}
+			values := []string{"any"}
+			for _, a := range d.Anomalies {
+				values = append(values, a.Kind)
+			}
+			return values
+		},
+	},
+}
+
+// FilterPreset is a named, commonly used filter query
+type FilterPreset struct {
+	Name  string
+	Query string
+}
+
+// FilterPresets are cycled through with a single key in the device list
+var FilterPresets = []FilterPreset{
+	{Name: "Ready to commission", Query: "commission:ready"},
+	{Name: "Separated trackers", Query: "tracker:separated"},
+	{Name: "Spoofing anomalies", Query: "anomaly:any"},
+}
+
+// FilterTerm is a single "field:value" condition. A leading '-' negates it.
+type FilterTerm struct {
+	Field  *FilterField
+	Value  string
+	Negate bool
+}
+
+// lookupFilterField returns the filter field with the given key
+func lookupFilterField(key string) *FilterField {
+	for i := range FilterFields {
+		if FilterFields[i].Key == key {
+			return &FilterFields[i]
+		}
+	}
+	return nil
+}
+
+// ParseFilterQuery parses a space-separated list of "field:value" terms.
+// Bare words match the device name. All terms must match.
+func ParseFilterQuery(query string) ([]FilterTerm, error) {
+	var terms []FilterTerm
+	for _, word := range strings.Fields(query) {
+		term := FilterTerm{}
+		if strings.HasPrefix(word, "-") && len(word) > 1 {
+			term.Negate = true
+			word = word[1:]
+		}
+
+		key, value, ok := strings.Cut(word, ":")
+		if !ok {
+			key, value = "name", word
+		}
+		term.Field = lookupFilterField(strings.ToLower(key))
+		if term.Field == nil {
+			return nil, fmt.Errorf("unknown filter field %q", key)
+		}
+		if value == "" {
+			return nil, fmt.Errorf("missing value for %q", key)
+		}
+		term.Value = strings.ToLower(value)
+		terms = append(terms, term)
+	}
+	return terms, nil
+}
+
+// MatchesTerms returns true if the device satisfies every term
+func MatchesTerms(d *ble.Device, terms []FilterTerm) bool {
+	for _, term := range terms {
+		if matchesTerm(d, term) == term.Negate {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesTerm(d *ble.Device, term FilterTerm) bool {
+	for _, v := range term.Field.Values(d) {
+		v = strings.ToLower(v)
+		if term.Field.Exact {
+			if v == term.Value {
+				return true
+			}
+		} else if strings.Contains(v, term.Value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package stats
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/buckleypaul/blescan/internal/ble"
+)
+
+// Reception is the fraction of a device's advertisements that were received
+type Reception struct {
+	Ratio    float64 // Received over sent, 0 to 1
+	Exact    bool    // Counted from a counter the device advertises
+	Source   string  // Counter name, or "base interval" for estimates
+	Sent     int
+	Received int
+	Base     time.Duration // Base interval of an estimate
+}
+
+// String formats the ratio as "92%", or "~92%" when estimated
+func (r Reception) String() string {
+	if r.Exact {
+		return fmt.Sprintf("%.0f%%", r.Ratio*100)
+	}
+	return fmt.Sprintf("~%.0f%%", r.Ratio*100)
+}
+
+// EstimateReception works out how many of a device's advertisements were
+// received: exactly from an advertised counter, or else from how many
+// advertising intervals the gaps between received advertisements span
+func EstimateReception(d *ble.Device) (Reception, bool) {
+	if d.Counter != nil {
+		if ratio, ok := d.Counter.Ratio(); ok {
+			return Reception{
+				Ratio:    ratio,
+				Exact:    true,
+				Source:   d.Counter.Source,
+				Sent:     d.Counter.Sent,
+				Received: d.Counter.Received,
+			}, true
+		}
+	}
+
+	a, ok := AnalyzeIntervals(arrivalTimes(d))
+	if !ok {
+		return Reception{}, false
+	}
+	// Explained and Missed describe the most recent phase only
+	current := a.Phases[len(a.Phases)-1].Gaps
+	received := int(a.Explained*float64(current) + 0.5)
+	return Reception{
+		Ratio:    1 - a.Loss,
+		Source:   "base interval",
+		Sent:     received + a.Missed,
+		Received: received,
+		Base:     a.Base,
+	}, true
+}
+
+// arrivalTimes returns the device's recent advertisement times, using the
+// longer history when the scanner provided it
+func arrivalTimes(d *ble.Device) []time.Time {
+	if len(d.Arrivals) > 0 {
+		return d.Arrivals
+	}
+	times := make([]time.Time, len(d.Advertisements))
+	for i, adv := range d.Advertisements {
+		times[i] = adv.Timestamp
+	}
+	return times
+}
+
+// ReceptionPoint is the reception ratio over one bucket of time
+type ReceptionPoint struct {
+	Start time.Time
+	Ratio float64
+	Exact bool
+	Known bool // False when the bucket is too short or the base interval unknown
+}
+
+// ReceptionOverTime returns the reception ratio of each bucket of time from
+// the start of the device's reception history up to now, including buckets
+// nothing was received in. Buckets with counter data are exact; the others
+// compare the advertisements received with the time covered divided by the
+// base interval.
+func ReceptionOverTime(d *ble.Device, base time.Duration, now time.Time) []ReceptionPoint {
+	if len(d.ReceptionHistory) == 0 {
+		return nil
+	}
+	buckets := make(map[int64]ble.ReceptionCount, len(d.ReceptionHistory))
+	for _, b := range d.ReceptionHistory {
+		buckets[b.Start.UnixNano()] = b
+	}
+
+	var points []ReceptionPoint
+	for start := d.ReceptionHistory[0].Start; !start.After(now); start = start.Add(ble.ReceptionBucket) {
+		p := ReceptionPoint{Start: start}
+		bucket := buckets[start.UnixNano()]
+		switch {
+		case bucket.Sent > 0:
+			p.Ratio = min(1, float64(bucket.Counted)/float64(bucket.Sent))
+			p.Exact = true
+			p.Known = true
+		case base > 0:
+			// Only the part of the bucket the device was known for counts
+			from, to := start, start.Add(ble.ReceptionBucket)
+			if from.Before(d.FirstSeen) {
+				from = d.FirstSeen
+			}
+			if to.After(now) {
+				to = now
+			}
+			if expected := float64(to.Sub(from)) / float64(base); expected >= 1 {
+				p.Ratio = min(1, float64(bucket.Received)/expected)
+				p.Known = true
+			}
+		}
+		points = append(points, p)
+	}
+	return points
+}
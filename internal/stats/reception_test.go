@@ -0,0 +1,83 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/buckleypaul/blescan/internal/ble"
+)
+
+// arrivalsAt returns arrival times separated by gaps, starting at start
+func arrivalsAt(start time.Time, gaps ...time.Duration) []time.Time {
+	times := []time.Time{start}
+	for _, g := range gaps {
+		start = start.Add(g)
+		times = append(times, start)
+	}
+	return times
+}
+
+// repeatGaps returns n gaps of d, with every missEvery-th one (if any)
+// spanning two intervals
+func repeatGaps(n int, d time.Duration, missEvery int) []time.Duration {
+	gaps := make([]time.Duration, n)
+	for i := range gaps {
+		gaps[i] = d
+		if missEvery > 0 && (i+1)%missEvery == 0 {
+			gaps[i] = 2 * d
+		}
+	}
+	return gaps
+}
+
+func TestEstimateReception(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	tests := []struct {
+		name     string
+		gaps     []time.Duration
+		sent     int
+		received int
+	}{
+		{
+			name:     "nothing missed",
+			gaps:     repeatGaps(50, 100*time.Millisecond, 0),
+			sent:     50,
+			received: 50,
+		},
+		{
+			name:     "every fifth missed",
+			gaps:     repeatGaps(50, 100*time.Millisecond, 5),
+			sent:     60,
+			received: 50,
+		},
+		{
+			// Only the current 100 ms phase counts, not the 1 s phase before it
+			name:     "after an interval switch",
+			gaps:     append(repeatGaps(60, time.Second, 0), repeatGaps(60, 100*time.Millisecond, 6)...),
+			sent:     70,
+			received: 60,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &ble.Device{Arrivals: arrivalsAt(start, tt.gaps...)}
+			r, ok := EstimateReception(d)
+			if !ok {
+				t.Fatal("no estimate")
+			}
+			if r.Exact {
+				t.Error("estimate marked exact")
+			}
+			if r.Sent != tt.sent || r.Received != tt.received {
+				t.Errorf("sent %d, received %d, want %d and %d", r.Sent, r.Received, tt.sent, tt.received)
+			}
+			if want := float64(tt.received) / float64(tt.sent); r.Ratio < want-0.001 || r.Ratio > want+0.001 {
+				t.Errorf("ratio = %.3f, want %.3f", r.Ratio, want)
+			}
+		})
+	}
+
+	if _, ok := EstimateReception(&ble.Device{Arrivals: arrivalsAt(start, repeatGaps(5, time.Second, 0)...)}); ok {
+		t.Error("estimated reception from 5 gaps")
+	}
+}
@@ -0,0 +1,361 @@
+// Package window maintains rolling statistics of a device's advertisements
+// over several time windows at once. Each advertisement updates every window
+// in constant time; summaries are computed on demand.
+package window
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Window is a span of recent time statistics are kept for
+type Window struct {
+	Name string
+	Span time.Duration // 0 for the whole session
+}
+
+// Windows are the spans every Set maintains, shortest first
+var Windows = []Window{
+	{Name: "10s", Span: 10 * time.Second},
+	{Name: "1m", Span: time.Minute},
+	{Name: "10m", Span: 10 * time.Minute},
+	{Name: "session"},
+}
+
+const (
+	// Buckets per window. A window expires a bucket at a time, so it covers
+	// between (bucketsPerWindow-1)/bucketsPerWindow of its span and all of it.
+	bucketsPerWindow = 10
+
+	// RSSI histogram: one bin per dBm from rssiLow to rssiLow+rssiBins-1
+	rssiLow  = -127
+	rssiBins = 148
+
+	// Interval histogram: logarithmic bins from gapLow to gapHigh
+	gapLow      = 10 * time.Millisecond
+	gapHigh     = 10 * time.Second
+	gapBins     = 120
+	gapsPerBase = 3.5 // Gaps longer than this many base intervals aren't packet loss but silence
+)
+
+// gapBinRatio is the width of an interval histogram bin as a ratio
+var gapBinRatio = math.Pow(float64(gapHigh)/float64(gapLow), 1.0/gapBins)
+
+// bucket accumulates the advertisements of a slice of time
+type bucket struct {
+	index int64 // Start time divided by the bucket width
+
+	count              int
+	rssiSum, rssiSumSq float64
+	rssiMin, rssiMax   int16
+	rssiHist           histogram
+
+	gaps    int
+	gapSum  float64 // Seconds
+	gapHist histogram
+
+	// Gaps divided by the advertising events they span, in seconds, for
+	// gaps of at most gapsPerBase base intervals
+	norms              int
+	normSum, normSumSq float64
+	missed             int // Advertising events estimated lost
+}
+
+// reset empties b for a new slice of time, keeping the histograms' storage
+func (b *bucket) reset(index int64) {
+	*b = bucket{index: index, rssiHist: b.rssiHist[:0], gapHist: b.gapHist[:0]}
+}
+
+func (b *bucket) add(rssi int16, gap, base time.Duration) {
+	if b.count == 0 || rssi < b.rssiMin {
+		b.rssiMin = rssi
+	}
+	if b.count == 0 || rssi > b.rssiMax {
+		b.rssiMax = rssi
+	}
+	b.count++
+	b.rssiSum += float64(rssi)
+	b.rssiSumSq += float64(rssi) * float64(rssi)
+	b.rssiHist.add(rssiBin(rssi), 1)
+
+	if gap < gapLow || gap >= gapHigh {
+		return
+	}
+	b.gaps++
+	b.gapSum += gap.Seconds()
+	b.gapHist.add(gapBin(gap), 1)
+
+	// A gap of a few base intervals means the advertisements in between
+	// were missed; a longer one is the device going quiet
+	events := 1.0
+	if base > 0 {
+		if events = math.Max(1, math.Round(float64(gap)/float64(base))); events > gapsPerBase {
+			return
+		}
+	}
+	b.norms++
+	b.missed += int(events) - 1
+	norm := gap.Seconds() / events
+	b.normSum += norm
+	b.normSumSq += norm * norm
+}
+
+// merge adds another bucket's totals to b
+func (b *bucket) merge(o *bucket) {
+	if o.count == 0 {
+		return
+	}
+	if b.count == 0 || o.rssiMin < b.rssiMin {
+		b.rssiMin = o.rssiMin
+	}
+	if b.count == 0 || o.rssiMax > b.rssiMax {
+		b.rssiMax = o.rssiMax
+	}
+	b.count += o.count
+	b.rssiSum += o.rssiSum
+	b.rssiSumSq += o.rssiSumSq
+	b.rssiHist.merge(o.rssiHist)
+	b.gaps += o.gaps
+	b.gapSum += o.gapSum
+	b.norms += o.norms
+	b.normSum += o.normSum
+	b.normSumSq += o.normSumSq
+	b.missed += o.missed
+	b.gapHist.merge(o.gapHist)
+}
+
+// histogram counts values per bin. Only bins that have been hit are stored,
+// in bin order: a bucket typically sees a few dozen distinct RSSI readings
+// and intervals, and a flood address heard once sees one, so a dense array
+// of every bin would be mostly zeros.
+type histogram []histBin
+
+type histBin struct {
+	bin   uint16
+	count uint32
+}
+
+func (h *histogram) add(bin int, n uint32) {
+	bins := *h
+	i := sort.Search(len(bins), func(i int) bool { return int(bins[i].bin) >= bin })
+	if i < len(bins) && int(bins[i].bin) == bin {
+		bins[i].count += n
+		return
+	}
+	bins = append(bins, histBin{})
+	copy(bins[i+1:], bins[i:])
+	bins[i] = histBin{bin: uint16(bin), count: n}
+	*h = bins
+}
+
+func (h *histogram) merge(o histogram) {
+	for _, hb := range o {
+		h.add(int(hb.bin), hb.count)
+	}
+}
+
+// percentile returns the bin holding the p-th fraction of count values
+func (h histogram) percentile(count int, p float64) int {
+	rank := int(math.Ceil(p * float64(count)))
+	if rank < 1 {
+		rank = 1
+	}
+	seen := 0
+	for _, hb := range h {
+		seen += int(hb.count)
+		if seen >= rank {
+			return int(hb.bin)
+		}
+	}
+	if len(h) == 0 {
+		return 0
+	}
+	return int(h[len(h)-1].bin)
+}
+
+func rssiBin(rssi int16) int {
+	i := int(rssi) - rssiLow
+	if i < 0 {
+		return 0
+	}
+	if i >= rssiBins {
+		return rssiBins - 1
+	}
+	return i
+}
+
+func gapBin(gap time.Duration) int {
+	i := int(math.Log(float64(gap)/float64(gapLow)) / math.Log(gapBinRatio))
+	if i < 0 {
+		return 0
+	}
+	if i >= gapBins {
+		return gapBins - 1
+	}
+	return i
+}
+
+// gapBinCenter returns the geometric middle of an interval histogram bin
+func gapBinCenter(i int) time.Duration {
+	return time.Duration(float64(gapLow) * math.Pow(gapBinRatio, float64(i)+0.5))
+}
+
+// ring is one window's buckets
+type ring struct {
+	width   time.Duration // Span of a bucket
+	buckets [bucketsPerWindow]bucket
+}
+
+func (r *ring) add(t time.Time, rssi int16, gap, base time.Duration) {
+	index := t.UnixNano() / int64(r.width)
+	b := &r.buckets[index%bucketsPerWindow]
+	if b.index != index {
+		b.reset(index)
+	}
+	b.add(rssi, gap, base)
+}
+
+// total merges the buckets still inside the window at now and returns the
+// start of the oldest one
+func (r *ring) total(now time.Time) (bucket, time.Time) {
+	current := now.UnixNano() / int64(r.width)
+	var total bucket
+	for i := range r.buckets {
+		b := &r.buckets[i]
+		if b.index > current-bucketsPerWindow && b.index <= current {
+			total.merge(b)
+		}
+	}
+	start := time.Unix(0, (current-bucketsPerWindow+1)*int64(r.width))
+	return total, start
+}
+
+// Set keeps statistics for every window in Windows
+type Set struct {
+	first   time.Time
+	rings   []ring
+	session bucket
+}
+
+// NewSet returns an empty Set
+func NewSet() *Set {
+	s := &Set{}
+	for _, w := range Windows {
+		if w.Span > 0 {
+			s.rings = append(s.rings, ring{width: w.Span / bucketsPerWindow})
+		}
+	}
+	return s
+}
+
+// Add records an advertisement received at t. gap is the time since the
+// previous advertisement (0 for the first) and base the device's expected
+// advertising interval, used to estimate missed advertisements (0 if
+// unknown).
+func (s *Set) Add(t time.Time, rssi int16, gap, base time.Duration) {
+	if s.first.IsZero() {
+		s.first = t
+	}
+	for i := range s.rings {
+		s.rings[i].add(t, rssi, gap, base)
+	}
+	s.session.add(rssi, gap, base)
+}
+
+// Summary is the statistics of one window
+type Summary struct {
+	Window   Window
+	Duration time.Duration // Time actually covered, shorter than the span early on
+	Adverts  int
+	Rate     float64 // Advertisements per second
+
+	RSSIMin    int16
+	RSSIMax    int16
+	RSSIMean   float64
+	RSSIStdDev float64
+	RSSIP50    int16
+	RSSIP95    int16 // Strongest 5% of readings are at or above this
+
+	Intervals      int           // Gaps measured
+	IntervalMean   time.Duration // Mean gap between advertisements
+	IntervalMedian time.Duration
+	Jitter         time.Duration // Standard deviation of the interval, corrected for missed advertisements
+	Loss           float64       // Estimated fraction of advertisements missed, 0 to 1
+}
+
+// Summaries returns the statistics of every window at now
+func (s *Set) Summaries(now time.Time) []Summary {
+	summaries := make([]Summary, 0, len(Windows))
+	r := 0
+	for _, w := range Windows {
+		if w.Span == 0 {
+			summaries = append(summaries, summarize(w, &s.session, now.Sub(s.first)))
+			continue
+		}
+		total, start := s.rings[r].total(now)
+		r++
+		if start.Before(s.first) {
+			start = s.first
+		}
+		summaries = append(summaries, summarize(w, &total, now.Sub(start)))
+	}
+	return summaries
+}
+
+func summarize(w Window, b *bucket, covered time.Duration) Summary {
+	sum := Summary{Window: w, Duration: covered, Adverts: b.count}
+	if b.count == 0 {
+		return sum
+	}
+	if covered > 0 {
+		sum.Rate = float64(b.count) / covered.Seconds()
+	}
+
+	n := float64(b.count)
+	sum.RSSIMin = b.rssiMin
+	sum.RSSIMax = b.rssiMax
+	sum.RSSIMean = b.rssiSum / n
+	sum.RSSIStdDev = math.Sqrt(math.Max(0, b.rssiSumSq/n-sum.RSSIMean*sum.RSSIMean))
+	sum.RSSIP50 = int16(b.rssiHist.percentile(b.count, 0.50) + rssiLow)
+	sum.RSSIP95 = int16(b.rssiHist.percentile(b.count, 0.95) + rssiLow)
+
+	if b.gaps > 0 {
+		g := float64(b.gaps)
+		sum.Intervals = b.gaps
+		sum.IntervalMean = time.Duration(b.gapSum / g * float64(time.Second))
+		sum.IntervalMedian = gapBinCenter(b.gapHist.percentile(b.gaps, 0.5))
+	}
+	if b.norms > 0 {
+		k := float64(b.norms)
+		mean := b.normSum / k
+		sum.Jitter = time.Duration(math.Sqrt(math.Max(0, b.normSumSq/k-mean*mean)) * float64(time.Second))
+		sum.Loss = float64(b.missed) / float64(b.missed+b.norms)
+	}
+	return sum
+}
+
+// Merge adds another Set's advertisements to s, for devices found to be the
+// same transmitter
+func (s *Set) Merge(o *Set) {
+	if o.first.IsZero() {
+		return
+	}
+	if s.first.IsZero() || o.first.Before(s.first) {
+		s.first = o.first
+	}
+	for i := range s.rings {
+		for j := range o.rings[i].buckets {
+			ob := &o.rings[i].buckets[j]
+			b := &s.rings[i].buckets[j]
+			switch {
+			case ob.index == b.index:
+				b.merge(ob)
+			case ob.index > b.index:
+				b.reset(ob.index)
+				b.merge(ob)
+			}
+		}
+	}
+	s.session.merge(&o.session)
+}
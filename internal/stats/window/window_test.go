@@ -0,0 +1,148 @@
+package window
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummaries(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	tests := []struct {
+		name     string
+		rssi     []int16
+		gap      time.Duration
+		at       time.Duration // When to summarize, after start
+		window   int           // Index into Windows
+		adverts  int
+		p50, p95 int16
+		rssiMin  int16
+		rssiMax  int16
+	}{
+		{
+			name:    "single reading",
+			rssi:    []int16{-60},
+			gap:     100 * time.Millisecond,
+			window:  0,
+			adverts: 1,
+			p50:     -60, p95: -60, rssiMin: -60, rssiMax: -60,
+		},
+		{
+			name:    "percentiles of a spread",
+			rssi:    []int16{-80, -70, -70, -60, -60, -60, -60, -50, -50, -40},
+			gap:     100 * time.Millisecond,
+			window:  0,
+			adverts: 10,
+			p50:     -60, p95: -40, rssiMin: -80, rssiMax: -40,
+		},
+		{
+			name:    "out of range readings clamp to the end bins",
+			rssi:    []int16{-128, 30},
+			gap:     100 * time.Millisecond,
+			window:  0,
+			adverts: 2,
+			p50:     -127, p95: rssiLow + rssiBins - 1, rssiMin: -128, rssiMax: 30,
+		},
+		{
+			name:    "short window expired, session kept",
+			rssi:    []int16{-60, -62, -64},
+			gap:     time.Second,
+			at:      30 * time.Second,
+			window:  0,
+			adverts: 0,
+		},
+		{
+			name:    "session",
+			rssi:    []int16{-60, -62, -64},
+			gap:     time.Second,
+			at:      30 * time.Second,
+			window:  len(Windows) - 1,
+			adverts: 3,
+			p50:     -62, p95: -60, rssiMin: -64, rssiMax: -60,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSet()
+			var last time.Time
+			for i, rssi := range tt.rssi {
+				last = start.Add(time.Duration(i) * tt.gap)
+				var gap time.Duration
+				if i > 0 {
+					gap = tt.gap
+				}
+				s.Add(last, rssi, gap, tt.gap)
+			}
+			now := last
+			if tt.at > 0 {
+				now = start.Add(tt.at)
+			}
+			sum := s.Summaries(now)[tt.window]
+			if sum.Adverts != tt.adverts {
+				t.Fatalf("adverts = %d, want %d", sum.Adverts, tt.adverts)
+			}
+			if tt.adverts == 0 {
+				return
+			}
+			if sum.RSSIP50 != tt.p50 || sum.RSSIP95 != tt.p95 {
+				t.Errorf("P50 = %d, P95 = %d, want %d and %d", sum.RSSIP50, sum.RSSIP95, tt.p50, tt.p95)
+			}
+			if sum.RSSIMin != tt.rssiMin || sum.RSSIMax != tt.rssiMax {
+				t.Errorf("min = %d, max = %d, want %d and %d", sum.RSSIMin, sum.RSSIMax, tt.rssiMin, tt.rssiMax)
+			}
+			if tt.adverts > 1 {
+				if d := sum.IntervalMedian - tt.gap; d < -tt.gap/10 || d > tt.gap/10 {
+					t.Errorf("median interval = %v, want about %v", sum.IntervalMedian, tt.gap)
+				}
+			}
+		})
+	}
+}
+
+func TestHistogramsGrowWithDistinctValues(t *testing.T) {
+	s := NewSet()
+	start := time.Unix(1700000000, 0)
+	for i := 0; i < 1000; i++ {
+		s.Add(start.Add(time.Duration(i)*100*time.Millisecond), int16(-60-i%3), 100*time.Millisecond, 100*time.Millisecond)
+	}
+	if n := len(s.session.rssiHist); n != 3 {
+		t.Errorf("session RSSI histogram holds %d bins, want 3", n)
+	}
+	if n := len(s.session.gapHist); n != 1 {
+		t.Errorf("session interval histogram holds %d bins, want 1", n)
+	}
+
+	// A bucket no advertisement has reached allocates nothing
+	empty := NewSet()
+	empty.Add(start, -60, 0, 0)
+	for _, r := range empty.rings {
+		for i := range r.buckets {
+			if b := &r.buckets[i]; b.count == 0 && (b.rssiHist != nil || b.gapHist != nil) {
+				t.Fatal("empty bucket allocated a histogram")
+			}
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	a, b := NewSet(), NewSet()
+	for i := 0; i < 5; i++ {
+		a.Add(start.Add(time.Duration(i)*time.Second), -50, 0, 0)
+		b.Add(start.Add(time.Duration(i)*time.Second+500*time.Millisecond), -70, 0, 0)
+	}
+	a.Merge(b)
+	for _, sum := range a.Summaries(start.Add(5 * time.Second)) {
+		if sum.Adverts != 10 {
+			t.Errorf("%s: adverts = %d, want 10", sum.Window.Name, sum.Adverts)
+		}
+		if sum.RSSIMin != -70 || sum.RSSIMax != -50 {
+			t.Errorf("%s: min = %d, max = %d, want -70 and -50", sum.Window.Name, sum.RSSIMin, sum.RSSIMax)
+		}
+	}
+
+	// Merged buckets don't share storage with the other Set
+	b.Add(start.Add(6*time.Second), -90, 0, 0)
+	if sum := a.Summaries(start.Add(6 * time.Second))[0]; sum.RSSIMin != -70 {
+		t.Errorf("adding to the merged-from Set changed s: min = %d", sum.RSSIMin)
+	}
+}
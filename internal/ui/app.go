@@ -1,10 +1,17 @@
 package ui
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/buckleypaul/blescan/internal/annotate"
 	"github.com/buckleypaul/blescan/internal/ble"
+	"github.com/buckleypaul/blescan/internal/config"
+	"github.com/buckleypaul/blescan/internal/export"
+	"github.com/buckleypaul/blescan/internal/stats"
 	"github.com/buckleypaul/blescan/internal/ui/views"
 )
 
@@ -14,6 +21,12 @@ type ViewState int
 const (
 	ViewDeviceList ViewState = iota
 	ViewDeviceDetail
+	ViewBroadcasts
+	ViewTrackers
+	ViewCalibration
+	ViewIntervals
+	ViewPayloadDiff
+	ViewPresence
 )
 
 // Model is the main application model
@@ -22,6 +35,13 @@ type Model struct {
 	viewState    ViewState
 	deviceList   views.DeviceListModel
 	deviceDetail views.DeviceDetailModel
+	broadcasts   views.BroadcastListModel
+	trackers     views.TrackerListModel
+	calibration  views.CalibrationModel
+	intervals    views.IntervalModel
+	payloadDiff  views.PayloadDiffModel
+	presence     views.PresenceModel
+	detailReturn ViewState // View to return to when leaving device detail
 	width        int
 	height       int
 	err          error
@@ -42,6 +62,9 @@ func NewModel(scanner *ble.Scanner) Model {
 		scanner:    scanner,
 		viewState:  ViewDeviceList,
 		deviceList: views.NewDeviceListModel(),
+		broadcasts: views.NewBroadcastListModel(),
+		trackers:   views.NewTrackerListModel(scanner.TrackerAlertThreshold()),
+		presence:   views.NewPresenceModel(),
 	}
 }
 
@@ -77,38 +100,163 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.viewState == ViewDeviceList && m.deviceList.IsFilterActive() {
 				break
 			}
+			// Or while typing an annotation
+			if m.viewState == ViewPayloadDiff && m.payloadDiff.IsEditing() && msg.String() == "q" {
+				break
+			}
 			m.scanner.Stop()
 			return m, tea.Quit
 		case "esc":
-			if m.viewState == ViewDeviceDetail {
+			switch m.viewState {
+			case ViewDeviceDetail:
+				m.viewState = m.detailReturn
+				return m, nil
+			case ViewBroadcasts, ViewTrackers, ViewPresence:
 				m.viewState = ViewDeviceList
 				return m, nil
+			case ViewCalibration, ViewIntervals:
+				m.viewState = ViewDeviceDetail
+				return m, nil
+			case ViewPayloadDiff:
+				// Esc cancels the annotation form first
+				if !m.payloadDiff.IsEditing() {
+					m.viewState = ViewDeviceDetail
+					return m, nil
+				}
 			}
-		case "enter":
+		case "c":
+			// Calibrate distance estimation on the device shown in detail
+			if m.viewState == ViewDeviceDetail {
+				m.calibration = views.NewCalibrationModel(m.detailDevice(), m.saveCalibration)
+				m.calibration, _ = m.calibration.Update(tea.WindowSizeMsg{
+					Width:  m.width,
+					Height: m.height,
+				})
+				m.viewState = ViewCalibration
+				return m, nil
+			}
+		case "i":
+			// Analyze the advertising intervals of the device shown in detail
+			if m.viewState == ViewDeviceDetail {
+				m.intervals = views.NewIntervalModel(m.detailDevice())
+				m.intervals, _ = m.intervals.Update(tea.WindowSizeMsg{
+					Width:  m.width,
+					Height: m.height,
+				})
+				m.viewState = ViewIntervals
+				return m, nil
+			}
+		case "p":
+			// Diff the payloads of the device shown in detail
+			if m.viewState == ViewDeviceDetail {
+				m.payloadDiff = views.NewPayloadDiffModel(m.detailDevice(), m.saveAnnotations)
+				m.payloadDiff, _ = m.payloadDiff.Update(tea.WindowSizeMsg{
+					Width:  m.width,
+					Height: m.height,
+				})
+				m.viewState = ViewPayloadDiff
+				return m, nil
+			}
+		case "b":
+			// Show LE Audio broadcast sources
 			if m.viewState == ViewDeviceList && !m.deviceList.IsFilterActive() {
-				if device, ok := m.deviceList.SelectedDevice(); ok {
-					m.deviceDetail = views.NewDeviceDetailModel(device)
-					m.viewState = ViewDeviceDetail
-					// Initialize detail view with current window size
-					m.deviceDetail, _ = m.deviceDetail.Update(tea.WindowSizeMsg{
-						Width:  m.width,
-						Height: m.height,
-					})
+				m.viewState = ViewBroadcasts
+				return m, nil
+			}
+		case "m":
+			// Merge the selected device into its suggested correlation match
+			if m.viewState == ViewDeviceList && !m.deviceList.IsFilterActive() {
+				if device, ok := m.deviceList.SelectedDevice(); ok && device.Correlation != nil {
+					if err := m.scanner.MergeDevices(device.ID, device.Correlation.CandidateID); err == nil {
+						m.refreshDevices()
+					}
 					return m, nil
 				}
 			}
+		case "t":
+			// Show location trackers
+			if m.viewState == ViewDeviceList && !m.deviceList.IsFilterActive() {
+				m.viewState = ViewTrackers
+				return m, nil
+			}
+		case "v":
+			// Show the presence timeline
+			if m.viewState == ViewDeviceList && !m.deviceList.IsFilterActive() {
+				m.presence.SetPresence(m.scanner.GetPresence(), m.scanner.GetPresenceLog())
+				m.viewState = ViewPresence
+				return m, nil
+			}
+		case "x":
+			// Export the anomaly log
+			if m.viewState == ViewDeviceList && !m.deviceList.IsFilterActive() {
+				m.deviceList.SetStatus(m.exportAnomalies())
+				return m, nil
+			}
+			// Export the visits and arrival/departure log
+			if m.viewState == ViewPresence {
+				m.presence.SetStatus(m.exportPresence())
+				return m, nil
+			}
+		case "S":
+			// Cycle the RSSI smoothing filter
+			if m.viewState == ViewDeviceList && !m.deviceList.IsFilterActive() {
+				m.deviceList.SetStatus(m.cycleSmoothing())
+				m.refreshDevices()
+				return m, nil
+			}
+		case "enter":
+			var device ble.Device
+			var ok bool
+			switch {
+			case m.viewState == ViewDeviceList && !m.deviceList.IsFilterActive():
+				device, ok = m.deviceList.SelectedDevice()
+			case m.viewState == ViewBroadcasts:
+				if id, found := m.broadcasts.SelectedDeviceID(); found {
+					device, ok = m.scanner.GetDevice(id)
+				}
+			case m.viewState == ViewTrackers:
+				if id, found := m.trackers.SelectedDeviceID(); found {
+					device, ok = m.scanner.GetDevice(id)
+				}
+			case m.viewState == ViewPresence:
+				// Only devices that haven't departed can be viewed
+				if id, found := m.presence.SelectedDeviceID(); found {
+					device, ok = m.scanner.GetDevice(id)
+				}
+			}
+			if ok {
+				m.deviceDetail = views.NewDeviceDetailModel(&device)
+				m.detailReturn = m.viewState
+				m.viewState = ViewDeviceDetail
+				// Initialize detail view with current window size
+				m.deviceDetail, _ = m.deviceDetail.Update(tea.WindowSizeMsg{
+					Width:  m.width,
+					Height: m.height,
+				})
+				return m, nil
+			}
 		}
 
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
-		// Pass size to current view
-		switch m.viewState {
-		case ViewDeviceList:
-			m.deviceList, _ = m.deviceList.Update(msg)
-		case ViewDeviceDetail:
+		// Pass size to the views; list views keep their size while hidden
+		m.deviceList, _ = m.deviceList.Update(msg)
+		m.broadcasts, _ = m.broadcasts.Update(msg)
+		m.trackers, _ = m.trackers.Update(msg)
+		m.presence, _ = m.presence.Update(msg)
+		if m.viewState == ViewDeviceDetail {
 			m.deviceDetail, _ = m.deviceDetail.Update(msg)
 		}
+		if m.viewState == ViewCalibration {
+			m.calibration, _ = m.calibration.Update(msg)
+		}
+		if m.viewState == ViewIntervals {
+			m.intervals, _ = m.intervals.Update(msg)
+		}
+		if m.viewState == ViewPayloadDiff {
+			m.payloadDiff, _ = m.payloadDiff.Update(msg)
+		}
 		return m, nil
 
 	case tickMsg:
@@ -134,9 +282,21 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case ViewDeviceDetail:
 		m.deviceDetail, cmd = m.deviceDetail.Update(msg)
 		// Also update the device data
-		if device, ok := m.scanner.GetDevice(m.deviceDetail.Device.Address); ok {
-			m.deviceDetail.UpdateDevice(device)
+		if device, ok := m.scanner.GetDevice(m.deviceDetail.Device.ID); ok {
+			m.deviceDetail.UpdateDevice(&device)
 		}
+	case ViewBroadcasts:
+		m.broadcasts, cmd = m.broadcasts.Update(msg)
+	case ViewTrackers:
+		m.trackers, cmd = m.trackers.Update(msg)
+	case ViewPresence:
+		m.presence, cmd = m.presence.Update(msg)
+	case ViewCalibration:
+		m.calibration, cmd = m.calibration.Update(msg)
+	case ViewIntervals:
+		m.intervals, cmd = m.intervals.Update(msg)
+	case ViewPayloadDiff:
+		m.payloadDiff, cmd = m.payloadDiff.Update(msg)
 	}
 
 	return m, cmd
@@ -145,13 +305,145 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m *Model) refreshDevices() {
 	devices := m.scanner.GetDevices()
 	m.deviceList.SetDevices(devices)
+	m.broadcasts.SetDevices(devices)
+	m.trackers.SetTrackers(m.scanner.GetTrackers())
+	m.deviceList.SetTrackerAlerts(m.trackers.AlertCount())
+	m.deviceList.SetFloods(m.scanner.GetFloods())
+	if m.viewState == ViewPresence {
+		m.presence.SetPresence(m.scanner.GetPresence(), m.scanner.GetPresenceLog())
+	}
 
 	// Update detail view if open
 	if m.viewState == ViewDeviceDetail {
-		if device, ok := m.scanner.GetDevice(m.deviceDetail.Device.Address); ok {
-			m.deviceDetail.UpdateDevice(device)
+		if device, ok := m.scanner.GetDevice(m.deviceDetail.Device.ID); ok {
+			m.deviceDetail.UpdateDevice(&device)
+		}
+	}
+
+	// Re-analyze intervals with new arrivals
+	if m.viewState == ViewIntervals {
+		if device, ok := m.scanner.GetDevice(m.intervals.Device.ID); ok {
+			m.intervals.UpdateDevice(&device)
+		}
+	}
+
+	// Diff newly received payloads
+	if m.viewState == ViewPayloadDiff {
+		if device, ok := m.scanner.GetDevice(m.payloadDiff.Device.ID); ok {
+			m.payloadDiff.UpdateDevice(&device)
+		}
+	}
+
+	// Feed the calibration wizard new readings
+	if m.viewState == ViewCalibration {
+		if device, ok := m.scanner.GetDevice(m.calibration.Device.ID); ok {
+			m.calibration.UpdateDevice(&device)
+		} else {
+			m.calibration.DeviceLost()
+		}
+	}
+}
+
+// detailDevice returns the device shown in detail for a view opened from it,
+// fresh from the scanner while it is still tracked
+func (m *Model) detailDevice() *ble.Device {
+	if device, ok := m.scanner.GetDevice(m.deviceDetail.Device.ID); ok {
+		return &device
+	}
+	return m.deviceDetail.Device
+}
+
+// cycleSmoothing switches to the next RSSI smoothing filter, keeping its
+// tuning, and returns a status message
+func (m *Model) cycleSmoothing() string {
+	c := ble.RSSISmoothing()
+	for i, kind := range ble.SmoothingKinds {
+		if kind == c.Kind {
+			c.Kind = ble.SmoothingKinds[(i+1)%len(ble.SmoothingKinds)]
+			break
 		}
 	}
+	if err := m.scanner.SetRSSISmoothing(c); err != nil {
+		return fmt.Sprintf("Smoothing not changed: %v", err)
+	}
+	return "RSSI smoothing: " + c.String()
+}
+
+// anomalyExport is the file format of an exported anomaly log
+type anomalyExport struct {
+	Exported  time.Time     `json:"exported"`
+	Anomalies []ble.Anomaly `json:"anomalies"`
+}
+
+// exportAnomalies writes the session's anomaly log to the current directory
+// and returns a status message
+func (m *Model) exportAnomalies() string {
+	now := time.Now()
+	anomalies := m.scanner.GetAnomalies()
+	path := export.Filename("anomalies", now)
+	if err := export.WriteJSON(path, anomalyExport{Exported: now, Anomalies: anomalies}); err != nil {
+		return fmt.Sprintf("Export failed: %v", err)
+	}
+	return fmt.Sprintf("Exported %d anomalies to %s", len(anomalies), path)
+}
+
+// presenceExport is the file format of an exported presence timeline
+type presenceExport struct {
+	Exported time.Time           `json:"exported"`
+	Devices  []ble.Presence      `json:"devices"`
+	Events   []ble.PresenceEvent `json:"events"`
+}
+
+// exportPresence writes every device's visits and the session's arrivals and
+// departures to the current directory and returns a status message
+func (m *Model) exportPresence() string {
+	now := time.Now()
+	devices := m.scanner.GetPresence()
+	events := m.scanner.GetPresenceLog()
+	path := export.Filename("presence", now)
+	if err := export.WriteJSON(path, presenceExport{Exported: now, Devices: devices, Events: events}); err != nil {
+		return fmt.Sprintf("Export failed: %v", err)
+	}
+	return fmt.Sprintf("Exported %d devices and %d events to %s", len(devices), len(events), path)
+}
+
+// saveCalibration stores a fitted path loss model in distance.json in the
+// config directory and applies it to the running session
+func (m *Model) saveCalibration(scope, key string, c stats.Calibration) (string, error) {
+	dir, err := config.EnsureDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "distance.json")
+	saved, err := stats.LoadPathLossModel(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	current := stats.CurrentPathLossModel()
+	if scope == views.CalibrationScopeType {
+		saved = saved.WithTypeCalibration(key, c)
+		current = current.WithTypeCalibration(key, c)
+	} else {
+		saved = saved.WithDeviceCalibration(key, c)
+		current = current.WithDeviceCalibration(key, c)
+	}
+	if err := stats.SavePathLossModel(path, saved); err != nil {
+		return "", err
+	}
+	stats.SetPathLossModel(current)
+	return path, nil
+}
+
+// saveAnnotations writes the payload field annotations to annotations.json
+// in the config directory
+func (m *Model) saveAnnotations() (string, error) {
+	dir, err := config.EnsureDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "annotations.json")
+	return path, annotate.Save(path)
 }
 
 // View renders the application
@@ -165,6 +457,18 @@ func (m Model) View() string {
 		return m.deviceList.View()
 	case ViewDeviceDetail:
 		return m.deviceDetail.View()
+	case ViewBroadcasts:
+		return m.broadcasts.View()
+	case ViewTrackers:
+		return m.trackers.View()
+	case ViewCalibration:
+		return m.calibration.View()
+	case ViewIntervals:
+		return m.intervals.View()
+	case ViewPayloadDiff:
+		return m.payloadDiff.View()
+	case ViewPresence:
+		return m.presence.View()
 	}
 
 	return ""
@@ -0,0 +1,187 @@
+package views
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/buckleypaul/blescan/internal/ble"
+	"github.com/buckleypaul/blescan/internal/ui/styles"
+)
+
+// BroadcastListModel lists LE Audio broadcast sources (Auracast)
+type BroadcastListModel struct {
+	sources []*ble.Device
+	table   table.Model
+	width   int
+	height  int
+}
+
+// broadcastColumns are the columns of the broadcast source table, with
+// their share of the available width
+var broadcastColumns = []struct {
+	title string
+	pct   int
+}{
+	{"Name", 22},
+	{"Broadcast ID", 10},
+	{"Encryption", 10},
+	{"Audio", 6},
+	{"Program", 22},
+	{"Lang", 5},
+	{"RSSI", 7},
+	{"Address", 18},
+}
+
+// NewBroadcastListModel creates a new broadcast source list
+func NewBroadcastListModel() BroadcastListModel {
+	t := table.New(
+		table.WithColumns([]table.Column{}),
+		table.WithRows([]table.Row{}),
+		table.WithFocused(true),
+		table.WithHeight(10),
+	)
+
+	s := table.DefaultStyles()
+	s.Header = s.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(styles.MutedColor).
+		BorderBottom(true).
+		Bold(true).
+		Foreground(styles.PrimaryColor)
+	s.Selected = s.Selected.
+		Foreground(lipgloss.Color("229")).
+		Background(lipgloss.Color("57")).
+		Bold(true)
+	s.Cell = s.Cell.Padding(0, 1)
+	t.SetStyles(s)
+
+	m := BroadcastListModel{table: t}
+	m.updateColumns()
+	return m
+}
+
+// Update handles broadcast list updates
+func (m BroadcastListModel) Update(msg tea.Msg) (BroadcastListModel, tea.Cmd) {
+	var cmd tea.Cmd
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.table.SetHeight(max(5, m.height-6))
+		m.updateColumns()
+		m.updateRows()
+	default:
+		m.table, cmd = m.table.Update(msg)
+	}
+	return m, cmd
+}
+
+// SetDevices keeps the devices that announce a broadcast source, strongest first
+func (m *BroadcastListModel) SetDevices(devices []ble.Device) {
+	m.sources = m.sources[:0]
+	for i := range devices {
+		if devices[i].Broadcast != nil {
+			m.sources = append(m.sources, &devices[i])
+		}
+	}
+	sort.Slice(m.sources, func(i, j int) bool {
+		return m.sources[i].RSSISmoothed > m.sources[j].RSSISmoothed
+	})
+	m.updateRows()
+}
+
+// SelectedDeviceID returns the device ID of the currently selected broadcast source
+func (m BroadcastListModel) SelectedDeviceID() (string, bool) {
+	idx := m.table.Cursor()
+	if idx >= 0 && idx < len(m.sources) {
+		return m.sources[idx].ID, true
+	}
+	return "", false
+}
+
+func (m *BroadcastListModel) updateColumns() {
+	available := max(len(broadcastColumns)*6, m.width-8)
+	columns := make([]table.Column, len(broadcastColumns))
+	for i, c := range broadcastColumns {
+		columns[i] = table.Column{Title: c.title, Width: max(4, available*c.pct/100)}
+	}
+	m.table.SetColumns(columns)
+}
+
+func (m *BroadcastListModel) updateRows() {
+	columns := m.table.Columns()
+	rows := make([]table.Row, len(m.sources))
+	for i := range m.sources {
+		d := m.sources[i]
+		b := d.Broadcast
+		program := b.ProgramInfo
+		if program == "" {
+			program = "-"
+		}
+		language := b.Language
+		if language == "" {
+			language = "-"
+		}
+		row := table.Row{
+			d.GetDisplayName(),
+			fmt.Sprintf("0x%06X", b.BroadcastID),
+			b.FormatEncryption(),
+			b.FormatAudioConfig(),
+			program,
+			language,
+			fmt.Sprintf("%.1f", d.RSSISmoothed),
+			d.Address,
+		}
+		for j := range row {
+			if j < len(columns) {
+				if maxLen := columns[j].Width - 2; maxLen > 3 && len(row[j]) > maxLen {
+					row[j] = row[j][:maxLen-3] + "..."
+				}
+			}
+		}
+		rows[i] = row
+	}
+	m.table.SetRows(rows)
+}
+
+// View renders the broadcast source list
+func (m BroadcastListModel) View() string {
+	var b strings.Builder
+
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(styles.PrimaryColor).
+		Background(lipgloss.Color("235")).
+		Padding(0, 2).
+		Width(m.width)
+
+	title := "LE Audio Broadcast Sources"
+	count := fmt.Sprintf("%d sources", len(m.sources))
+	b.WriteString(titleStyle.Render(title + strings.Repeat(" ", max(0, m.width-len(title)-len(count)-6)) + count))
+	b.WriteString("\n")
+
+	tableStyle := lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(styles.MutedColor).
+		Width(m.width - 2)
+	if len(m.sources) == 0 {
+		emptyStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Padding(1, 2)
+		b.WriteString(tableStyle.Render(emptyStyle.Render("No broadcast sources seen. Broadcast announcements are sent with extended advertising, so they usually require replaying a capture.")))
+	} else {
+		b.WriteString(tableStyle.Render(m.table.View()))
+	}
+	b.WriteString("\n")
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(styles.MutedColor).
+		Background(lipgloss.Color("235")).
+		Padding(0, 2).
+		Width(m.width)
+	b.WriteString(helpStyle.Render("↑/↓ Row • Enter View • Esc Back • q Quit"))
+
+	return b.String()
+}
@@ -0,0 +1,319 @@
+package views
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/buckleypaul/blescan/internal/ble"
+	"github.com/buckleypaul/blescan/internal/stats"
+	"github.com/buckleypaul/blescan/internal/ui/styles"
+)
+
+// Calibration scopes passed to the save function
+const (
+	CalibrationScopeDevice = "device"
+	CalibrationScopeType   = "type"
+)
+
+const (
+	defaultCalibrationTime = 10 * time.Second
+	minCalibrationTime     = 5 * time.Second
+	maxCalibrationTime     = 60 * time.Second
+)
+
+// calibrationDistances are the distances the wizard asks for, in meters
+var calibrationDistances = []float64{1, 2, 4, 8}
+
+// CalibrationSaveFunc stores a fitted calibration for a device ID or device
+// type and returns where it was saved
+type CalibrationSaveFunc func(scope, key string, c stats.Calibration) (string, error)
+
+// CalibrationModel guides the user through measuring a device's RSSI at
+// known distances and fits a path loss model to the readings
+type CalibrationModel struct {
+	Device *ble.Device
+
+	duration   time.Duration
+	step       int // Index into calibrationDistances
+	collecting bool
+	stepStart  time.Time
+	lastAdv    time.Time // Newest advertisement already collected
+	readings   []int16
+	points     []stats.CalibrationPoint
+
+	result *stats.Calibration
+	err    error
+	status string
+	save   CalibrationSaveFunc
+
+	width  int
+	height int
+}
+
+// NewCalibrationModel starts a calibration of device
+func NewCalibrationModel(device *ble.Device, save CalibrationSaveFunc) CalibrationModel {
+	return CalibrationModel{Device: device, duration: defaultCalibrationTime, save: save}
+}
+
+// Update handles calibration wizard input
+func (m CalibrationModel) Update(msg tea.Msg) (CalibrationModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	case tea.KeyMsg:
+		if m.collecting {
+			return m, nil
+		}
+		switch msg.String() {
+		case "enter":
+			if m.result == nil && m.step < len(calibrationDistances) {
+				m.collecting = true
+				m.stepStart = time.Now()
+				m.lastAdv = m.stepStart
+				m.readings = nil
+				m.err = nil
+			}
+		case "+", "=":
+			if m.duration < maxCalibrationTime {
+				m.duration += 5 * time.Second
+			}
+		case "-":
+			if m.duration > minCalibrationTime {
+				m.duration -= 5 * time.Second
+			}
+		case "f":
+			// Finish early with the distances measured so far
+			if m.result == nil && len(m.points) >= 2 {
+				m.fit()
+			}
+		case "r":
+			// Start over
+			width, height := m.width, m.height
+			m = NewCalibrationModel(m.Device, m.save)
+			m.width, m.height = width, height
+		case "d":
+			if m.result == nil {
+				break
+			}
+			if m.rotatingAddress() {
+				// The next address gets a new ID the calibration wouldn't follow
+				m.status = "This device's address rotates, so a calibration saved for it would stop applying"
+				if m.Device.Class.Category != "" {
+					m.status += fmt.Sprintf("; press t to save for all %s devices", m.Device.Class.Category)
+				}
+				break
+			}
+			m.saveResult(CalibrationScopeDevice, m.Device.ID)
+		case "t":
+			if m.result != nil && m.Device.Class.Category != "" {
+				m.saveResult(CalibrationScopeType, m.Device.Class.Category)
+			}
+		}
+	}
+	return m, nil
+}
+
+// UpdateDevice collects the RSSI of advertisements received since the last
+// update while a distance is being measured
+func (m *CalibrationModel) UpdateDevice(device *ble.Device) {
+	m.Device = device
+	if !m.collecting {
+		return
+	}
+
+	for _, adv := range device.Advertisements {
+		if adv.Timestamp.After(m.lastAdv) {
+			m.readings = append(m.readings, adv.RSSI)
+			m.lastAdv = adv.Timestamp
+		}
+	}
+
+	if time.Since(m.stepStart) < m.duration {
+		return
+	}
+	m.collecting = false
+	if len(m.readings) == 0 {
+		m.err = fmt.Errorf("no advertisements received at %s, try again", formatMeters(calibrationDistances[m.step]))
+		return
+	}
+	m.points = append(m.points, stats.CalibrationPoint{Meters: calibrationDistances[m.step], RSSI: m.readings})
+	m.readings = nil
+	m.step++
+	if m.step == len(calibrationDistances) {
+		m.fit()
+	}
+}
+
+// DeviceLost ends the measurement in progress when the device is no longer
+// being tracked, e.g. after it went stale
+func (m *CalibrationModel) DeviceLost() {
+	if !m.collecting {
+		return
+	}
+	m.collecting = false
+	m.readings = nil
+	m.err = fmt.Errorf("lost the device while measuring at %s, try again once it is advertising", formatMeters(calibrationDistances[m.step]))
+}
+
+// rotatingAddress reports whether the device uses a private address that
+// changes over time and wasn't resolved to an identity, so its ID won't last
+func (m CalibrationModel) rotatingAddress() bool {
+	if m.Device.IdentityName != "" {
+		return false
+	}
+	return m.Device.AddressType == ble.AddressTypeResolvablePrivate ||
+		m.Device.AddressType == ble.AddressTypeNonResolvablePrivate
+}
+
+func (m *CalibrationModel) fit() {
+	c, err := stats.FitPathLoss(m.points)
+	if err != nil {
+		m.err = err
+		return
+	}
+	m.result = &c
+}
+
+func (m *CalibrationModel) saveResult(scope, key string) {
+	path, err := m.save(scope, key, *m.result)
+	if err != nil {
+		m.status = fmt.Sprintf("Save failed: %v", err)
+		return
+	}
+	if scope == CalibrationScopeType {
+		m.status = fmt.Sprintf("Saved for all %s devices to %s", key, path)
+	} else {
+		m.status = fmt.Sprintf("Saved for this device to %s", path)
+	}
+}
+
+func formatMeters(d float64) string {
+	return fmt.Sprintf("%g m", d)
+}
+
+// medianReading returns the median of RSSI readings
+func medianReading(readings []int16) int16 {
+	sorted := append([]int16(nil), readings...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
+
+// View renders the calibration wizard
+func (m CalibrationModel) View() string {
+	var b strings.Builder
+
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(styles.PrimaryColor).
+		Background(lipgloss.Color("235")).
+		Padding(0, 2).
+		Width(m.width)
+	b.WriteString(titleStyle.Render("RSSI Calibration: " + m.Device.GetDisplayName()))
+	b.WriteString("\n")
+
+	sectionStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(styles.SecondaryColor).
+		Padding(1, 2).
+		Width(m.width - 2)
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.SecondaryColor)
+	labelStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Width(20)
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+	promptStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.AccentColor)
+	errorStyle := lipgloss.NewStyle().Foreground(styles.ErrorColor)
+
+	var content strings.Builder
+	content.WriteString(headerStyle.Render("Measurements"))
+	content.WriteString("\n")
+	for i, d := range calibrationDistances {
+		content.WriteString("\n")
+		content.WriteString(labelStyle.Render(formatMeters(d) + ":"))
+		switch {
+		case i < len(m.points):
+			p := m.points[i]
+			content.WriteString(valueStyle.Render(fmt.Sprintf("median %d dBm (%d readings)", medianReading(p.RSSI), len(p.RSSI))))
+		case i == m.step && m.collecting:
+			content.WriteString(valueStyle.Render(m.progress()))
+		default:
+			content.WriteString(valueStyle.Render("-"))
+		}
+	}
+	content.WriteString("\n\n")
+
+	switch {
+	case m.result != nil:
+		r := m.result
+		content.WriteString(headerStyle.Render("Fitted Model"))
+		content.WriteString("\n\n")
+		content.WriteString(labelStyle.Render("Power at 1 m:"))
+		content.WriteString(valueStyle.Render(fmt.Sprintf("%.1f dBm", r.MeasuredPower)))
+		content.WriteString("\n")
+		content.WriteString(labelStyle.Render("Environment Factor:"))
+		content.WriteString(valueStyle.Render(fmt.Sprintf("%.2f", r.EnvFactor)))
+		content.WriteString("\n")
+		content.WriteString(labelStyle.Render("Fit (R²):"))
+		content.WriteString(valueStyle.Render(fmt.Sprintf("%.2f over %d readings", r.RSquared, r.Samples)))
+		content.WriteString("\n\n")
+		var prompt string
+		switch {
+		case !m.rotatingAddress():
+			prompt = "Press d to save for this device"
+			if m.Device.Class.Category != "" {
+				prompt += fmt.Sprintf(", t to save for all %s devices", m.Device.Class.Category)
+			}
+		case m.Device.Class.Category != "":
+			prompt = fmt.Sprintf("Press t to save for all %s devices (this device's address rotates)", m.Device.Class.Category)
+		default:
+			prompt = "This device's address rotates and its type is unknown, so the calibration can't be saved"
+		}
+		content.WriteString(promptStyle.Render(prompt))
+	case m.collecting:
+		content.WriteString(promptStyle.Render(fmt.Sprintf("Collecting at %s, keep the device still...", formatMeters(calibrationDistances[m.step]))))
+	default:
+		content.WriteString(promptStyle.Render(fmt.Sprintf("Place the device %s from this computer and press Enter (%ds per distance, +/- to change)",
+			formatMeters(calibrationDistances[m.step]), int(m.duration.Seconds()))))
+		if len(m.points) >= 2 {
+			content.WriteString("\n")
+			content.WriteString(valueStyle.Render("Or press f to fit the distances measured so far"))
+		}
+	}
+	if m.err != nil {
+		content.WriteString("\n\n")
+		content.WriteString(errorStyle.Render(m.err.Error()))
+	}
+	if m.status != "" {
+		content.WriteString("\n\n")
+		content.WriteString(valueStyle.Render(m.status))
+	}
+
+	b.WriteString(sectionStyle.Render(content.String()))
+	b.WriteString("\n")
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(styles.MutedColor).
+		Background(lipgloss.Color("235")).
+		Padding(0, 2).
+		Width(m.width)
+	b.WriteString(helpStyle.Render("Enter Measure • +/- Duration • f Fit • d/t Save • r Restart • Esc Back • q Quit"))
+
+	return b.String()
+}
+
+// progress describes the measurement in progress
+func (m CalibrationModel) progress() string {
+	elapsed := time.Since(m.stepStart)
+	const width = 20
+	filled := min(width, int(float64(width)*elapsed.Seconds()/m.duration.Seconds()))
+	bar := "[" + strings.Repeat("#", filled) + strings.Repeat(".", width-filled) + "]"
+	remaining := max(0, int((m.duration-elapsed).Seconds()+0.5))
+	s := fmt.Sprintf("%s %ds left, %d readings", bar, remaining, len(m.readings))
+	if len(m.readings) > 0 {
+		s += fmt.Sprintf(", median %d dBm", medianReading(m.readings))
+	}
+	return s
+}
@@ -2,8 +2,11 @@ package views
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/buckleypaul/blescan/internal/annotate"
 	"github.com/buckleypaul/blescan/internal/ble"
+	"github.com/buckleypaul/blescan/internal/stats"
 )
 
 // ColumnCategory represents the category of a column
@@ -213,7 +216,7 @@ var ColumnRegistry = []ColumnDefinition{
 		Formatter: func(d *ble.Device) string {
 			return d.FormatRawData()
 		},
-		Available: false, // TinyGo doesn't expose raw advertisement bytes
+		Available: true, // Only populated when replaying captures; TinyGo doesn't expose raw bytes
 	},
 	{
 		ID:           "company",
@@ -249,6 +252,258 @@ var ColumnRegistry = []ColumnDefinition{
 		},
 		Available: true,
 	},
+	{
+		ID:           "type",
+		Title:        "Type",
+		ShortTitle:   "Type",
+		Category:     CategoryMetadata,
+		MinWidth:     8,
+		DefaultWidth: 15,
+		WidthPct:     9,
+		ADTypes:      []uint8{},
+		Formatter: func(d *ble.Device) string {
+			return d.Class.String()
+		},
+		Available: true,
+	},
+	{
+		ID:           "phy",
+		Title:        "PHY",
+		ShortTitle:   "PHY",
+		Category:     CategoryMetadata,
+		MinWidth:     6,
+		DefaultWidth: 9,
+		WidthPct:     6,
+		ADTypes:      []uint8{},
+		Formatter: func(d *ble.Device) string {
+			if d.Extended == nil {
+				return "-"
+			}
+			return d.Extended.FormatPHY()
+		},
+		Available: true,
+	},
+	{
+		ID:           "sid",
+		Title:        "SID",
+		ShortTitle:   "SID",
+		Category:     CategoryMetadata,
+		MinWidth:     5,
+		DefaultWidth: 5,
+		WidthPct:     4,
+		ADTypes:      []uint8{},
+		Formatter: func(d *ble.Device) string {
+			if d.Extended == nil {
+				return "-"
+			}
+			return d.Extended.FormatSID()
+		},
+		Available: true,
+	},
+	{
+		ID:           "addr_type",
+		Title:        "Addr Type",
+		ShortTitle:   "AType",
+		Category:     CategoryMetadata,
+		MinWidth:     8,
+		DefaultWidth: 10,
+		WidthPct:     7,
+		ADTypes:      []uint8{},
+		Formatter: func(d *ble.Device) string {
+			return d.AddressType.ShortString()
+		},
+		Available: true,
+	},
+	{
+		ID:           "likely_same",
+		Title:        "Likely Same",
+		ShortTitle:   "Same",
+		Category:     CategoryMetadata,
+		MinWidth:     10,
+		DefaultWidth: 16,
+		WidthPct:     10,
+		ADTypes:      []uint8{},
+		Formatter: func(d *ble.Device) string {
+			if d.Correlation == nil {
+				return "-"
+			}
+			return fmt.Sprintf("%.0f%% %s", d.Correlation.Score*100, d.Correlation.CandidateName)
+		},
+		Available: true,
+	},
+	{
+		ID:           "decoded",
+		Title:        "Decoded",
+		ShortTitle:   "Proto",
+		Category:     CategoryAdvertisement,
+		MinWidth:     12,
+		DefaultWidth: 24,
+		WidthPct:     15,
+		ADTypes:      []uint8{0x16, 0xFF},
+		Formatter: func(d *ble.Device) string {
+			return d.FormatDecoded()
+		},
+		Available: true,
+	},
+	{
+		ID:           "mesh",
+		Title:        "Mesh",
+		ShortTitle:   "Mesh",
+		Category:     CategoryAdvertisement,
+		MinWidth:     8,
+		DefaultWidth: 14,
+		WidthPct:     9,
+		ADTypes:      []uint8{0x16, 0x29, 0x2A, 0x2B},
+		Formatter: func(d *ble.Device) string {
+			if d.Mesh == nil {
+				return "-"
+			}
+			return d.Mesh.State.String()
+		},
+		Available: true,
+	},
+	{
+		ID:           "mesh_net",
+		Title:        "Mesh Network",
+		ShortTitle:   "MeshNet",
+		Category:     CategoryAdvertisement,
+		MinWidth:     8,
+		DefaultWidth: 18,
+		WidthPct:     10,
+		ADTypes:      []uint8{0x16, 0x2B},
+		Formatter: func(d *ble.Device) string {
+			switch {
+			case d.Mesh == nil:
+				return "-"
+			case d.Mesh.NetworkID != "":
+				return d.Mesh.NetworkID
+			case d.Mesh.DeviceUUID != "":
+				return "uuid " + d.Mesh.DeviceUUID
+			case d.Mesh.NodeIdentity:
+				return "node identity"
+			}
+			return "-"
+		},
+		Available: true,
+	},
+	{
+		ID:           "mesh_iv",
+		Title:        "IV Index",
+		ShortTitle:   "IV",
+		Category:     CategoryAdvertisement,
+		MinWidth:     6,
+		DefaultWidth: 10,
+		WidthPct:     6,
+		ADTypes:      []uint8{0x2B},
+		Formatter: func(d *ble.Device) string {
+			if d.Mesh == nil {
+				return "-"
+			}
+			return d.Mesh.FormatIVIndex()
+		},
+		Available: true,
+	},
+	{
+		ID:           "commission",
+		Title:        "Commissioning",
+		ShortTitle:   "Comm",
+		Category:     CategoryAdvertisement,
+		MinWidth:     8,
+		DefaultWidth: 14,
+		WidthPct:     9,
+		ADTypes:      []uint8{0x16, 0xFF},
+		Formatter: func(d *ble.Device) string {
+			if d.Commissioning == nil {
+				return "-"
+			}
+			return d.Commissioning.Protocol + " " + d.Commissioning.Status()
+		},
+		Available: true,
+	},
+	{
+		ID:           "tracker",
+		Title:        "Tracker",
+		ShortTitle:   "Trk",
+		Category:     CategoryAdvertisement,
+		MinWidth:     8,
+		DefaultWidth: 18,
+		WidthPct:     10,
+		ADTypes:      []uint8{0x16, 0xFF},
+		Formatter: func(d *ble.Device) string {
+			if d.Tracker == nil {
+				return "-"
+			}
+			return d.Tracker.Kind + " " + d.Tracker.FormatState()
+		},
+		Available: true,
+	},
+	{
+		ID:           "ead",
+		Title:        "Encryption",
+		ShortTitle:   "EAD",
+		Category:     CategoryAdvertisement,
+		MinWidth:     8,
+		DefaultWidth: 10,
+		WidthPct:     7,
+		ADTypes:      []uint8{0x31},
+		Formatter: func(d *ble.Device) string {
+			return d.EAD.String()
+		},
+		Available: true,
+	},
+	{
+		ID:           "lint",
+		Title:        "Lint",
+		ShortTitle:   "Lint",
+		Category:     CategoryMetadata,
+		MinWidth:     6,
+		DefaultWidth: 8,
+		WidthPct:     6,
+		ADTypes:      []uint8{},
+		Formatter: func(d *ble.Device) string {
+			errs, warns := ble.CountLintFindings(d.LintFindings)
+			if errs == 0 && warns == 0 {
+				return "-"
+			}
+			return fmt.Sprintf("%dE %dW", errs, warns)
+		},
+		Available: true,
+	},
+	{
+		ID:           "anomalies",
+		Title:        "Anomalies",
+		ShortTitle:   "Anom",
+		Category:     CategoryMetadata,
+		MinWidth:     6,
+		DefaultWidth: 10,
+		WidthPct:     6,
+		ADTypes:      []uint8{},
+		Formatter: func(d *ble.Device) string {
+			if d.AnomalyCount == 0 {
+				return "-"
+			}
+			return fmt.Sprintf("%d", d.AnomalyCount)
+		},
+		Available: true,
+	},
+	{
+		ID:           "distance",
+		Title:        "Distance",
+		ShortTitle:   "Dist",
+		Category:     CategoryMetadata,
+		MinWidth:     8,
+		DefaultWidth: 16,
+		WidthPct:     9,
+		ADTypes:      []uint8{},
+		Formatter: func(d *ble.Device) string {
+			e, ok := stats.EstimateDistance(d)
+			if !ok {
+				return "-"
+			}
+			return e.String()
+		},
+		Available: true,
+	},
 	{
 		ID:           "rssi",
 		Title:        "RSSI",
@@ -259,7 +514,21 @@ var ColumnRegistry = []ColumnDefinition{
 		WidthPct:     9,
 		ADTypes:      []uint8{},
 		Formatter: func(d *ble.Device) string {
-			return fmt.Sprintf("%.1f", d.RSSIAverage)
+			return fmt.Sprintf("%.1f", d.RSSISmoothed)
+		},
+		Available: true,
+	},
+	{
+		ID:           "rssi_raw",
+		Title:        "Raw RSSI",
+		ShortTitle:   "Raw",
+		Category:     CategoryMetadata,
+		MinWidth:     6,
+		DefaultWidth: 9,
+		WidthPct:     8,
+		ADTypes:      []uint8{},
+		Formatter: func(d *ble.Device) string {
+			return fmt.Sprintf("%d", d.RSSICurrent)
 		},
 		Available: true,
 	},
@@ -277,6 +546,47 @@ var ColumnRegistry = []ColumnDefinition{
 		},
 		Available: true,
 	},
+	{
+		ID:           "fields",
+		Title:        "Fields",
+		ShortTitle:   "Fld",
+		Category:     CategoryMetadata,
+		MinWidth:     10,
+		DefaultWidth: 20,
+		WidthPct:     12,
+		ADTypes:      []uint8{0x16, 0x20, 0x21, 0xFF},
+		Formatter: func(d *ble.Device) string {
+			var values []string
+			for _, f := range annotate.ForDevice(d) {
+				if v, ok := annotate.Latest(d, f); ok {
+					values = append(values, f.Name+"="+f.Format(v))
+				}
+			}
+			if len(values) == 0 {
+				return "-"
+			}
+			return strings.Join(values, " ")
+		},
+		Available: true,
+	},
+	{
+		ID:           "prr",
+		Title:        "PRR",
+		ShortTitle:   "PRR",
+		Category:     CategoryMetadata,
+		MinWidth:     5,
+		DefaultWidth: 6,
+		WidthPct:     6,
+		ADTypes:      []uint8{},
+		Formatter: func(d *ble.Device) string {
+			r, ok := stats.EstimateReception(d)
+			if !ok {
+				return "-"
+			}
+			return r.String()
+		},
+		Available: true,
+	},
 	{
 		ID:           "interval",
 		Title:        "Interval",
@@ -306,6 +616,7 @@ func DefaultEnabledColumns() []string {
 		"appearance",
 		"unknown_ad",
 		"company",
+		"type",
 		"rssi",
 		"count",
 		"interval",
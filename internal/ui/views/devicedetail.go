@@ -2,20 +2,24 @@ package views
 
 import (
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/buckleypaul/blescan/internal/annotate"
 	"github.com/buckleypaul/blescan/internal/ble"
 	"github.com/buckleypaul/blescan/internal/stats"
+	"github.com/buckleypaul/blescan/internal/stats/history"
+	"github.com/buckleypaul/blescan/internal/stats/window"
 	"github.com/buckleypaul/blescan/internal/ui/styles"
 )
 
 // DeviceDetailModel represents the device detail view
 type DeviceDetailModel struct {
-	Device   ble.Device
+	Device   *ble.Device
 	viewport viewport.Model
 	width    int
 	height   int
@@ -23,7 +27,7 @@ type DeviceDetailModel struct {
 }
 
 // NewDeviceDetailModel creates a new device detail model
-func NewDeviceDetailModel(device ble.Device) DeviceDetailModel {
+func NewDeviceDetailModel(device *ble.Device) DeviceDetailModel {
 	return DeviceDetailModel{
 		Device: device,
 	}
@@ -113,7 +117,7 @@ func (m DeviceDetailModel) View() string {
 		Width(m.width)
 
 	scrollPercent := fmt.Sprintf("%3.f%%", m.viewport.ScrollPercent()*100)
-	help := "↑/↓ Scroll • Esc Back • q Quit"
+	help := "↑/↓ Scroll • i Intervals • p Payload diff • c Calibrate distance • Esc Back • q Quit"
 	helpContent := help + strings.Repeat(" ", max(0, m.width-len(help)-len(scrollPercent)-6)) + scrollPercent
 	b.WriteString(helpStyle.Render(helpContent))
 
@@ -123,12 +127,60 @@ func (m DeviceDetailModel) View() string {
 func (m DeviceDetailModel) renderContent() string {
 	var sections []string
 
+	// Identity section
+	sections = append(sections, m.renderIdentitySection())
+
 	// Signal section
 	sections = append(sections, m.renderSignalSection())
 
 	// Statistics section
 	sections = append(sections, m.renderStatsSection())
 
+	// Rolling statistics per window
+	if len(m.Device.Windows) > 0 {
+		sections = append(sections, m.renderWindowsSection())
+	}
+
+	// Packet reception over time
+	if r, ok := stats.EstimateReception(m.Device); ok {
+		sections = append(sections, m.renderReceptionSection(r))
+	}
+
+	// Long-term RSSI and payload trends
+	if len(m.Device.SecondTrend) > 1 {
+		sections = append(sections, m.renderTrendSection())
+	}
+
+	// Annotated payload fields over time
+	if fields := annotate.ForDevice(m.Device); len(fields) > 0 {
+		sections = append(sections, m.renderFieldsSection(fields))
+	}
+
+	// Smart-home commissioning
+	if m.Device.Commissioning != nil {
+		sections = append(sections, m.renderCommissioningSection())
+	}
+
+	// Decoded protocol payloads
+	if len(m.Device.Decoded) > 0 {
+		sections = append(sections, m.renderDecodedSection())
+	}
+
+	// Spec compliance warnings
+	if len(m.Device.LintFindings) > 0 {
+		sections = append(sections, m.renderLintSection())
+	}
+
+	// Spoofing baseline and anomalies
+	if m.Device.Baseline != nil {
+		sections = append(sections, m.renderAnomalySection())
+	}
+
+	// Extended advertising section
+	if m.Device.Extended != nil {
+		sections = append(sections, m.renderExtendedSection())
+	}
+
 	// AD Types section
 	adTypes := m.Device.GetADTypes()
 	if len(adTypes) > 0 {
@@ -141,6 +193,75 @@ func (m DeviceDetailModel) renderContent() string {
 	return strings.Join(sections, "\n\n")
 }
 
+func (m DeviceDetailModel) renderIdentitySection() string {
+	sectionStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(styles.SecondaryColor).
+		Padding(0, 2).
+		Width(m.width - 8)
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.SecondaryColor)
+	labelStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Width(16)
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+
+	var content strings.Builder
+	content.WriteString(headerStyle.Render("Identity"))
+	content.WriteString("\n\n")
+
+	if m.Device.IdentityName != "" {
+		content.WriteString(labelStyle.Render("Identity:"))
+		content.WriteString(valueStyle.Render(m.Device.IdentityName + " (resolved via IRK)"))
+		content.WriteString("\n")
+	}
+
+	content.WriteString(labelStyle.Render("Type:"))
+	content.WriteString(valueStyle.Render(m.Device.Class.String()))
+	content.WriteString("\n")
+	if len(m.Device.Class.Reasons) > 0 {
+		content.WriteString(labelStyle.Render("Type Evidence:"))
+		content.WriteString(valueStyle.Render(strings.Join(m.Device.Class.Reasons, ", ")))
+		content.WriteString("\n")
+	}
+
+	content.WriteString(labelStyle.Render("Address:"))
+	content.WriteString(valueStyle.Render(m.Device.Address))
+	content.WriteString("\n")
+
+	content.WriteString(labelStyle.Render("Address Type:"))
+	content.WriteString(valueStyle.Render(m.Device.AddressType.String()))
+
+	if m.Device.AddressType == ble.AddressTypePublic {
+		content.WriteString("\n")
+		content.WriteString(labelStyle.Render("OUI Vendor:"))
+		content.WriteString(valueStyle.Render(m.Device.FormatAddressVendor()))
+	}
+
+	if c := m.Device.Correlation; c != nil {
+		content.WriteString("\n")
+		content.WriteString(labelStyle.Render("Likely Same As:"))
+		content.WriteString(valueStyle.Render(fmt.Sprintf("%s (%.0f%% confidence, m in list to merge)", c.CandidateName, c.Score*100)))
+		content.WriteString("\n")
+		content.WriteString(labelStyle.Render("Evidence:"))
+		content.WriteString(valueStyle.Render(strings.Join(c.Reasons, ", ")))
+	}
+
+	// Address history is only interesting once a device has rotated addresses
+	if len(m.Device.AddressHistory) > 1 {
+		timeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+		content.WriteString("\n\n")
+		content.WriteString(headerStyle.Render(fmt.Sprintf("Address History (%d)", len(m.Device.AddressHistory))))
+		for i := len(m.Device.AddressHistory) - 1; i >= 0; i-- {
+			sighting := m.Device.AddressHistory[i]
+			content.WriteString("\n")
+			content.WriteString(valueStyle.Render(sighting.Address))
+			content.WriteString("  ")
+			content.WriteString(timeStyle.Render(sighting.FirstSeen.Format("15:04:05") + " - " + sighting.LastSeen.Format("15:04:05")))
+		}
+	}
+
+	return sectionStyle.Render(content.String())
+}
+
 func (m DeviceDetailModel) renderSignalSection() string {
 	sectionStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
@@ -164,6 +285,10 @@ func (m DeviceDetailModel) renderSignalSection() string {
 	content.WriteString(rssiColor.Render(fmt.Sprintf("%d dBm", m.Device.RSSICurrent)))
 	content.WriteString("\n")
 
+	content.WriteString(labelStyle.Render("Smoothed RSSI:"))
+	content.WriteString(valueStyle.Render(fmt.Sprintf("%.1f dBm (%s)", m.Device.RSSISmoothed, ble.RSSISmoothing())))
+	content.WriteString("\n")
+
 	content.WriteString(labelStyle.Render("Average RSSI:"))
 	content.WriteString(valueStyle.Render(fmt.Sprintf("%.1f dBm", m.Device.RSSIAverage)))
 	content.WriteString("\n")
@@ -173,6 +298,15 @@ func (m DeviceDetailModel) renderSignalSection() string {
 	content.WriteString(qualityStyle.Render(stats.SignalStrengthLabel(deviceStats.SignalStrength)))
 	content.WriteString("\n")
 
+	if e, ok := stats.EstimateDistance(m.Device); ok {
+		content.WriteString(labelStyle.Render("Distance:"))
+		content.WriteString(valueStyle.Render(fmt.Sprintf("%s, %s (smoothed RSSI %.0f dBm)", e.String(), e.Proximity, e.RSSI)))
+		content.WriteString("\n")
+		content.WriteString(labelStyle.Render("1 m Power:"))
+		content.WriteString(valueStyle.Render(fmt.Sprintf("%.0f dBm (%s), n = %.1f", e.MeasuredPower, e.PowerSource, e.EnvFactor)))
+		content.WriteString("\n")
+	}
+
 	if m.Device.TxPowerLevel != nil {
 		content.WriteString(labelStyle.Render("TX Power:"))
 		content.WriteString(valueStyle.Render(fmt.Sprintf("%d dBm", *m.Device.TxPowerLevel)))
@@ -231,6 +365,544 @@ func (m DeviceDetailModel) renderStatsSection() string {
 	content.WriteString(labelStyle.Render("Last Seen:"))
 	content.WriteString(valueStyle.Render(m.Device.LastSeen.Format("15:04:05")))
 
+	if p := m.Device.Presence; p != nil {
+		content.WriteString("\n")
+		content.WriteString(labelStyle.Render("Visits:"))
+		content.WriteString(valueStyle.Render(fmt.Sprintf("%d, %s total", p.Count, formatDuration(p.Dwell))))
+	}
+
+	return sectionStyle.Render(content.String())
+}
+
+func (m DeviceDetailModel) renderWindowsSection() string {
+	sectionStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(styles.SecondaryColor).
+		Padding(0, 2).
+		Width(m.width - 8)
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.SecondaryColor)
+	labelStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Width(16)
+	columnStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Width(16)
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255")).Width(16)
+
+	var content strings.Builder
+	content.WriteString(headerStyle.Render("Rolling Statistics"))
+	content.WriteString("\n\n")
+
+	content.WriteString(labelStyle.Render(""))
+	for _, w := range m.Device.Windows {
+		content.WriteString(columnStyle.Render(w.Window.Name))
+	}
+
+	rows := []struct {
+		label    string
+		interval bool // Needs measured intervals
+		format   func(s window.Summary) string
+	}{
+		{"Advertisements:", false, func(s window.Summary) string { return fmt.Sprintf("%d", s.Adverts) }},
+		{"Rate:", false, func(s window.Summary) string { return fmt.Sprintf("%.1f/sec", s.Rate) }},
+		{"RSSI Min/Max:", false, func(s window.Summary) string { return fmt.Sprintf("%d / %d", s.RSSIMin, s.RSSIMax) }},
+		{"RSSI Mean:", false, func(s window.Summary) string { return fmt.Sprintf("%.1f ±%.1f", s.RSSIMean, s.RSSIStdDev) }},
+		{"RSSI p50/p95:", false, func(s window.Summary) string { return fmt.Sprintf("%d / %d", s.RSSIP50, s.RSSIP95) }},
+		{"Interval Mean:", true, func(s window.Summary) string { return formatInterval(s.IntervalMean) }},
+		{"Interval Median:", true, func(s window.Summary) string { return formatInterval(s.IntervalMedian) }},
+		{"Jitter:", true, func(s window.Summary) string { return fmt.Sprintf("%.1fms", float64(s.Jitter.Microseconds())/1000) }},
+		{"Est. Loss:", true, func(s window.Summary) string { return fmt.Sprintf("%.0f%%", s.Loss*100) }},
+	}
+	for _, r := range rows {
+		content.WriteString("\n")
+		content.WriteString(labelStyle.Render(r.label))
+		for _, s := range m.Device.Windows {
+			value := "-"
+			if s.Adverts > 0 && (!r.interval || s.Intervals > 0) {
+				value = r.format(s)
+			}
+			content.WriteString(valueStyle.Render(value))
+		}
+	}
+
+	return sectionStyle.Render(content.String())
+}
+
+// receptionChartRows is the height of the reception chart in lines
+const receptionChartRows = 4
+
+func (m DeviceDetailModel) renderReceptionSection(r stats.Reception) string {
+	sectionStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(styles.SecondaryColor).
+		Padding(0, 2).
+		Width(m.width - 8)
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.SecondaryColor)
+	labelStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Width(16)
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+	exactStyle := lipgloss.NewStyle().Foreground(styles.SuccessColor)
+	estimateStyle := lipgloss.NewStyle().Foreground(styles.PrimaryColor)
+	axisStyle := lipgloss.NewStyle().Foreground(styles.MutedColor)
+
+	var content strings.Builder
+	content.WriteString(headerStyle.Render("Packet Reception"))
+	content.WriteString("\n\n")
+
+	content.WriteString(labelStyle.Render("PRR:"))
+	if r.Exact {
+		content.WriteString(valueStyle.Render(fmt.Sprintf("%s (%d of %d counted by %s)", r, r.Received, r.Sent, r.Source)))
+	} else {
+		content.WriteString(valueStyle.Render(fmt.Sprintf("%s (estimated from the %s base interval)", r, formatMillis(r.Base))))
+	}
+	if m.Device.Counter != nil && m.Device.Counter.Restarts > 0 {
+		content.WriteString("\n")
+		content.WriteString(labelStyle.Render("Counter Resets:"))
+		content.WriteString(valueStyle.Render(fmt.Sprintf("%d", m.Device.Counter.Restarts)))
+	}
+
+	// Base interval for the buckets without counter data
+	base := r.Base
+	if a, ok := stats.AnalyzeIntervals(m.Device.Arrivals); ok {
+		base = a.Base
+	}
+	points := stats.ReceptionOverTime(m.Device, base, time.Now())
+	if width := m.width - 30; len(points) > width && width > 0 {
+		points = points[len(points)-width:]
+	}
+	if len(points) == 0 {
+		return sectionStyle.Render(content.String())
+	}
+
+	columns := make([]chartColumn, len(points))
+	for i, p := range points {
+		columns[i] = chartColumn{height: p.Ratio, known: p.Known, style: estimateStyle}
+		if p.Exact {
+			columns[i].style = exactStyle
+		}
+	}
+	content.WriteString("\n")
+	content.WriteString(renderColumnChart(columns, receptionChartRows, "100%", "0%"))
+	content.WriteString("\n")
+	span := time.Duration(len(points)) * ble.ReceptionBucket
+	axis := fmt.Sprintf("-%s", span.Round(time.Second))
+	content.WriteString(labelStyle.Render(""))
+	content.WriteString(axisStyle.Render(axis + strings.Repeat(" ", max(1, len(points)-len(axis)-1)) + "now"))
+	content.WriteString("\n")
+	content.WriteString(labelStyle.Render(""))
+	content.WriteString(axisStyle.Render(fmt.Sprintf("%s per column; ", ble.ReceptionBucket)))
+	content.WriteString(exactStyle.Render("counted"))
+	content.WriteString(axisStyle.Render(", "))
+	content.WriteString(estimateStyle.Render("estimated"))
+
+	return sectionStyle.Render(content.String())
+}
+
+// fieldChartRows is the height of each annotated field's chart in lines
+const fieldChartRows = 3
+
+func (m DeviceDetailModel) renderFieldsSection(fields []annotate.Field) string {
+	sectionStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(styles.SecondaryColor).
+		Padding(0, 2).
+		Width(m.width - 8)
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.SecondaryColor)
+	labelStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Width(16)
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+	barStyle := lipgloss.NewStyle().Foreground(styles.PrimaryColor)
+	axisStyle := lipgloss.NewStyle().Foreground(styles.MutedColor)
+
+	var content strings.Builder
+	content.WriteString(headerStyle.Render("Annotated Fields"))
+	for _, f := range fields {
+		content.WriteString("\n\n")
+		content.WriteString(labelStyle.Render(f.Name + ":"))
+		samples := annotate.Series(m.Device.Advertisements, f)
+		if len(samples) == 0 {
+			content.WriteString(valueStyle.Render("-"))
+			content.WriteString(axisStyle.Render(fmt.Sprintf("  (%s at byte %d of %s)", f.Type, f.Offset, f.Source)))
+			continue
+		}
+		low, high := samples[0].Value, samples[0].Value
+		for _, s := range samples {
+			low = math.Min(low, s.Value)
+			high = math.Max(high, s.Value)
+		}
+		content.WriteString(valueStyle.Render(f.Format(samples[len(samples)-1].Value)))
+		content.WriteString(axisStyle.Render(fmt.Sprintf("  (%s at byte %d of %s; %s to %s over %d adverts)",
+			f.Type, f.Offset, f.Source, f.Format(low), f.Format(high), len(samples))))
+
+		// One column per advertisement, newest on the right
+		if width := m.width - 30; len(samples) > width && width > 0 {
+			samples = samples[len(samples)-width:]
+		}
+		columns := make([]chartColumn, len(samples))
+		for i, s := range samples {
+			// The lowest value still gets an eighth of a block
+			frac := 0.5
+			if high > low {
+				frac = (s.Value - low) / (high - low)
+			}
+			height := (1 + frac*(fieldChartRows*8-1)) / (fieldChartRows * 8)
+			columns[i] = chartColumn{height: height, known: true, style: barStyle}
+		}
+		content.WriteString("\n")
+		content.WriteString(renderColumnChart(columns, fieldChartRows, f.Format(high), f.Format(low)))
+		content.WriteString("\n")
+		from := samples[0].Time.Format("15:04:05")
+		to := samples[len(samples)-1].Time.Format("15:04:05")
+		content.WriteString(labelStyle.Render(""))
+		content.WriteString(axisStyle.Render(from + strings.Repeat(" ", max(1, len(samples)-len(from)-len(to))) + to))
+	}
+
+	return sectionStyle.Render(content.String())
+}
+
+// trendChartRows is the height of each long-term history chart in lines
+const trendChartRows = 3
+
+func (m DeviceDetailModel) renderTrendSection() string {
+	sectionStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(styles.SecondaryColor).
+		Padding(0, 2).
+		Width(m.width - 8)
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.SecondaryColor)
+	labelStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Width(16)
+	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+	barStyle := lipgloss.NewStyle().Foreground(styles.PrimaryColor)
+	changeStyle := lipgloss.NewStyle().Foreground(styles.AccentColor)
+	axisStyle := lipgloss.NewStyle().Foreground(styles.MutedColor)
+
+	// Per-second points while they reach back as far as the per-minute
+	// ones, per-minute ones after that
+	points, period, tier := m.Device.SecondTrend, time.Second, "per-second"
+	if minutes := m.Device.MinuteTrend; len(minutes) > 1 && minutes[0].Start.Before(points[0].Start.Truncate(time.Minute)) {
+		points, period, tier = m.Device.MinuteTrend, time.Minute, "per-minute"
+	}
+	span := points[len(points)-1].Start.Add(period).Sub(points[0].Start)
+	columns, width := history.Resample(points, period, max(10, m.width-30))
+
+	var content strings.Builder
+	content.WriteString(headerStyle.Render("Long-term History"))
+	content.WriteString(axisStyle.Render(fmt.Sprintf("  %s of %s points, %s per column",
+		formatDuration(span), tier, formatDuration(width))))
+
+	// chart draws one metric per column, scaled between its extremes
+	chart := func(title string, style lipgloss.Style, value func(history.Point) (float64, bool), format func(float64) string) {
+		low, high := math.Inf(1), math.Inf(-1)
+		values := make([]float64, len(columns))
+		known := make([]bool, len(columns))
+		for i, p := range columns {
+			if values[i], known[i] = value(p); known[i] {
+				low = math.Min(low, values[i])
+				high = math.Max(high, values[i])
+			}
+		}
+		if math.IsInf(low, 1) {
+			return
+		}
+		bars := make([]chartColumn, len(columns))
+		for i := range columns {
+			frac := 0.5
+			if high > low {
+				frac = (values[i] - low) / (high - low)
+			}
+			// The lowest value still gets an eighth of a block
+			height := (1 + frac*(trendChartRows*8-1)) / (trendChartRows * 8)
+			bars[i] = chartColumn{height: height, known: known[i], style: style}
+		}
+		content.WriteString("\n\n")
+		content.WriteString(labelStyle.Render(""))
+		content.WriteString(titleStyle.Render(title))
+		content.WriteString("\n")
+		content.WriteString(renderColumnChart(bars, trendChartRows, format(high), format(low)))
+	}
+
+	chart("RSSI, mean", barStyle, func(p history.Point) (float64, bool) {
+		return p.RSSIMean(), p.Adverts > 0
+	}, func(v float64) string { return fmt.Sprintf("%.0f dBm", v) })
+	chart("Advertising rate", barStyle, func(p history.Point) (float64, bool) {
+		return float64(p.Adverts) / width.Seconds(), p.Adverts > 0
+	}, func(v float64) string { return fmt.Sprintf("%.1f/s", v) })
+	chart("Payload changes", changeStyle, func(p history.Point) (float64, bool) {
+		return float64(p.PayloadChanges), p.Adverts > 0
+	}, func(v float64) string { return fmt.Sprintf("%.0f", v) })
+	for _, f := range annotate.ForDevice(m.Device) {
+		f := f
+		chart(f.Name+", last per column", barStyle, func(p history.Point) (float64, bool) {
+			if p.Adverts == 0 {
+				return 0, false
+			}
+			return f.Value(ble.Advertisement{ManufacturerData: p.ManufacturerData, ServiceData: p.ServiceData})
+		}, f.Format)
+	}
+
+	from := points[0].Start.Format("15:04:05")
+	to := points[len(points)-1].Start.Add(period).Format("15:04:05")
+	content.WriteString("\n")
+	content.WriteString(labelStyle.Render(""))
+	content.WriteString(axisStyle.Render(from + strings.Repeat(" ", max(1, len(columns)-len(from)-len(to))) + to))
+
+	return sectionStyle.Render(content.String())
+}
+
+// chartColumn is one column of a column chart
+type chartColumn struct {
+	height float64 // Fraction of the chart height, 0 to 1
+	known  bool
+	style  lipgloss.Style
+}
+
+// renderColumnChart draws columns filled from the bottom with eighth blocks,
+// labelling the top and bottom rows. Unknown columns are dotted on the
+// baseline.
+func renderColumnChart(columns []chartColumn, rows int, top, bottom string) string {
+	labelStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Width(16)
+	axisStyle := lipgloss.NewStyle().Foreground(styles.MutedColor)
+	blocks := []rune(" ▁▂▃▄▅▆▇█")
+
+	lines := make([]string, 0, rows)
+	for row := rows - 1; row >= 0; row-- {
+		var line strings.Builder
+		label := ""
+		switch row {
+		case rows - 1:
+			label = top
+		case 0:
+			label = bottom
+		}
+		line.WriteString(labelStyle.Render(fmt.Sprintf("%14s │", label)))
+		for _, c := range columns {
+			if !c.known {
+				cell := " "
+				if row == 0 {
+					cell = "·"
+				}
+				line.WriteString(axisStyle.Render(cell))
+				continue
+			}
+			eighths := int(c.height*float64(rows*8)+0.5) - row*8
+			eighths = max(0, min(8, eighths))
+			line.WriteString(c.style.Render(string(blocks[eighths])))
+		}
+		lines = append(lines, line.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m DeviceDetailModel) renderExtendedSection() string {
+	sectionStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(styles.SecondaryColor).
+		Padding(0, 2).
+		Width(m.width - 8)
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.SecondaryColor)
+	labelStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Width(16)
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+
+	ext := m.Device.Extended
+
+	var content strings.Builder
+	content.WriteString(headerStyle.Render("Extended Advertising"))
+	content.WriteString("\n\n")
+
+	content.WriteString(labelStyle.Render("Primary PHY:"))
+	content.WriteString(valueStyle.Render(ext.PrimaryPHY.String()))
+	content.WriteString("\n")
+
+	content.WriteString(labelStyle.Render("Secondary PHY:"))
+	content.WriteString(valueStyle.Render(ext.SecondaryPHY.String()))
+	content.WriteString("\n")
+
+	content.WriteString(labelStyle.Render("Adv SID:"))
+	content.WriteString(valueStyle.Render(ext.FormatSID()))
+	content.WriteString("\n")
+
+	if ext.PeriodicInterval > 0 {
+		content.WriteString(labelStyle.Render("Periodic Adv:"))
+		content.WriteString(valueStyle.Render(fmt.Sprintf("%.2fms", float64(ext.PeriodicInterval.Microseconds())/1000)))
+		content.WriteString("\n")
+	}
+
+	payloadLen := 0
+	if n := len(m.Device.Advertisements); n > 0 {
+		payloadLen = len(m.Device.Advertisements[n-1].RawData)
+	}
+	content.WriteString(labelStyle.Render("Data:"))
+	content.WriteString(valueStyle.Render(fmt.Sprintf("%d bytes, %s, %d fragment(s)", payloadLen, ext.DataStatus, ext.Fragments)))
+
+	return sectionStyle.Render(content.String())
+}
+
+func (m DeviceDetailModel) renderCommissioningSection() string {
+	sectionStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(styles.SecondaryColor).
+		Padding(0, 2).
+		Width(m.width - 8)
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.SecondaryColor)
+	labelStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Width(16)
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+
+	c := m.Device.Commissioning
+	status := "Paired / not advertising for commissioning"
+	statusStyle := valueStyle
+	if c.Ready {
+		status = "Ready to commission"
+		statusStyle = lipgloss.NewStyle().Foreground(styles.SuccessColor).Bold(true)
+	}
+
+	var content strings.Builder
+	content.WriteString(headerStyle.Render("Commissioning"))
+	content.WriteString("\n\n")
+
+	content.WriteString(labelStyle.Render("Protocol:"))
+	content.WriteString(valueStyle.Render(c.Protocol))
+	content.WriteString("\n")
+
+	content.WriteString(labelStyle.Render("Status:"))
+	content.WriteString(statusStyle.Render(status))
+
+	for _, f := range c.Fields {
+		content.WriteString("\n")
+		content.WriteString(labelStyle.Render(f.Name + ":"))
+		content.WriteString(valueStyle.Render(f.Value))
+	}
+
+	return sectionStyle.Render(content.String())
+}
+
+func (m DeviceDetailModel) renderDecodedSection() string {
+	sectionStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(styles.SecondaryColor).
+		Padding(0, 2).
+		Width(m.width - 8)
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.SecondaryColor)
+	protocolStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.PrimaryColor)
+	labelStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Width(20)
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+
+	var content strings.Builder
+	content.WriteString(headerStyle.Render("Decoded Payloads"))
+
+	for _, p := range m.Device.Decoded {
+		content.WriteString("\n\n")
+		content.WriteString(protocolStyle.Render(p.Protocol))
+
+		fields := []ble.ADType{{Name: "Intent", Value: p.Intent}}
+		if p.Name != "" {
+			fields = append(fields, ble.ADType{Name: "Display Name", Value: p.Name})
+		}
+		fields = append(fields, p.Fields...)
+		for _, f := range fields {
+			if f.Value == "" {
+				continue
+			}
+			content.WriteString("\n")
+			content.WriteString(labelStyle.Render(f.Name + ":"))
+			content.WriteString(valueStyle.Render(f.Value))
+		}
+	}
+
+	return sectionStyle.Render(content.String())
+}
+
+func (m DeviceDetailModel) renderLintSection() string {
+	sectionStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(styles.AccentColor).
+		Padding(0, 2).
+		Width(m.width - 8)
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.AccentColor)
+	errorStyle := lipgloss.NewStyle().Foreground(styles.ErrorColor).Width(10)
+	warnStyle := lipgloss.NewStyle().Foreground(styles.AccentColor).Width(10)
+	codeStyle := lipgloss.NewStyle().Foreground(styles.MutedColor)
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+
+	var content strings.Builder
+	content.WriteString(headerStyle.Render(fmt.Sprintf("Spec Warnings (%d of %d advertisements)", m.Device.LintCount, m.Device.AdvCount)))
+	content.WriteString("\n")
+
+	for _, f := range m.Device.LintFindings {
+		content.WriteString("\n")
+		if f.Severity == ble.LintError {
+			content.WriteString(errorStyle.Render("error"))
+		} else {
+			content.WriteString(warnStyle.Render("warning"))
+		}
+		code := f.Code
+		if f.Offset >= 0 {
+			code = fmt.Sprintf("%s @%d", f.Code, f.Offset)
+		}
+		content.WriteString(codeStyle.Render(code + "  "))
+		content.WriteString(valueStyle.Render(f.Message))
+	}
+
+	return sectionStyle.Render(content.String())
+}
+
+func (m DeviceDetailModel) renderAnomalySection() string {
+	color := styles.SecondaryColor
+	if m.Device.AnomalyCount > 0 {
+		color = styles.ErrorColor
+	}
+	sectionStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(color).
+		Padding(0, 2).
+		Width(m.width - 8)
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(color)
+	labelStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Width(16)
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+	timeStyle := lipgloss.NewStyle().Foreground(styles.MutedColor)
+	kindStyle := lipgloss.NewStyle().Foreground(styles.ErrorColor).Width(24)
+
+	var content strings.Builder
+	content.WriteString(headerStyle.Render(fmt.Sprintf("Baseline & Anomalies (%d)", m.Device.AnomalyCount)))
+	content.WriteString("\n")
+
+	b := m.Device.Baseline
+	if !b.Established {
+		content.WriteString("\n")
+		content.WriteString(valueStyle.Render(fmt.Sprintf("Learning baseline (%d advertisements)...", b.Samples)))
+	} else {
+		layouts := "none"
+		if len(b.Layouts) > 0 {
+			layouts = strings.Join(b.Layouts, ", ")
+		}
+		name := b.Name
+		if name == "" {
+			name = "(none)"
+		}
+		rows := []struct{ label, value string }{
+			{"Layouts:", layouts},
+			{"Name:", name},
+			{"Interval:", formatInterval(b.Interval)},
+			{"RSSI Range:", fmt.Sprintf("%d to %d dBm", b.RSSIMin, b.RSSIMax)},
+		}
+		for _, r := range rows {
+			content.WriteString("\n")
+			content.WriteString(labelStyle.Render(r.label))
+			content.WriteString(valueStyle.Render(r.value))
+		}
+	}
+
+	// Most recent first
+	for i := len(m.Device.Anomalies) - 1; i >= 0; i-- {
+		a := m.Device.Anomalies[i]
+		content.WriteString("\n")
+		content.WriteString(timeStyle.Render(a.Time.Format("15:04:05") + "  "))
+		content.WriteString(kindStyle.Render(a.Kind))
+		content.WriteString(valueStyle.Render(a.Message))
+	}
+
 	return sectionStyle.Render(content.String())
 }
 
@@ -346,10 +1018,7 @@ func formatAdvPayload(adv ble.Advertisement, maxLen int) string {
 		for uuid, data := range adv.ServiceData {
 			if len(data) > 0 {
 				// Show shortened UUID prefix
-				shortUUID := uuid
-				if len(uuid) > 8 {
-					shortUUID = uuid[:8]
-				}
+				shortUUID := ble.FormatUUID(uuid)
 				prefix = shortUUID + ":"
 				dataHex = fmt.Sprintf("%x", data)
 				break
@@ -369,7 +1038,7 @@ func formatAdvPayload(adv ble.Advertisement, maxLen int) string {
 }
 
 // UpdateDevice updates the device being displayed
-func (m *DeviceDetailModel) UpdateDevice(device ble.Device) {
+func (m *DeviceDetailModel) UpdateDevice(device *ble.Device) {
 	m.Device = device
 	if m.ready {
 		m.viewport.SetContent(m.renderContent())
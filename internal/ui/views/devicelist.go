@@ -9,6 +9,8 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/buckleypaul/blescan/internal/ble"
+	"github.com/buckleypaul/blescan/internal/classify"
+	"github.com/buckleypaul/blescan/internal/stats"
 	"github.com/buckleypaul/blescan/internal/ui/styles"
 )
 
@@ -26,6 +28,11 @@ type DeviceListModel struct {
 	columnWidths   []int
 	enabledColumns []string
 	columnDefs     map[string]*ColumnDefinition
+	trackerAlerts  int
+	floods         []ble.Flood
+	status         string
+	groupByType    bool
+	reception      map[string]stats.Reception // Per device ID, estimated once per SetDevices
 }
 
 // NewDeviceListModel creates a new device list model
@@ -110,6 +117,7 @@ func (m DeviceListModel) Update(msg tea.Msg) (DeviceListModel, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		m.status = ""
 		switch msg.String() {
 		case "left", "h":
 			if m.selectedColumn > 0 {
@@ -135,10 +143,19 @@ func (m DeviceListModel) Update(msg tea.Msg) (DeviceListModel, tea.Cmd) {
 			return m, m.filter.SetMode(FilterModeName)
 		case "r":
 			return m, m.filter.SetMode(FilterModeRSSI)
+		case "f":
+			return m, m.filter.SetMode(FilterModeQuery)
+		case "p":
+			m.filter.NextPreset()
+			m.applyFilterAndSort()
 		case "tab":
 			// Start column configuration
 			m.filter.tempEnabledColumns = append([]string(nil), m.enabledColumns...)
 			return m, m.filter.SetMode(FilterModeColumns)
+		case "g":
+			// Group rows by device type
+			m.groupByType = !m.groupByType
+			m.applyFilterAndSort()
 		case "c":
 			m.filter.ClearFilters()
 			m.applyFilterAndSort()
@@ -209,6 +226,13 @@ func (m DeviceListModel) View() string {
 	if len(m.filtered) != len(m.devices) {
 		deviceCount = fmt.Sprintf("%d/%d devices", len(m.filtered), len(m.devices))
 	}
+	if distinct := m.distinctEstimate(); distinct != len(m.devices) {
+		deviceCount += fmt.Sprintf(" (~%d distinct)", distinct)
+	}
+	if m.trackerAlerts > 0 {
+		deviceCount = fmt.Sprintf("! %d tracker alert(s) (t) • ", m.trackerAlerts) + deviceCount
+	}
+
 	titleContent := title + strings.Repeat(" ", max(0, m.width-len(title)-len(deviceCount)-6)) + deviceCount
 	b.WriteString(titleStyle.Render(titleContent))
 	b.WriteString("\n")
@@ -226,8 +250,19 @@ func (m DeviceListModel) View() string {
 		filterContent = ""
 	} else if m.filter.Mode != FilterModeNone {
 		filterContent = m.filter.View()
+	} else if len(m.floods) > 0 {
+		// Flood banner takes over the hint line
+		filterBarStyle = filterBarStyle.Bold(true).Foreground(lipgloss.Color("255")).Background(styles.ErrorColor)
+		filterContent = m.floodBanner()
+		if m.filter.IsFiltering() {
+			filterContent += " • " + m.filter.FilterSummary()
+		}
+	} else if m.status != "" {
+		filterContent = m.status
 	} else if m.filter.IsFiltering() {
 		filterContent = m.filter.FilterSummary()
+	} else if m.groupByType {
+		filterContent = m.groupSummary()
 	} else {
 		filterContent = "Use ←/→ to select column, 's' to sort"
 	}
@@ -255,7 +290,7 @@ func (m DeviceListModel) View() string {
 		Padding(0, 2).
 		Width(m.width)
 
-	help := "↑/↓ Row • ←/→ Column • s Sort • Enter View • / Name • r RSSI • Tab Columns • c Clear • q Quit"
+	help := "↑/↓ Row • ←/→ Column • s Sort • Enter View • / Name • r RSSI • f Filter • p Preset • m Merge • b Broadcasts • t Trackers • v Presence • g Group • S Smoothing • x Export • Tab Columns • c Clear • q Quit"
 	b.WriteString(helpStyle.Render(help))
 
 	return b.String()
@@ -325,7 +360,12 @@ func (m *DeviceListModel) updateTableRows() {
 			def := m.columnDefs[colID]
 
 			// Extract data using formatter
-			value := def.Formatter(&device)
+			var value string
+			if colID == "prr" {
+				value = m.receptionCell(device.ID)
+			} else {
+				value = def.Formatter(&device)
+			}
 
 			// Truncate to fit column width
 			maxLen := m.columnWidths[j] - 2
@@ -349,13 +389,23 @@ func (m DeviceListModel) matchesFilter(d ble.Device) bool {
 			return false
 		}
 	}
-	if m.filter.Config.MinRSSI != nil && d.RSSIAverage < float64(*m.filter.Config.MinRSSI) {
+	if m.filter.Config.MinRSSI != nil && d.RSSISmoothed < float64(*m.filter.Config.MinRSSI) {
+		return false
+	}
+	if !stats.MatchesTerms(&d, m.filter.Config.Terms) {
 		return false
 	}
 	return true
 }
 
 func (m DeviceListModel) compareDevices(a, b ble.Device) bool {
+	// Groups keep their order whichever way the column is sorted
+	if m.groupByType {
+		if cmp := compareCategory(&a, &b); cmp != 0 {
+			return cmp < 0
+		}
+	}
+
 	cmp := m.compareByColumn(a, b, m.sortColumn)
 
 	// Check if sorting by rssi column
@@ -365,8 +415,8 @@ func (m DeviceListModel) compareDevices(a, b ble.Device) bool {
 	}
 
 	if cmp == 0 && !sortByRSSI {
-		// Secondary sort by average RSSI (higher first) to reduce jumping
-		cmp = compareFloat(b.RSSIAverage, a.RSSIAverage)
+		// Secondary sort by smoothed RSSI (higher first) to reduce jumping
+		cmp = compareFloat(b.RSSISmoothed, a.RSSISmoothed)
 	}
 	if m.sortAscending {
 		return cmp > 0
@@ -386,11 +436,23 @@ func (m DeviceListModel) compareByColumn(a, b ble.Device, col int) int {
 	// For numeric columns, provide better sorting
 	switch colID {
 	case "rssi":
-		return compareFloat(b.RSSIAverage, a.RSSIAverage) // Higher RSSI first
+		return compareFloat(b.RSSISmoothed, a.RSSISmoothed) // Higher RSSI first
+	case "rssi_raw":
+		return compareInt(int(b.RSSICurrent), int(a.RSSICurrent))
 	case "count":
 		return compareInt(int(b.AdvCount), int(a.AdvCount)) // Higher count first
 	case "interval":
 		return compareInt(int(a.AdvInterval), int(b.AdvInterval)) // Lower interval first
+	case "prr":
+		ra, okA := m.reception[a.ID]
+		rb, okB := m.reception[b.ID]
+		if okA != okB {
+			if okA {
+				return -1
+			}
+			return 1
+		}
+		return compareFloat(rb.Ratio, ra.Ratio) // Best reception first
 	case "flags":
 		aFlags := uint8(0)
 		bFlags := uint8(0)
@@ -403,6 +465,21 @@ func (m DeviceListModel) compareByColumn(a, b ble.Device, col int) int {
 		return compareInt(int(aFlags), int(bFlags))
 	case "name":
 		return strings.Compare(strings.ToLower(a.GetDisplayName()), strings.ToLower(b.GetDisplayName()))
+	case "distance":
+		ea, okA := stats.EstimateDistance(&a)
+		eb, okB := stats.EstimateDistance(&b)
+		if okA != okB {
+			if okA {
+				return -1 // Estimates before unknowns
+			}
+			return 1
+		}
+		return compareFloat(ea.Meters, eb.Meters) // Nearer first
+	case "type":
+		if cmp := compareCategory(&a, &b); cmp != 0 {
+			return cmp
+		}
+		return compareFloat(b.Class.Confidence, a.Class.Confidence) // More confident first
 	case "service_uuids":
 		return compareInt(len(b.ServiceUUIDs), len(a.ServiceUUIDs)) // More UUIDs first
 	case "service_data":
@@ -457,12 +534,92 @@ func compareFloat(a, b float64) int {
 	return 0
 }
 
+// SetTrackerAlerts sets the number of tracker alerts shown in the title bar
+func (m *DeviceListModel) SetTrackerAlerts(n int) {
+	m.trackerAlerts = n
+}
+
+// deviceCategory returns the device's type, treating unclassified as unknown
+func deviceCategory(d *ble.Device) string {
+	if d.Class.Category == "" {
+		return classify.Unknown
+	}
+	return d.Class.Category
+}
+
+// compareCategory orders devices by type in display order
+func compareCategory(a, b *ble.Device) int {
+	ca, cb := deviceCategory(a), deviceCategory(b)
+	if cmp := compareInt(classify.CategoryOrder(ca), classify.CategoryOrder(cb)); cmp != 0 {
+		return cmp
+	}
+	return strings.Compare(ca, cb)
+}
+
+// groupSummary counts the shown devices of each type
+func (m DeviceListModel) groupSummary() string {
+	counts := make(map[string]int)
+	var order []string
+	for i := range m.filtered {
+		c := deviceCategory(&m.filtered[i])
+		if counts[c] == 0 {
+			order = append(order, c)
+		}
+		counts[c]++
+	}
+	parts := make([]string, len(order))
+	for i, c := range order {
+		parts[i] = fmt.Sprintf("%s %d", c, counts[c])
+	}
+	return "Grouped by type: " + strings.Join(parts, ", ")
+}
+
+// SetStatus shows a message in the filter bar until the next key press
+func (m *DeviceListModel) SetStatus(status string) {
+	m.status = status
+}
+
+// SetFloods sets the ongoing advertisement floods shown in the banner
+func (m *DeviceListModel) SetFloods(floods []ble.Flood) {
+	m.floods = floods
+}
+
+// floodBanner describes the largest ongoing flood
+func (m DeviceListModel) floodBanner() string {
+	f := m.floods[0]
+	banner := fmt.Sprintf("⚠ FLOOD: %s, %.0f new addresses/min, %s", f.Description, f.Rate, f.FormatSources())
+	if f.Suppressed > 0 {
+		banner += fmt.Sprintf(", %d suppressed", f.Suppressed)
+	}
+	if len(m.floods) > 1 {
+		banner += fmt.Sprintf(" (+%d more)", len(m.floods)-1)
+	}
+	return banner
+}
+
 // SetDevices updates the device list
 func (m *DeviceListModel) SetDevices(devices []ble.Device) {
 	m.devices = devices
+	// Estimating reception analyzes the whole interval history, so do it
+	// once here rather than on every sort comparison and cell render
+	m.reception = make(map[string]stats.Reception, len(devices))
+	for i := range devices {
+		if r, ok := stats.EstimateReception(&devices[i]); ok {
+			m.reception[devices[i].ID] = r
+		}
+	}
 	m.applyFilterAndSort()
 }
 
+// receptionCell renders the PRR column from the cached estimate
+func (m DeviceListModel) receptionCell(id string) string {
+	r, ok := m.reception[id]
+	if !ok {
+		return "-"
+	}
+	return r.String()
+}
+
 // SelectedDevice returns the currently selected device
 func (m DeviceListModel) SelectedDevice() (ble.Device, bool) {
 	idx := m.table.Cursor()
@@ -472,6 +629,31 @@ func (m DeviceListModel) SelectedDevice() (ble.Device, bool) {
 	return ble.Device{}, false
 }
 
+// distinctEstimate returns the device count minus devices that are probably
+// another listed device under a rotated address. A candidate that has left
+// the list isn't double counted, and each candidate is paired at most once.
+func (m DeviceListModel) distinctEstimate() int {
+	listed := make(map[string]bool, len(m.devices))
+	for i := range m.devices {
+		listed[m.devices[i].ID] = true
+	}
+	distinct := len(m.devices)
+	paired := make(map[string]bool)
+	for i := range m.devices {
+		d := &m.devices[i]
+		if d.Correlation == nil {
+			continue
+		}
+		candidate := d.Correlation.CandidateID
+		if !listed[candidate] || paired[candidate] || paired[d.ID] {
+			continue
+		}
+		paired[candidate], paired[d.ID] = true, true
+		distinct--
+	}
+	return distinct
+}
+
 // IsFilterActive returns true if filter input is focused
 func (m DeviceListModel) IsFilterActive() bool {
 	return m.filter.Mode != FilterModeNone
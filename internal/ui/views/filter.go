@@ -16,6 +16,7 @@ const (
 	FilterModeNone FilterMode = iota
 	FilterModeName
 	FilterModeRSSI
+	FilterModeQuery
 	FilterModeColumns
 )
 
@@ -26,6 +27,8 @@ type FilterModel struct {
 	textInput          textinput.Model
 	columnSelectorIdx  int
 	tempEnabledColumns []string // Temporary storage during column selection
+	queryErr           error    // Parse error from the last query, if any
+	preset             int      // 1-based index of the active stats.FilterPresets entry, 0 if none
 }
 
 // NewFilterModel creates a new filter model
@@ -121,6 +124,8 @@ func (m FilterModel) View() string {
 		label = "Filter by name: "
 	case FilterModeRSSI:
 		label = "Min RSSI (dBm): "
+	case FilterModeQuery:
+		label = "Filter (field:value): "
 	}
 
 	return styles.FilterLabelStyle.Render(label) + m.textInput.View()
@@ -141,6 +146,9 @@ func (m *FilterModel) SetMode(mode FilterMode) tea.Cmd {
 		} else {
 			m.textInput.SetValue("")
 		}
+	case FilterModeQuery:
+		m.textInput.Placeholder = "addrtype:public company:apple"
+		m.textInput.SetValue(m.Config.Query)
 	}
 
 	m.textInput.Focus()
@@ -162,18 +170,45 @@ func (m *FilterModel) applyFilter() {
 				m.Config.MinRSSI = &r
 			}
 		}
+	case FilterModeQuery:
+		terms, err := stats.ParseFilterQuery(value)
+		m.queryErr = err
+		if err == nil {
+			m.Config.Query = value
+			m.Config.Terms = terms
+			m.preset = 0
+		}
 	}
 }
 
 // ClearFilters clears all filter criteria
 func (m *FilterModel) ClearFilters() {
 	m.Config = stats.FilterConfig{}
+	m.queryErr = nil
+	m.preset = 0
 	m.textInput.SetValue("")
 }
 
+// NextPreset replaces the query with the next filter preset, cycling back
+// to no query after the last one
+func (m *FilterModel) NextPreset() {
+	m.preset = (m.preset + 1) % (len(stats.FilterPresets) + 1)
+	m.queryErr = nil
+	if m.preset == 0 {
+		m.Config.Query = ""
+		m.Config.Terms = nil
+		return
+	}
+	preset := stats.FilterPresets[m.preset-1]
+	terms, err := stats.ParseFilterQuery(preset.Query)
+	m.queryErr = err
+	m.Config.Query = preset.Query
+	m.Config.Terms = terms
+}
+
 // IsFiltering returns true if any filter is active
 func (m FilterModel) IsFiltering() bool {
-	return m.Config.NameContains != "" || m.Config.MinRSSI != nil
+	return m.Config.NameContains != "" || m.Config.MinRSSI != nil || len(m.Config.Terms) > 0 || m.queryErr != nil
 }
 
 // FilterSummary returns a string describing active filters
@@ -189,6 +224,14 @@ func (m FilterModel) FilterSummary() string {
 	if m.Config.MinRSSI != nil {
 		parts = append(parts, "rssi>="+strconv.Itoa(int(*m.Config.MinRSSI)))
 	}
+	if m.preset > 0 {
+		parts = append(parts, "preset: "+stats.FilterPresets[m.preset-1].Name)
+	} else if m.Config.Query != "" {
+		parts = append(parts, m.Config.Query)
+	}
+	if m.queryErr != nil {
+		parts = append(parts, "invalid query: "+m.queryErr.Error())
+	}
 
 	result := "Filters: "
 	for i, p := range parts {
@@ -0,0 +1,202 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/buckleypaul/blescan/internal/ble"
+	"github.com/buckleypaul/blescan/internal/stats"
+	"github.com/buckleypaul/blescan/internal/ui/styles"
+)
+
+const (
+	defaultHistogramBins = 20
+	minHistogramBins     = 10
+	maxHistogramBins     = 60
+)
+
+// IntervalModel shows an analysis of a device's advertising intervals
+type IntervalModel struct {
+	Device *ble.Device
+
+	analysis stats.IntervalAnalysis
+	ok       bool
+	bins     int
+
+	width  int
+	height int
+}
+
+// NewIntervalModel analyzes device's advertising intervals
+func NewIntervalModel(device *ble.Device) IntervalModel {
+	m := IntervalModel{bins: defaultHistogramBins}
+	m.UpdateDevice(device)
+	return m
+}
+
+// UpdateDevice re-analyzes the intervals with the device's latest arrivals
+func (m *IntervalModel) UpdateDevice(device *ble.Device) {
+	m.Device = device
+	m.analysis, m.ok = stats.AnalyzeIntervals(device.Arrivals)
+}
+
+// Update handles interval view input
+func (m IntervalModel) Update(msg tea.Msg) (IntervalModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "+", "=":
+			if m.bins < maxHistogramBins {
+				m.bins += 5
+			}
+		case "-":
+			if m.bins > minHistogramBins {
+				m.bins -= 5
+			}
+		}
+	}
+	return m, nil
+}
+
+// View renders the interval analysis
+func (m IntervalModel) View() string {
+	var b strings.Builder
+
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(styles.PrimaryColor).
+		Background(lipgloss.Color("235")).
+		Padding(0, 2).
+		Width(m.width)
+	b.WriteString(titleStyle.Render("Interval Analysis: " + m.Device.GetDisplayName()))
+	b.WriteString("\n")
+
+	sectionStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(styles.SecondaryColor).
+		Padding(0, 2).
+		Width(m.width - 2)
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.SecondaryColor)
+	labelStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Width(20)
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+	warnStyle := lipgloss.NewStyle().Foreground(styles.AccentColor)
+
+	if !m.ok {
+		b.WriteString(sectionStyle.Render(valueStyle.Render(fmt.Sprintf("Waiting for advertisements (%d intervals so far)...", len(m.analysis.Gaps)))))
+		b.WriteString("\n")
+		b.WriteString(m.renderHelp())
+		return b.String()
+	}
+	a := m.analysis
+
+	var summary strings.Builder
+	summary.WriteString(headerStyle.Render(fmt.Sprintf("Summary (%d intervals)", len(a.Gaps))))
+	summary.WriteString("\n")
+	rows := []struct{ label, value string }{
+		{"Base Interval:", formatMillis(a.Base)},
+		{"Configured (est.):", fmt.Sprintf("%s (%d × 0.625 ms)", formatMillis(a.Configured), int64(a.Configured/(625*time.Microsecond)))},
+		{"Fits Base:", fmt.Sprintf("%.0f%% of intervals are whole multiples", a.Explained*100)},
+		{"Missed:", fmt.Sprintf("%d advertisements (%.0f%%)", a.Missed, a.Loss*100)},
+		{"Jitter:", fmt.Sprintf("%s standard deviation", formatMillis(a.Jitter))},
+		{"advDelay (est.):", fmt.Sprintf("0-%s (spec: 0-10 ms)", formatMillis(a.DelaySpread))},
+	}
+	for _, r := range rows {
+		summary.WriteString("\n")
+		summary.WriteString(labelStyle.Render(r.label))
+		summary.WriteString(valueStyle.Render(r.value))
+	}
+
+	if a.Switched() {
+		summary.WriteString("\n\n")
+		summary.WriteString(warnStyle.Render("Interval switching:"))
+		for _, p := range a.Phases {
+			summary.WriteString("\n")
+			summary.WriteString(labelStyle.Render(p.Start.Format("15:04:05") + "-" + p.End.Format("15:04:05")))
+			summary.WriteString(valueStyle.Render(fmt.Sprintf("%s (%d intervals)", formatMillis(p.Interval), p.Gaps)))
+		}
+	}
+	if a.MultipleSets() {
+		summary.WriteString("\n\n")
+		msg := fmt.Sprintf("%.0f%% of intervals don't fit the base interval: multiple advertising sets or transmitters", (1-a.Explained)*100)
+		if len(a.Others) > 0 {
+			var others []string
+			for _, o := range a.Others {
+				others = append(others, formatMillis(o))
+			}
+			msg += " (clusters at " + strings.Join(others, ", ") + ")"
+		}
+		summary.WriteString(warnStyle.Render(msg))
+	}
+	b.WriteString(sectionStyle.Render(summary.String()))
+	b.WriteString("\n")
+
+	b.WriteString(sectionStyle.Render(m.renderHistogram()))
+	b.WriteString("\n")
+	b.WriteString(m.renderHelp())
+	return b.String()
+}
+
+// renderHistogram draws a horizontal bar per bin, marking the bins that hold
+// multiples of the base interval
+func (m IntervalModel) renderHistogram() string {
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.SecondaryColor)
+	labelStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Width(20)
+	barStyle := lipgloss.NewStyle().Foreground(styles.PrimaryColor)
+	markStyle := lipgloss.NewStyle().Foreground(styles.AccentColor)
+
+	hist := stats.IntervalHistogram(m.analysis.Gaps, m.bins)
+	peak := 0
+	for _, bin := range hist {
+		peak = max(peak, bin.Count)
+	}
+	barWidth := max(10, m.width-46)
+
+	var content strings.Builder
+	content.WriteString(headerStyle.Render("Inter-arrival Times"))
+	content.WriteString("\n")
+	for i, bin := range hist {
+		content.WriteString("\n")
+		label := fmt.Sprintf("%s-%s", formatMillis(bin.Low), formatMillis(bin.High))
+		if i == len(hist)-1 {
+			label = "≥" + formatMillis(bin.Low)
+		}
+		content.WriteString(labelStyle.Render(label))
+		n := 0
+		if peak > 0 {
+			n = bin.Count * barWidth / peak
+		}
+		if n == 0 && bin.Count > 0 {
+			n = 1
+		}
+		content.WriteString(barStyle.Render(strings.Repeat("█", n)))
+		content.WriteString(fmt.Sprintf(" %d", bin.Count))
+		if base := m.analysis.Base; base > 0 {
+			for k := 1; k <= 4; k++ {
+				if multiple := time.Duration(k) * base; multiple >= bin.Low && multiple < bin.High {
+					content.WriteString(markStyle.Render(fmt.Sprintf("  ← %d× base", k)))
+				}
+			}
+		}
+	}
+	return content.String()
+}
+
+func (m IntervalModel) renderHelp() string {
+	helpStyle := lipgloss.NewStyle().
+		Foreground(styles.MutedColor).
+		Background(lipgloss.Color("235")).
+		Padding(0, 2).
+		Width(m.width)
+	return helpStyle.Render(fmt.Sprintf("+/- Histogram bins (%d) • Esc Back • q Quit", m.bins))
+}
+
+// formatMillis formats a duration in milliseconds with one decimal
+func formatMillis(d time.Duration) string {
+	return fmt.Sprintf("%.1f ms", float64(d.Microseconds())/1000)
+}
@@ -0,0 +1,553 @@
+package views
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/buckleypaul/blescan/internal/annotate"
+	"github.com/buckleypaul/blescan/internal/ble"
+	"github.com/buckleypaul/blescan/internal/stats"
+	"github.com/buckleypaul/blescan/internal/ui/styles"
+)
+
+// maxTimelineSegments is how many of the latest structure changes are shown
+const maxTimelineSegments = 8
+
+// Annotation form inputs
+const (
+	fieldInputName = iota
+	fieldInputType
+	fieldInputDivisor
+	fieldInputUnit
+	fieldInputs
+)
+
+var fieldInputLabels = [fieldInputs]string{"Name:", "Type:", "Divisor:", "Unit:"}
+
+// AnnotationSaveFunc stores the annotations and returns where they were saved
+type AnnotationSaveFunc func() (string, error)
+
+// PayloadDiffModel lines up a device's successive payloads byte by byte,
+// highlighting changes and classifying each byte position. Byte ranges can
+// be annotated as named fields.
+type PayloadDiffModel struct {
+	Device *ble.Device
+
+	streams   []stats.PayloadStream
+	positions []stats.BytePosition
+	timeline  []stats.PayloadSegment
+	fields    []annotate.Field // Annotations of the selected stream's source
+	stream    int              // Index into streams
+	streamKey string           // Key of the selected stream, kept across updates
+	offset    int              // First byte position shown
+	cursor    int              // Byte position under the cursor
+	anchor    int              // Other end of the selection, -1 if none
+	scroll    int              // Payloads scrolled past, newest first
+
+	editing bool
+	inputs  [fieldInputs]textinput.Model
+	focus   int
+	status  string
+	save    AnnotationSaveFunc
+
+	width  int
+	height int
+}
+
+// NewPayloadDiffModel analyzes device's recent payloads
+func NewPayloadDiffModel(device *ble.Device, save AnnotationSaveFunc) PayloadDiffModel {
+	m := PayloadDiffModel{anchor: -1, save: save}
+	for i := range m.inputs {
+		m.inputs[i] = textinput.New()
+		m.inputs[i].CharLimit = 20
+		m.inputs[i].Width = 20
+	}
+	m.inputs[fieldInputName].Placeholder = "temp"
+	m.inputs[fieldInputType].Placeholder = strings.Join(annotate.Types, " ")
+	m.inputs[fieldInputDivisor].Placeholder = "1"
+	m.inputs[fieldInputUnit].Placeholder = "°C"
+	m.UpdateDevice(device)
+	return m
+}
+
+// UpdateDevice re-analyzes the payloads with the device's latest
+// advertisements, keeping the selected stream
+func (m *PayloadDiffModel) UpdateDevice(device *ble.Device) {
+	m.Device = device
+	m.streams = stats.PayloadStreams(device.Advertisements)
+	m.timeline = stats.PayloadTimeline(device.Advertisements)
+	m.stream = 0
+	for i, s := range m.streams {
+		if s.Key == m.streamKey {
+			m.stream = i
+		}
+	}
+	m.positions = nil
+	m.fields = nil
+	if len(m.streams) > 0 {
+		m.streamKey = m.streams[m.stream].Key
+		m.positions = stats.ClassifyBytes(m.streams[m.stream].Samples)
+		m.fields = annotate.ForSource(m.source())
+	}
+}
+
+// IsEditing reports whether the annotation form has focus
+func (m PayloadDiffModel) IsEditing() bool {
+	return m.editing
+}
+
+// source returns the annotation source of the selected stream
+func (m PayloadDiffModel) source() string {
+	stream := m.streams[m.stream]
+	if stream.Key != "mfr" {
+		return annotate.ServiceSource(stream.Key)
+	}
+	data := stream.Samples[len(stream.Samples)-1].Data
+	if len(data) < 2 {
+		return ""
+	}
+	return annotate.ManufacturerSource(uint16(data[0]) | uint16(data[1])<<8)
+}
+
+// selection returns the first and last selected byte positions
+func (m PayloadDiffModel) selection() (int, int) {
+	if m.anchor < 0 {
+		return m.cursor, m.cursor
+	}
+	return min(m.anchor, m.cursor), max(m.anchor, m.cursor)
+}
+
+// fieldAt returns the annotation covering a byte position
+func (m PayloadDiffModel) fieldAt(pos int) (annotate.Field, bool) {
+	for _, f := range m.fields {
+		if f.Covers(pos) {
+			return f, true
+		}
+	}
+	return annotate.Field{}, false
+}
+
+// Update handles payload diff view input
+func (m PayloadDiffModel) Update(msg tea.Msg) (PayloadDiffModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+	case tea.KeyMsg:
+		if m.editing {
+			return m.updateForm(msg)
+		}
+		switch msg.String() {
+		case "tab", "shift+tab":
+			if len(m.streams) > 1 {
+				step := 1
+				if msg.String() == "shift+tab" {
+					step = len(m.streams) - 1
+				}
+				m.streamKey = m.streams[(m.stream+step)%len(m.streams)].Key
+				m.offset, m.cursor, m.anchor, m.scroll = 0, 0, -1, 0
+				m.UpdateDevice(m.Device)
+			}
+		case "left", "h":
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case "right", "l":
+			if m.cursor < len(m.positions)-1 {
+				m.cursor++
+			}
+		case "up", "k":
+			if m.scroll > 0 {
+				m.scroll--
+			}
+		case "down", "j":
+			if len(m.streams) > 0 && m.scroll < len(m.streams[m.stream].Samples)-1 {
+				m.scroll++
+			}
+		case " ":
+			// Start or clear a selection at the cursor
+			if m.anchor < 0 {
+				m.anchor = m.cursor
+			} else {
+				m.anchor = -1
+			}
+		case "a":
+			if len(m.streams) > 0 {
+				return m.startForm()
+			}
+		case "d":
+			if f, ok := m.fieldAt(m.cursor); ok {
+				annotate.Remove(f.Source, f.Name)
+				m.status = m.saveAnnotations("Removed " + f.Name)
+				m.UpdateDevice(m.Device)
+			}
+		}
+
+		// Keep the cursor on screen
+		if m.cursor < m.offset {
+			m.offset = m.cursor
+		} else if columns := m.byteColumns(); m.cursor >= m.offset+columns {
+			m.offset = m.cursor - columns + 1
+		}
+	}
+	return m, nil
+}
+
+// startForm opens the annotation form for the selection, or for the field
+// under the cursor so it can be edited
+func (m PayloadDiffModel) startForm() (PayloadDiffModel, tea.Cmd) {
+	first, last := m.selection()
+	values := [fieldInputs]string{"", annotate.U8, "", ""}
+	switch last - first + 1 {
+	case 2:
+		values[fieldInputType] = annotate.U16LE
+	case 4:
+		values[fieldInputType] = annotate.U32LE
+	}
+	if f, ok := m.fieldAt(m.cursor); ok && m.anchor < 0 {
+		values = [fieldInputs]string{f.Name, f.Type, "", f.Unit}
+		if f.Divisor > 0 {
+			values[fieldInputDivisor] = strconv.FormatFloat(f.Divisor, 'g', -1, 64)
+		}
+	}
+	for i := range m.inputs {
+		m.inputs[i].SetValue(values[i])
+		m.inputs[i].Blur()
+	}
+	m.editing = true
+	m.focus = fieldInputName
+	m.status = ""
+	m.inputs[m.focus].Focus()
+	return m, textinput.Blink
+}
+
+// updateForm handles annotation form input
+func (m PayloadDiffModel) updateForm(msg tea.KeyMsg) (PayloadDiffModel, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.editing = false
+		return m, nil
+	case "enter":
+		f, err := m.formField()
+		if err != nil {
+			m.status = err.Error()
+			return m, nil
+		}
+		// Editing a field under a new name replaces it
+		if old, ok := m.fieldAt(m.cursor); ok && m.anchor < 0 && old.Name != f.Name {
+			annotate.Remove(old.Source, old.Name)
+		}
+		if err := annotate.Add(f); err != nil {
+			m.status = err.Error()
+			return m, nil
+		}
+		m.editing = false
+		m.anchor = -1
+		m.status = m.saveAnnotations("Saved " + f.Name)
+		m.UpdateDevice(m.Device)
+		return m, nil
+	case "tab", "down":
+		m.inputs[m.focus].Blur()
+		m.focus = (m.focus + 1) % fieldInputs
+		m.inputs[m.focus].Focus()
+		return m, textinput.Blink
+	case "shift+tab", "up":
+		m.inputs[m.focus].Blur()
+		m.focus = (m.focus + fieldInputs - 1) % fieldInputs
+		m.inputs[m.focus].Focus()
+		return m, textinput.Blink
+	}
+	var cmd tea.Cmd
+	m.inputs[m.focus], cmd = m.inputs[m.focus].Update(msg)
+	return m, cmd
+}
+
+// formField builds a field from the form and the selection
+func (m PayloadDiffModel) formField() (annotate.Field, error) {
+	first, _ := m.selection()
+	if f, ok := m.fieldAt(m.cursor); ok && m.anchor < 0 {
+		first = f.Offset
+	}
+	f := annotate.Field{
+		Source: m.source(),
+		Name:   strings.TrimSpace(m.inputs[fieldInputName].Value()),
+		Offset: first,
+		Unit:   strings.TrimSpace(m.inputs[fieldInputUnit].Value()),
+	}
+	t, err := annotate.ParseType(m.inputs[fieldInputType].Value())
+	if err != nil {
+		return f, err
+	}
+	f.Type = t
+	if s := strings.TrimPrefix(strings.TrimSpace(m.inputs[fieldInputDivisor].Value()), "/"); s != "" {
+		divisor, err := strconv.ParseFloat(s, 64)
+		if err != nil || divisor <= 0 {
+			return f, fmt.Errorf("divisor must be a positive number")
+		}
+		f.Divisor = divisor
+	}
+	return f, f.Validate()
+}
+
+// saveAnnotations stores the annotations, returning a status message
+func (m PayloadDiffModel) saveAnnotations(done string) string {
+	if m.save == nil {
+		return done
+	}
+	path, err := m.save()
+	if err != nil {
+		return fmt.Sprintf("%s, but saving failed: %v", done, err)
+	}
+	return fmt.Sprintf("%s in %s", done, path)
+}
+
+// View renders the payload diff
+func (m PayloadDiffModel) View() string {
+	var b strings.Builder
+
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(styles.PrimaryColor).
+		Background(lipgloss.Color("235")).
+		Padding(0, 2).
+		Width(m.width)
+	b.WriteString(titleStyle.Render("Payload Diff: " + m.Device.GetDisplayName()))
+	b.WriteString("\n")
+
+	sectionStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(styles.SecondaryColor).
+		Padding(0, 2).
+		Width(m.width - 2)
+
+	if len(m.streams) == 0 {
+		valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+		b.WriteString(sectionStyle.Render(valueStyle.Render("No manufacturer or service data received yet...")))
+		b.WriteString("\n")
+		b.WriteString(m.renderHelp())
+		return b.String()
+	}
+
+	lower := m.renderTimeline()
+	if m.editing {
+		lower = m.renderForm()
+	}
+	// Title, help, two section borders, and the payload section's header,
+	// offset, class, fields, blank, legend and field value lines
+	overhead := 2 + 4 + 6 + len(m.fields) + lipgloss.Height(lower)
+	if m.status != "" {
+		overhead++
+	}
+	b.WriteString(sectionStyle.Render(m.renderPayloads(max(3, m.height-overhead))))
+	b.WriteString("\n")
+	b.WriteString(sectionStyle.Render(lower))
+	b.WriteString("\n")
+	b.WriteString(m.renderHelp())
+	return b.String()
+}
+
+// byteColumns returns how many byte positions fit across the screen
+func (m PayloadDiffModel) byteColumns() int {
+	// Borders and padding, then the 14-column time label
+	return max(4, (m.width-8-14)/3)
+}
+
+// byteClassStyle returns the color of bytes in a position of class c
+func byteClassStyle(c stats.ByteClass) lipgloss.Style {
+	switch c {
+	case stats.ByteConstant:
+		return lipgloss.NewStyle().Foreground(styles.MutedColor)
+	case stats.ByteCounter:
+		return lipgloss.NewStyle().Foreground(styles.SuccessColor)
+	case stats.ByteRandom:
+		return lipgloss.NewStyle().Foreground(styles.ErrorColor)
+	case stats.ByteSensor:
+		return lipgloss.NewStyle().Foreground(styles.PrimaryColor)
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+}
+
+// byteClassMarks are the one-letter marks of each class in the class row
+var byteClassMarks = map[stats.ByteClass]string{
+	stats.ByteUnknown:  ".",
+	stats.ByteConstant: "C",
+	stats.ByteCounter:  "+",
+	stats.ByteRandom:   "?",
+	stats.ByteSensor:   "~",
+}
+
+// renderPayloads draws the byte offsets, the class of each position, the
+// annotated fields and the latest payloads, highlighting bytes that differ
+// from the payload before
+func (m PayloadDiffModel) renderPayloads(rows int) string {
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.SecondaryColor)
+	labelStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Width(14)
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+	changedStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("0")).Background(styles.AccentColor)
+	cursorStyle := lipgloss.NewStyle().Reverse(true)
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("0")).Background(styles.SecondaryColor)
+	fieldStyle := lipgloss.NewStyle().Foreground(styles.SecondaryColor)
+
+	stream := m.streams[m.stream]
+	samples := stream.Samples
+	first := min(m.offset, max(0, len(m.positions)-1))
+	last := min(len(m.positions), first+m.byteColumns())
+	selFirst, selLast := m.selection()
+
+	var content strings.Builder
+	header := fmt.Sprintf("%s (%d payloads)", stream.Label, len(samples))
+	if len(m.streams) > 1 {
+		header += fmt.Sprintf("  [%d/%d]", m.stream+1, len(m.streams))
+	}
+	content.WriteString(headerStyle.Render(header))
+	content.WriteString("\n")
+
+	content.WriteString(labelStyle.Render("Offset"))
+	for i := first; i < last; i++ {
+		cell := fmt.Sprintf("%02X", i)
+		switch {
+		case i == m.cursor:
+			cell = cursorStyle.Render(cell)
+		case m.anchor >= 0 && i >= selFirst && i <= selLast:
+			cell = selectedStyle.Render(cell)
+		}
+		content.WriteString(cell + " ")
+	}
+	content.WriteString("\n")
+	content.WriteString(labelStyle.Render("Class"))
+	for _, p := range m.positions[first:last] {
+		content.WriteString(byteClassStyle(p.Class).Render(" " + byteClassMarks[p.Class] + " "))
+	}
+	content.WriteString("\n")
+
+	// Field names span the bytes they cover
+	content.WriteString(labelStyle.Render("Fields"))
+	for pos := first; pos < last; {
+		f, ok := m.fieldAt(pos)
+		if !ok {
+			content.WriteString("   ")
+			pos++
+			continue
+		}
+		span := min(f.Offset+f.Size(), last) - pos
+		name := f.Name
+		if width := span*3 - 1; len(name) > width {
+			name = name[:width]
+		}
+		content.WriteString(fieldStyle.Render(fmt.Sprintf("%-*s", span*3-1, name)) + " ")
+		pos += span
+	}
+	content.WriteString("\n")
+
+	// Newest first, each compared with the one received before it
+	end := len(samples) - 1 - m.scroll
+	for i := end; i >= 0 && i > end-rows; i-- {
+		content.WriteString("\n")
+		content.WriteString(labelStyle.Render(samples[i].Timestamp.Format("15:04:05.000")))
+		data := samples[i].Data
+		for pos := first; pos < last; pos++ {
+			if pos >= len(data) {
+				content.WriteString("   ")
+				continue
+			}
+			cell := fmt.Sprintf("%02x", data[pos])
+			if i > 0 && (pos >= len(samples[i-1].Data) || samples[i-1].Data[pos] != data[pos]) {
+				content.WriteString(changedStyle.Render(cell))
+			} else {
+				content.WriteString(byteClassStyle(m.positions[pos].Class).Render(cell))
+			}
+			content.WriteString(" ")
+		}
+	}
+
+	content.WriteString("\n\n")
+	var legend []string
+	for _, c := range []stats.ByteClass{stats.ByteConstant, stats.ByteCounter, stats.ByteSensor, stats.ByteRandom, stats.ByteUnknown} {
+		legend = append(legend, byteClassStyle(c).Render(byteClassMarks[c]+" "+c.String()))
+	}
+	legend = append(legend, changedStyle.Render("changed"))
+	content.WriteString(strings.Join(legend, "  "))
+
+	for _, f := range m.fields {
+		content.WriteString("\n")
+		content.WriteString(fieldStyle.Render(fmt.Sprintf("%-14s", f.Name)))
+		value := "-"
+		if v, ok := f.Extract(samples[len(samples)-1].Data); ok {
+			value = f.Format(v)
+		}
+		content.WriteString(valueStyle.Render(value))
+		content.WriteString(labelStyle.UnsetWidth().Render(fmt.Sprintf("  %s at %02X", f.Type, f.Offset)))
+		if f.Divisor > 0 {
+			content.WriteString(labelStyle.UnsetWidth().Render(fmt.Sprintf(" /%g", f.Divisor)))
+		}
+	}
+	if m.status != "" {
+		content.WriteString("\n")
+		content.WriteString(lipgloss.NewStyle().Foreground(styles.AccentColor).Render(m.status))
+	}
+	return content.String()
+}
+
+// renderTimeline lists the latest runs of advertisements sharing a payload
+// structure
+func (m PayloadDiffModel) renderTimeline() string {
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.SecondaryColor)
+	labelStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Width(28)
+	valueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("255"))
+	countStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Width(12)
+
+	var content strings.Builder
+	changes := max(0, len(m.timeline)-1)
+	content.WriteString(headerStyle.Render(fmt.Sprintf("Structure Timeline (%d changes)", changes)))
+
+	segments := m.timeline
+	if len(segments) > maxTimelineSegments {
+		segments = segments[len(segments)-maxTimelineSegments:]
+	}
+	for _, s := range segments {
+		content.WriteString("\n")
+		content.WriteString(labelStyle.Render(s.Start.Format("15:04:05.000") + "-" + s.End.Format("15:04:05.000")))
+		content.WriteString(countStyle.Render(fmt.Sprintf("%d adverts", s.Adverts)))
+		content.WriteString(valueStyle.Render(s.Structure))
+	}
+	return content.String()
+}
+
+// renderForm draws the annotation form
+func (m PayloadDiffModel) renderForm() string {
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.SecondaryColor)
+	labelStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Width(12)
+	mutedStyle := lipgloss.NewStyle().Foreground(styles.MutedColor)
+
+	first, last := m.selection()
+	if f, ok := m.fieldAt(m.cursor); ok && m.anchor < 0 {
+		first, last = f.Offset, f.Offset+f.Size()-1
+	}
+
+	var content strings.Builder
+	content.WriteString(headerStyle.Render(fmt.Sprintf("Annotate bytes %02X-%02X of %s", first, last, m.source())))
+	for i, input := range m.inputs {
+		content.WriteString("\n")
+		content.WriteString(labelStyle.Render(fieldInputLabels[i]))
+		content.WriteString(input.View())
+	}
+	content.WriteString("\n")
+	content.WriteString(mutedStyle.Render("Types: " + strings.Join(annotate.Types, " ")))
+	return content.String()
+}
+
+func (m PayloadDiffModel) renderHelp() string {
+	helpStyle := lipgloss.NewStyle().
+		Foreground(styles.MutedColor).
+		Background(lipgloss.Color("235")).
+		Padding(0, 2).
+		Width(m.width)
+	if m.editing {
+		return helpStyle.Render("Tab Next input • Enter Save • Esc Cancel")
+	}
+	return helpStyle.Render("Tab Stream • ←/→ Cursor • Space Select • a Annotate • d Delete • ↑/↓ Scroll • Esc Back • q Quit")
+}
@@ -0,0 +1,344 @@
+package views
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/buckleypaul/blescan/internal/ble"
+	"github.com/buckleypaul/blescan/internal/ui/styles"
+)
+
+const (
+	// Arrivals and departures shown below the timeline
+	presenceLogRows = 8
+	// Width of the name, visits and dwell columns left of the bars
+	presenceNameWidth  = 20
+	presenceCountWidth = 7
+	presenceDwellWidth = 9
+)
+
+// Presence timeline sort orders
+const (
+	PresenceSortArrival = "first arrival"
+	PresenceSortDwell   = "dwell time"
+	PresenceSortVisits  = "visits"
+)
+
+var presenceSorts = []string{PresenceSortArrival, PresenceSortDwell, PresenceSortVisits}
+
+// PresenceModel draws a Gantt-style timeline of when each device was present,
+// with its dwell time and visits, above a log of arrivals and departures
+type PresenceModel struct {
+	presence []ble.Presence
+	events   []ble.PresenceEvent
+	sortBy   string
+	cursor   int
+	offset   int
+	status   string
+
+	width  int
+	height int
+}
+
+// NewPresenceModel creates an empty presence timeline
+func NewPresenceModel() PresenceModel {
+	return PresenceModel{sortBy: PresenceSortArrival}
+}
+
+// SetPresence replaces the visits and the arrival/departure log
+func (m *PresenceModel) SetPresence(presence []ble.Presence, events []ble.PresenceEvent) {
+	selected, _ := m.SelectedDeviceID()
+	m.presence = presence
+	for i, p := range m.presence {
+		if p.DeviceID == selected {
+			m.cursor = i
+		}
+	}
+	m.events = events
+	m.sortPresence()
+	m.clampCursor()
+}
+
+// SetStatus shows a message in place of the summary until the next key press
+func (m *PresenceModel) SetStatus(status string) {
+	m.status = status
+}
+
+// SelectedDeviceID returns the device ID of the selected row
+func (m PresenceModel) SelectedDeviceID() (string, bool) {
+	if m.cursor >= 0 && m.cursor < len(m.presence) {
+		return m.presence[m.cursor].DeviceID, true
+	}
+	return "", false
+}
+
+// Update handles presence timeline input
+func (m PresenceModel) Update(msg tea.Msg) (PresenceModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.clampCursor()
+	case tea.KeyMsg:
+		m.status = ""
+		switch msg.String() {
+		case "up", "k":
+			m.cursor--
+		case "down", "j":
+			m.cursor++
+		case "pgup":
+			m.cursor -= m.visibleRows()
+		case "pgdown":
+			m.cursor += m.visibleRows()
+		case "home":
+			m.cursor = 0
+		case "end":
+			m.cursor = len(m.presence) - 1
+		case "s":
+			for i, s := range presenceSorts {
+				if s == m.sortBy {
+					m.sortBy = presenceSorts[(i+1)%len(presenceSorts)]
+					break
+				}
+			}
+			m.sortPresence()
+		}
+		m.clampCursor()
+	}
+	return m, nil
+}
+
+// sortPresence sorts the devices, keeping the selected device selected
+func (m *PresenceModel) sortPresence() {
+	selected, _ := m.SelectedDeviceID()
+	defer func() {
+		for i, p := range m.presence {
+			if p.DeviceID == selected {
+				m.cursor = i
+			}
+		}
+	}()
+	sort.SliceStable(m.presence, func(i, j int) bool {
+		a, b := m.presence[i], m.presence[j]
+		switch m.sortBy {
+		case PresenceSortDwell:
+			return a.Dwell > b.Dwell
+		case PresenceSortVisits:
+			return a.Count > b.Count
+		}
+		return a.FirstSeen().Before(b.FirstSeen())
+	})
+}
+
+// visibleRows returns how many devices fit above the log
+func (m PresenceModel) visibleRows() int {
+	// Title, summary, borders, header, axis, log header and help
+	return max(3, m.height-presenceLogRows-11)
+}
+
+func (m *PresenceModel) clampCursor() {
+	m.cursor = min(m.cursor, len(m.presence)-1)
+	m.cursor = max(m.cursor, 0)
+	rows := m.visibleRows()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+rows {
+		m.offset = m.cursor - rows + 1
+	}
+	m.offset = max(0, min(m.offset, len(m.presence)-rows))
+}
+
+// View renders the presence timeline
+func (m PresenceModel) View() string {
+	var b strings.Builder
+
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(styles.PrimaryColor).
+		Background(lipgloss.Color("235")).
+		Padding(0, 2).
+		Width(m.width)
+	present := 0
+	for _, p := range m.presence {
+		if p.Present() {
+			present++
+		}
+	}
+	title := "Presence Timeline"
+	count := fmt.Sprintf("%d devices • %d present", len(m.presence), present)
+	b.WriteString(titleStyle.Render(title + strings.Repeat(" ", max(0, m.width-len(title)-len(count)-6)) + count))
+	b.WriteString("\n")
+
+	summaryStyle := lipgloss.NewStyle().
+		Foreground(styles.SecondaryColor).
+		Background(lipgloss.Color("236")).
+		Padding(0, 2).
+		Width(m.width)
+	summary := m.status
+	if summary == "" {
+		arrivals, departures := 0, 0
+		for _, e := range m.events {
+			if e.Kind == ble.PresenceArrival {
+				arrivals++
+			} else {
+				departures++
+			}
+		}
+		summary = fmt.Sprintf("%d arrivals • %d departures • sorted by %s • departed after %s without advertising",
+			arrivals, departures, m.sortBy, formatDuration(ble.DeviceTimeout))
+	}
+	b.WriteString(summaryStyle.Render(summary))
+	b.WriteString("\n")
+
+	sectionStyle := lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(styles.MutedColor).
+		Width(m.width - 2)
+	if len(m.presence) == 0 {
+		emptyStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Padding(1, 2)
+		b.WriteString(sectionStyle.Render(emptyStyle.Render("No devices seen yet.")))
+	} else {
+		b.WriteString(sectionStyle.Render(m.renderTimeline()))
+		b.WriteString("\n")
+		b.WriteString(sectionStyle.Render(m.renderLog()))
+	}
+	b.WriteString("\n")
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(styles.MutedColor).
+		Background(lipgloss.Color("235")).
+		Padding(0, 2).
+		Width(m.width)
+	b.WriteString(helpStyle.Render("↑/↓ Row • s Sort • Enter View • x Export • Esc Back • q Quit"))
+
+	return b.String()
+}
+
+// renderTimeline draws one bar per device across the session, from the
+// first arrival to now
+func (m PresenceModel) renderTimeline() string {
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.PrimaryColor)
+	mutedStyle := lipgloss.NewStyle().Foreground(styles.MutedColor)
+	presentStyle := lipgloss.NewStyle().Foreground(styles.SuccessColor)
+	pastStyle := lipgloss.NewStyle().Foreground(styles.SecondaryColor)
+	selectedStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("229")).Background(lipgloss.Color("57")).Bold(true)
+
+	now := time.Now()
+	start := now
+	for _, p := range m.presence {
+		if first := p.FirstSeen(); !first.IsZero() && first.Before(start) {
+			start = first
+		}
+	}
+	span := max(int(now.Sub(start)), int(time.Second))
+	barWidth := max(10, m.width-6-presenceNameWidth-presenceCountWidth-presenceDwellWidth)
+	// column returns the bar column a moment falls into
+	column := func(t time.Time) int {
+		return min(barWidth-1, max(0, int(int64(t.Sub(start))*int64(barWidth)/int64(span))))
+	}
+
+	var content strings.Builder
+	content.WriteString(headerStyle.Render(fmt.Sprintf("%-*s%*s%*s  %s",
+		presenceNameWidth, "Device", presenceCountWidth, "Visits", presenceDwellWidth, "Dwell", "Present")))
+
+	end := min(len(m.presence), m.offset+m.visibleRows())
+	for i := m.offset; i < end; i++ {
+		p := m.presence[i]
+		name := p.Name
+		if name == "" {
+			name = p.Address
+		}
+		if len(name) > presenceNameWidth-1 {
+			name = name[:presenceNameWidth-4] + "..."
+		}
+		label := fmt.Sprintf("%-*s%*d%*s  ", presenceNameWidth, name, presenceCountWidth, p.Count, presenceDwellWidth, formatDuration(p.Dwell))
+
+		// Mark every column a visit overlaps; the current visit stands out
+		cells := make([]int, barWidth) // 0 absent, 1 past visit, 2 current visit
+		for _, v := range p.Visits {
+			kind := 1
+			if v.Open {
+				kind = 2
+			}
+			to := v.End
+			if v.Open {
+				to = now
+			}
+			for c := column(v.Start); c <= column(to); c++ {
+				cells[c] = max(cells[c], kind)
+			}
+		}
+		var bar strings.Builder
+		for c := 0; c < barWidth; {
+			run := c
+			for run < barWidth && cells[run] == cells[c] {
+				run++
+			}
+			switch cells[c] {
+			case 2:
+				bar.WriteString(presentStyle.Render(strings.Repeat("█", run-c)))
+			case 1:
+				bar.WriteString(pastStyle.Render(strings.Repeat("█", run-c)))
+			default:
+				bar.WriteString(mutedStyle.Render(strings.Repeat("·", run-c)))
+			}
+			c = run
+		}
+
+		content.WriteString("\n")
+		if i == m.cursor {
+			content.WriteString(selectedStyle.Render(label))
+		} else {
+			content.WriteString(label)
+		}
+		content.WriteString(bar.String())
+	}
+
+	// Time axis under the bars
+	left, right := start.Format("15:04:05"), "now"
+	if span >= int(time.Hour) {
+		right = "now (" + formatDuration(now.Sub(start)) + ")"
+	}
+	content.WriteString("\n")
+	content.WriteString(strings.Repeat(" ", presenceNameWidth+presenceCountWidth+presenceDwellWidth+2))
+	content.WriteString(mutedStyle.Render(left + strings.Repeat(" ", max(1, barWidth-len(left)-len(right))) + right))
+	return content.String()
+}
+
+// renderLog lists the newest arrivals and departures, newest first
+func (m PresenceModel) renderLog() string {
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(styles.SecondaryColor)
+	mutedStyle := lipgloss.NewStyle().Foreground(styles.MutedColor)
+	arrivalStyle := lipgloss.NewStyle().Foreground(styles.SuccessColor)
+	departureStyle := lipgloss.NewStyle().Foreground(styles.AccentColor)
+
+	var content strings.Builder
+	content.WriteString(headerStyle.Render(fmt.Sprintf("Arrivals & Departures (%d)", len(m.events))))
+	if len(m.events) == 0 {
+		content.WriteString("\n")
+		content.WriteString(mutedStyle.Render("None yet."))
+	}
+	for i := len(m.events) - 1; i >= max(0, len(m.events)-presenceLogRows); i-- {
+		e := m.events[i]
+		name := e.Address
+		if e.Name != "" {
+			name = e.Name + " (" + e.Address + ")"
+		}
+		content.WriteString("\n")
+		content.WriteString(mutedStyle.Render(e.Time.Format("15:04:05") + "  "))
+		if e.Kind == ble.PresenceArrival {
+			content.WriteString(arrivalStyle.Render("▲ arrived   "))
+			content.WriteString(name)
+		} else {
+			content.WriteString(departureStyle.Render("▼ departed  "))
+			content.WriteString(name)
+			content.WriteString(mutedStyle.Render(" after " + formatDuration(e.Visit)))
+		}
+	}
+	return content.String()
+}
@@ -0,0 +1,236 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/buckleypaul/blescan/internal/ble"
+	"github.com/buckleypaul/blescan/internal/ui/styles"
+)
+
+// TrackerListModel lists location trackers and how long they have been near
+type TrackerListModel struct {
+	trackers  []ble.TrackerSighting
+	threshold time.Duration
+	table     table.Model
+	width     int
+	height    int
+}
+
+// trackerColumns are the columns of the tracker table, with their share of
+// the available width
+var trackerColumns = []struct {
+	title string
+	pct   int
+}{
+	{"", 3},
+	{"Tracker", 10},
+	{"State", 13},
+	{"Near For", 10},
+	{"Last Heard", 10},
+	{"RSSI", 7},
+	{"Addrs", 6},
+	{"Address", 18},
+	{"Name", 20},
+}
+
+// NewTrackerListModel creates a new tracker list
+func NewTrackerListModel(threshold time.Duration) TrackerListModel {
+	t := table.New(
+		table.WithColumns([]table.Column{}),
+		table.WithRows([]table.Row{}),
+		table.WithFocused(true),
+		table.WithHeight(10),
+	)
+
+	s := table.DefaultStyles()
+	s.Header = s.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(styles.MutedColor).
+		BorderBottom(true).
+		Bold(true).
+		Foreground(styles.PrimaryColor)
+	s.Selected = s.Selected.
+		Foreground(lipgloss.Color("229")).
+		Background(lipgloss.Color("57")).
+		Bold(true)
+	s.Cell = s.Cell.Padding(0, 1)
+	t.SetStyles(s)
+
+	m := TrackerListModel{table: t, threshold: threshold}
+	m.updateColumns()
+	return m
+}
+
+// Update handles tracker list updates
+func (m TrackerListModel) Update(msg tea.Msg) (TrackerListModel, tea.Cmd) {
+	var cmd tea.Cmd
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.table.SetHeight(max(5, m.height-7))
+		m.updateColumns()
+		m.updateRows()
+	default:
+		m.table, cmd = m.table.Update(msg)
+	}
+	return m, cmd
+}
+
+// SetTrackers replaces the tracker list
+func (m *TrackerListModel) SetTrackers(trackers []ble.TrackerSighting) {
+	m.trackers = trackers
+	m.updateRows()
+}
+
+// AlertCount returns the number of trackers over the alert threshold
+func (m TrackerListModel) AlertCount() int {
+	n := 0
+	for _, t := range m.trackers {
+		if t.Alert {
+			n++
+		}
+	}
+	return n
+}
+
+// UnknownCount returns the number of trackers over the alert threshold that
+// don't say whether their owner is nearby
+func (m TrackerListModel) UnknownCount() int {
+	n := 0
+	for _, t := range m.trackers {
+		if t.OwnerUnknown {
+			n++
+		}
+	}
+	return n
+}
+
+// SelectedDeviceID returns the device ID of the selected tracker
+func (m TrackerListModel) SelectedDeviceID() (string, bool) {
+	idx := m.table.Cursor()
+	if idx >= 0 && idx < len(m.trackers) {
+		return m.trackers[idx].DeviceID, true
+	}
+	return "", false
+}
+
+func (m *TrackerListModel) updateColumns() {
+	available := max(len(trackerColumns)*6, m.width-8)
+	columns := make([]table.Column, len(trackerColumns))
+	for i, c := range trackerColumns {
+		columns[i] = table.Column{Title: c.title, Width: max(2, available*c.pct/100)}
+	}
+	m.table.SetColumns(columns)
+}
+
+func (m *TrackerListModel) updateRows() {
+	columns := m.table.Columns()
+	now := time.Now()
+	rows := make([]table.Row, len(m.trackers))
+	for i, t := range m.trackers {
+		alert := ""
+		switch {
+		case t.Alert:
+			alert = "!"
+		case t.OwnerUnknown:
+			alert = "?"
+		}
+		address := ""
+		if n := len(t.Addresses); n > 0 {
+			address = t.Addresses[n-1]
+		}
+		row := table.Row{
+			alert,
+			t.Kind,
+			t.State,
+			formatDuration(t.Dwell()),
+			formatDuration(now.Sub(t.LastSeen)) + " ago",
+			fmt.Sprintf("%d", t.RSSI),
+			fmt.Sprintf("%d", len(t.Addresses)),
+			address,
+			t.Name,
+		}
+		for j := range row {
+			if j < len(columns) {
+				if maxLen := columns[j].Width - 2; maxLen > 3 && len(row[j]) > maxLen {
+					row[j] = row[j][:maxLen-3] + "..."
+				}
+			}
+		}
+		rows[i] = row
+	}
+	m.table.SetRows(rows)
+}
+
+// formatDuration formats a duration as "1h02m", "5m03s" or "12s"
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+}
+
+// View renders the tracker list
+func (m TrackerListModel) View() string {
+	var b strings.Builder
+
+	titleStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(styles.PrimaryColor).
+		Background(lipgloss.Color("235")).
+		Padding(0, 2).
+		Width(m.width)
+
+	title := "Tracker Detection"
+	count := fmt.Sprintf("%d trackers", len(m.trackers))
+	b.WriteString(titleStyle.Render(title + strings.Repeat(" ", max(0, m.width-len(title)-len(count)-6)) + count))
+	b.WriteString("\n")
+
+	// Alert banner
+	bannerStyle := lipgloss.NewStyle().
+		Padding(0, 2).
+		Width(m.width)
+	if alerts := m.AlertCount(); alerts > 0 {
+		bannerStyle = bannerStyle.Bold(true).Foreground(lipgloss.Color("255")).Background(styles.ErrorColor)
+		b.WriteString(bannerStyle.Render(fmt.Sprintf("⚠ %d tracker(s) away from their owner have been near you for over %s", alerts, formatDuration(m.threshold))))
+	} else if unknown := m.UnknownCount(); unknown > 0 {
+		bannerStyle = bannerStyle.Foreground(styles.AccentColor).Background(lipgloss.Color("236"))
+		b.WriteString(bannerStyle.Render(fmt.Sprintf("? %d tracker(s) with owner state unknown have been near you for over %s", unknown, formatDuration(m.threshold))))
+	} else {
+		bannerStyle = bannerStyle.Foreground(styles.SecondaryColor).Background(lipgloss.Color("236"))
+		b.WriteString(bannerStyle.Render(fmt.Sprintf("Alerting on separated trackers near you for over %s", formatDuration(m.threshold))))
+	}
+	b.WriteString("\n")
+
+	tableStyle := lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(styles.MutedColor).
+		Width(m.width - 2)
+	if len(m.trackers) == 0 {
+		emptyStyle := lipgloss.NewStyle().Foreground(styles.MutedColor).Padding(1, 2)
+		b.WriteString(tableStyle.Render(emptyStyle.Render("No trackers seen.")))
+	} else {
+		b.WriteString(tableStyle.Render(m.table.View()))
+	}
+	b.WriteString("\n")
+
+	helpStyle := lipgloss.NewStyle().
+		Foreground(styles.MutedColor).
+		Background(lipgloss.Color("235")).
+		Padding(0, 2).
+		Width(m.width)
+	b.WriteString(helpStyle.Render("↑/↓ Row • Enter View • Esc Back • q Quit"))
+
+	return b.String()
+}